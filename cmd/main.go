@@ -51,6 +51,32 @@ func main() {
 		return application.Server.Run(ctx)
 	})
 
+	eg.Go(func() error {
+		return application.Sweeper.Run(ctx)
+	})
+
+	eg.Go(func() error {
+		return application.Imports.Run(ctx)
+	})
+
+	eg.Go(func() error {
+		return application.Clicks.Run(ctx)
+	})
+
+	eg.Go(func() error {
+		return application.Live.Run(ctx)
+	})
+
+	eg.Go(func() error {
+		return application.Classifier.Watch(ctx, cfg.Analytics.UARulesReloadInterval)
+	})
+
+	if application.GeoIP != nil {
+		eg.Go(func() error {
+			return application.GeoIP.Watch(ctx, cfg.GeoIP.ReloadInterval)
+		})
+	}
+
 	eg.Go(func() error {
 		return (http.ListenAndServe(":8081", pMux))
 	})
@@ -1,33 +1,70 @@
 package local
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 	"url-shortener/internal/domain"
+	"url-shortener/pkg/hll"
 )
 
 type repository struct {
-	Long map[string]string
-	Short map[string]domain.URL
-	mu        sync.RWMutex
+	Long    map[string]string
+	Short   map[string]domain.URL
+	History map[string][]domain.LinkChange
+	Clicks  map[string][]domain.ClickEvent
+	// Visitors holds a sketch per link and day, see visitorsKey.
+	Visitors map[string]*hll.Sketch
+	mu       sync.RWMutex
 }
 
 func New() *repository {
 	return &repository{
-		Long: make(map[string]string),
-		Short: make(map[string]domain.URL),
-		mu:        sync.RWMutex{}}
+		Long:     make(map[string]string),
+		Short:    make(map[string]domain.URL),
+		History:  make(map[string][]domain.LinkChange),
+		Clicks:   make(map[string][]domain.ClickEvent),
+		Visitors: make(map[string]*hll.Sketch),
+		mu:       sync.RWMutex{}}
 }
 
 func (r *repository) InsertUrl(ctx context.Context, url domain.URL) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.Long[url.LongURL] = url.ShortURL
+	if _, ok := r.Short[url.ShortURL]; ok {
+		return domain.ErrShortURLAlreadyExist
+	}
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now()
+	}
+	if !url.Expiring() {
+		r.Long[longKey(url.OwnerID, url.LongURL)] = url.ShortURL
+	}
 	r.Short[url.ShortURL] = url
 	return nil
 }
 
+func (r *repository) InsertUrls(ctx context.Context, urls []domain.URL) ([]string, error) {
+	saved := make([]string, 0, len(urls))
+	for _, url := range urls {
+		err := r.InsertUrl(ctx, url)
+		if errors.Is(err, domain.ErrShortURLAlreadyExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		saved = append(saved, url.ShortURL)
+	}
+
+	return saved, nil
+}
+
 func (r *repository) GetShortUrl(ctx context.Context, url string) (*domain.URL, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -46,25 +83,366 @@ func (r *repository) GetCountShortUrls(ctx context.Context) (int, error) {
 	return len(r.Short), nil
 }
 
+func (r *repository) CountClicks(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, link := range r.Short {
+		count += link.Clicks
+	}
+
+	return count, nil
+}
+
 func (r *repository) DeleteShortUrl(ctx context.Context, shortURL string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.Short[shortURL]; !ok {
+	url, ok := r.Short[shortURL]
+	if !ok {
 		return errors.New("not found short url")
 	}
-	delete(r.Long, r.Short[shortURL].LongURL)
-	delete(r.Short, shortURL)
+	r.delete(url)
 	return nil
-  }
+}
+
+func (r *repository) delete(url domain.URL) {
+	key := longKey(url.OwnerID, url.LongURL)
+	if r.Long[key] == url.ShortURL {
+		delete(r.Long, key)
+	}
+	delete(r.Short, url.ShortURL)
+}
 
-  func (r *repository) GetByLongUrl(ctx context.Context, url string) (*domain.URL, error) {
+func (r *repository) GetByLongUrl(ctx context.Context, ownerID string, url string) (*domain.URL, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.Long[url]; !ok {
+	short, ok := r.Long[longKey(ownerID, url)]
+	if !ok {
 		return nil, domain.ErrOriginalURLNotFound
 	}
-	res := r.Short[r.Long[url]]
+	res := r.Short[short]
 
 	return &res, nil
 }
 
+func (r *repository) GetByLongUrls(ctx context.Context, ownerID string, urls []string) (map[string]domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	links := make(map[string]domain.URL)
+	for _, url := range urls {
+		if short, ok := r.Long[longKey(ownerID, url)]; ok {
+			links[url] = r.Short[short]
+		}
+	}
+
+	return links, nil
+}
+
+func (r *repository) IncrementClicks(ctx context.Context, shortURL string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	url, ok := r.Short[shortURL]
+	if !ok || (url.MaxClicks > 0 && url.Clicks >= url.MaxClicks) {
+		return domain.ErrLinkExpired
+	}
+	url.Clicks++
+	r.Short[shortURL] = url
+	return nil
+}
+
+func (r *repository) UpdateLongUrl(ctx context.Context, shortURL string, longURL string, changedBy string) (*domain.URL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	url, ok := r.Short[shortURL]
+	if !ok {
+		return nil, domain.ErrOriginalURLNotFound
+	}
+
+	r.History[shortURL] = append(r.History[shortURL], domain.LinkChange{
+		ShortURL:  shortURL,
+		LongURL:   url.LongURL,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+	})
+
+	r.delete(url)
+	url.LongURL = longURL
+	if !url.Expiring() {
+		r.Long[longKey(url.OwnerID, url.LongURL)] = url.ShortURL
+	}
+	r.Short[shortURL] = url
+
+	return &url, nil
+}
+
+func (r *repository) GetLinkHistory(ctx context.Context, shortURL string) ([]domain.LinkChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.History[shortURL]), nil
+}
+
+func (r *repository) InsertClicks(ctx context.Context, events []domain.ClickEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range events {
+		event.IP, event.VisitorID = "", ""
+		r.Clicks[event.ShortURL] = append(r.Clicks[event.ShortURL], event)
+	}
+	return nil
+}
+
+func (r *repository) AddClicks(ctx context.Context, clicks map[string]int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for shortURL, n := range clicks {
+		url, ok := r.Short[shortURL]
+		if !ok || url.MaxClicks > 0 {
+			continue
+		}
+		url.Clicks += n
+		r.Short[shortURL] = url
+	}
+	return nil
+}
+
+func (r *repository) ClickStats(ctx context.Context, shortURL string, query domain.StatsQuery) ([]domain.StatsPoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clicks := make(map[time.Time]*domain.StatsPoint)
+	for _, event := range r.clicksInRange(shortURL, query) {
+		start := query.Bucket.Truncate(event.OccurredAt)
+		point, ok := clicks[start]
+		if !ok {
+			point = &domain.StatsPoint{Start: start}
+			clicks[start] = point
+		}
+		point.Clicks++
+		if !event.Bot {
+			point.HumanClicks++
+		}
+	}
+
+	points := make([]domain.StatsPoint, 0, len(clicks))
+	for _, point := range clicks {
+		points = append(points, *point)
+	}
+	slices.SortFunc(points, func(a, b domain.StatsPoint) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	return points, nil
+}
+
+func (r *repository) ClickBreakdown(ctx context.Context, shortURL string, query domain.StatsQuery) ([]domain.BreakdownItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clicks := make(map[string]*domain.BreakdownItem)
+	for _, event := range r.clicksInRange(shortURL, query) {
+		var value string
+		switch query.GroupBy {
+		case domain.StatsDimensionBrowser:
+			value = event.Browser
+		case domain.StatsDimensionOS:
+			value = event.OS
+		case domain.StatsDimensionDevice:
+			value = event.Device
+		case domain.StatsDimensionBot:
+			value = "human"
+			if event.Bot {
+				value = "bot"
+			}
+		case domain.StatsDimensionCountry:
+			value = event.Country
+		case domain.StatsDimensionRegion:
+			value = event.Region
+		default:
+			return nil, domain.ErrInvalidStatsQuery
+		}
+
+		item, ok := clicks[value]
+		if !ok {
+			item = &domain.BreakdownItem{Value: value}
+			clicks[value] = item
+		}
+		item.Clicks++
+		if !event.Bot {
+			item.HumanClicks++
+		}
+	}
+
+	items := make([]domain.BreakdownItem, 0, len(clicks))
+	for _, item := range clicks {
+		items = append(items, *item)
+	}
+	slices.SortFunc(items, func(a, b domain.BreakdownItem) int {
+		return cmp.Or(cmp.Compare(b.Clicks, a.Clicks), cmp.Compare(a.Value, b.Value))
+	})
+
+	return items, nil
+}
+
+func (r *repository) ExportClicks(ctx context.Context, shortURL string, from, to time.Time, fn func(domain.ClickEvent) error) error {
+	r.mu.RLock()
+	events := r.clicksInRange(shortURL, domain.StatsQuery{From: from, To: to})
+	r.mu.RUnlock()
+
+	slices.SortStableFunc(events, func(a, b domain.ClickEvent) int {
+		return a.OccurredAt.Compare(b.OccurredAt)
+	})
+	for _, event := range events {
+		err := fn(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repository) clicksInRange(shortURL string, query domain.StatsQuery) []domain.ClickEvent {
+	events := make([]domain.ClickEvent, 0)
+	for _, event := range r.Clicks[shortURL] {
+		if event.OccurredAt.Before(query.From) || !event.OccurredAt.Before(query.To) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// AddVisitors counts visitors in memory, the sketches live as long as the
+// process, so ttl is not used.
+func (r *repository) AddVisitors(ctx context.Context, events []domain.ClickEvent, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range events {
+		if event.VisitorID == "" {
+			continue
+		}
+		key := visitorsKey(event.ShortURL, event.OccurredAt)
+		sketch, ok := r.Visitors[key]
+		if !ok {
+			sketch = hll.New()
+			r.Visitors[key] = sketch
+		}
+		sketch.Add([]byte(event.VisitorID))
+	}
+	return nil
+}
+
+func (r *repository) DailyVisitors(ctx context.Context, shortURL string, days []time.Time) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make([]int64, 0, len(days))
+	for _, day := range days {
+		var count int64
+		if sketch, ok := r.Visitors[visitorsKey(shortURL, day)]; ok {
+			count = int64(sketch.Count())
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+func (r *repository) UniqueVisitors(ctx context.Context, shortURL string, days []time.Time) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	union := hll.New()
+	for _, day := range days {
+		if sketch, ok := r.Visitors[visitorsKey(shortURL, day)]; ok {
+			union.Merge(sketch)
+		}
+	}
+	return int64(union.Count()), nil
+}
+
+func visitorsKey(shortURL string, day time.Time) string {
+	return shortURL + " " + day.UTC().Format("20060102")
+}
+
+// ArchiveExpiredUrls drops expired links, the in-memory storage keeps no archive.
+func (r *repository) ArchiveExpiredUrls(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, url := range r.Short {
+		if url.Expired(now) {
+			r.delete(url)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *repository) ListLinks(ctx context.Context, filter domain.LinkFilter) ([]domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	compare := func(a, b domain.URL) int {
+		var res int
+		if filter.SortBy == domain.LinkSortClicks {
+			res = cmp.Compare(a.Clicks, b.Clicks)
+		} else {
+			res = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		if res == 0 {
+			res = strings.Compare(a.Id, b.Id)
+		}
+		if !filter.Ascending {
+			res = -res
+		}
+		return res
+	}
+
+	var after domain.URL
+	if filter.After != nil {
+		after = domain.URL{Id: filter.After.ID, CreatedAt: filter.After.CreatedAt, Clicks: filter.After.Clicks}
+	}
+
+	links := make([]domain.URL, 0)
+	for _, link := range r.Short {
+		if !filter.AnyOwner && link.OwnerID != filter.OwnerID {
+			continue
+		}
+		if filter.Host != "" && !strings.EqualFold(hostOf(link.LongURL), filter.Host) {
+			continue
+		}
+		if !filter.CreatedFrom.IsZero() && link.CreatedAt.Before(filter.CreatedFrom) {
+			continue
+		}
+		if !filter.CreatedTo.IsZero() && !link.CreatedAt.Before(filter.CreatedTo) {
+			continue
+		}
+		if filter.After != nil && compare(link, after) <= 0 {
+			continue
+		}
+		links = append(links, link)
+	}
+
+	slices.SortFunc(links, compare)
+	if len(links) > filter.Limit {
+		links = links[:filter.Limit]
+	}
+
+	return links, nil
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// longKey indexes destinations per owner, so every user gets their own short link.
+func longKey(ownerID, url string) string {
+	return ownerID + " " + url
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"time"
 	"url-shortener/internal/domain"
 
 	"github.com/jackc/pgx/v5"
@@ -22,17 +25,63 @@ func NewRepositoruPG(conn *pgxpool.Pool) *RepositoryPG {
 }
 
 func (pg *RepositoryPG) InsertUrl(ctx context.Context, url domain.URL) error {
-	_, err := pg.conn.Exec(ctx, "INSERT INTO short_urls (unique_id, short_url, long_url) VALUES($1, $2, $3)", url.Id, url.ShortURL, url.LongURL)
+	_, err := pg.conn.Exec(ctx, "INSERT INTO short_urls (unique_id, short_url, long_url, owner_id, expires_at, max_clicks) VALUES($1, $2, $3, $4, $5, $6)",
+		url.Id, url.ShortURL, url.LongURL, nullIfEmpty(url.OwnerID), nullIfZeroTime(url.ExpiresAt), nullIfZero(url.MaxClicks))
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "short_urls_short_url_key" {
+			return domain.ErrShortURLAlreadyExist
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (pg *RepositoryPG) GetByLongUrl(ctx context.Context, url string) (*domain.URL, error) {
-	var link domain.URL
-	err := pg.conn.QueryRow(ctx, "SELECT unique_id, short_url, long_url FROM short_urls WHERE long_url = $1", url).Scan(&link.Id, &link.ShortURL, &link.LongURL)
+// InsertUrls saves the links in one transaction, the links whose short url is
+// taken are skipped. It returns the short urls of the saved links.
+func (pg *RepositoryPG) InsertUrls(ctx context.Context, urls []domain.URL) ([]string, error) {
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.InsertUrls: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, url := range urls {
+		batch.Queue(`INSERT INTO short_urls (unique_id, short_url, long_url, owner_id, expires_at, max_clicks) VALUES($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (short_url) DO NOTHING`,
+			url.Id, url.ShortURL, url.LongURL, nullIfEmpty(url.OwnerID), nullIfZeroTime(url.ExpiresAt), nullIfZero(url.MaxClicks))
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	saved := make([]string, 0, len(urls))
+	for _, url := range urls {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return nil, fmt.Errorf("storage.pg.InsertUrls: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			saved = append(saved, url.ShortURL)
+		}
+	}
+	err = results.Close()
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.InsertUrls: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.InsertUrls: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (pg *RepositoryPG) GetByLongUrl(ctx context.Context, ownerID string, url string) (*domain.URL, error) {
+	row := pg.conn.QueryRow(ctx, "SELECT "+urlColumns+" FROM short_urls WHERE long_url = $1 AND owner_id IS NOT DISTINCT FROM $2 AND expires_at IS NULL AND max_clicks IS NULL LIMIT 1", url, nullIfEmpty(ownerID))
+	link, err := scanURL(row)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrOriginalURLNotFound
@@ -40,12 +89,38 @@ func (pg *RepositoryPG) GetByLongUrl(ctx context.Context, url string) (*domain.U
 		return nil, err
 	}
 
-	return &link, nil
+	return link, nil
+}
+
+// GetByLongUrls finds the links of the owner to urls that have no expiration
+// rules, keyed by destination.
+func (pg *RepositoryPG) GetByLongUrls(ctx context.Context, ownerID string, urls []string) (map[string]domain.URL, error) {
+	rows, err := pg.conn.Query(ctx, "SELECT DISTINCT ON (long_url) "+urlColumns+` FROM short_urls
+		WHERE long_url = ANY($1) AND owner_id IS NOT DISTINCT FROM $2 AND expires_at IS NULL AND max_clicks IS NULL
+		ORDER BY long_url, id`, urls, nullIfEmpty(ownerID))
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.GetByLongUrls: %w", err)
+	}
+	defer rows.Close()
+
+	links := make(map[string]domain.URL)
+	for rows.Next() {
+		link, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.GetByLongUrls: %w", err)
+		}
+		links[link.LongURL] = *link
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.GetByLongUrls: %w", err)
+	}
+
+	return links, nil
 }
 
 func (pg *RepositoryPG) GetShortUrl(ctx context.Context, url string) (*domain.URL, error) {
-	var link domain.URL
-	err := pg.conn.QueryRow(ctx, "SELECT unique_id, short_url, long_url FROM short_urls WHERE short_url = $1", url).Scan(&link.Id, &link.ShortURL, &link.LongURL)
+	row := pg.conn.QueryRow(ctx, "SELECT "+urlColumns+" FROM short_urls WHERE short_url = $1", url)
+	link, err := scanURL(row)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrOriginalURLNotFound
@@ -53,7 +128,7 @@ func (pg *RepositoryPG) GetShortUrl(ctx context.Context, url string) (*domain.UR
 		return nil, err
 	}
 
-	return &link, nil
+	return link, nil
 }
 
 func (pg *RepositoryPG) GetCountShortUrls(ctx context.Context) (int, error) {
@@ -66,21 +141,314 @@ func (pg *RepositoryPG) GetCountShortUrls(ctx context.Context) (int, error) {
 	return count, nil
 }
 
+func (pg *RepositoryPG) CountClicks(ctx context.Context) (int64, error) {
+	var count int64
+	err := pg.conn.QueryRow(ctx, "SELECT COALESCE(SUM(clicks), 0) FROM short_urls").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("storage.pg.CountClicks: %w", err)
+	}
+
+	return count, nil
+}
+
 func (pg *RepositoryPG) DeleteShortUrl(ctx context.Context, shortURL string) error {
 	_, err := pg.conn.Exec(ctx, "DELETE FROM short_urls WHERE short_url = $1", shortURL)
 	if err != nil {
-	  return err
+		return err
+	}
+	return nil
+}
+
+func (pg *RepositoryPG) IncrementClicks(ctx context.Context, shortURL string) error {
+	tag, err := pg.conn.Exec(ctx, "UPDATE short_urls SET clicks = clicks + 1 WHERE short_url = $1 AND (max_clicks IS NULL OR clicks < max_clicks)", shortURL)
+	if err != nil {
+		return fmt.Errorf("storage.pg.IncrementClicks: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrLinkExpired
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) UpdateLongUrl(ctx context.Context, shortURL string, longURL string, changedBy string) (*domain.URL, error) {
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.UpdateLongUrl: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO short_url_history (short_url, long_url, changed_by)
+		SELECT short_url, long_url, $2 FROM short_urls WHERE short_url = $1 FOR UPDATE`, shortURL, nullIfEmpty(changedBy))
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.UpdateLongUrl: %w", err)
+	}
+
+	row := tx.QueryRow(ctx, "UPDATE short_urls SET long_url = $2 WHERE short_url = $1 RETURNING "+urlColumns, shortURL, longURL)
+	link, err := scanURL(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrOriginalURLNotFound
+		}
+		return nil, fmt.Errorf("storage.pg.UpdateLongUrl: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.UpdateLongUrl: %w", err)
+	}
+
+	return link, nil
+}
+
+func (pg *RepositoryPG) GetLinkHistory(ctx context.Context, shortURL string) ([]domain.LinkChange, error) {
+	rows, err := pg.conn.Query(ctx, "SELECT short_url, long_url, COALESCE(changed_by::text, ''), changed_at FROM short_url_history WHERE short_url = $1 ORDER BY changed_at, id", shortURL)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.GetLinkHistory: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]domain.LinkChange, 0)
+	for rows.Next() {
+		var change domain.LinkChange
+		err := rows.Scan(&change.ShortURL, &change.LongURL, &change.ChangedBy, &change.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.GetLinkHistory: %w", err)
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.GetLinkHistory: %w", err)
+	}
+
+	return history, nil
+}
+
+func (pg *RepositoryPG) ArchiveExpiredUrls(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := pg.conn.Exec(ctx, `WITH expired AS (
+		DELETE FROM short_urls
+		WHERE expires_at <= $1 OR clicks >= max_clicks
+		RETURNING unique_id, short_url, long_url, owner_id, created_at, clicks, expires_at, max_clicks
+	)
+	INSERT INTO short_urls_archive (unique_id, short_url, long_url, owner_id, created_at, clicks, expires_at, max_clicks)
+	SELECT unique_id, short_url, long_url, owner_id, created_at, clicks, expires_at, max_clicks FROM expired`, now)
+	if err != nil {
+		return 0, fmt.Errorf("storage.pg.ArchiveExpiredUrls: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (pg *RepositoryPG) ListLinks(ctx context.Context, filter domain.LinkFilter) ([]domain.URL, error) {
+	sortColumn := "created_at"
+	if filter.SortBy == domain.LinkSortClicks {
+		sortColumn = "clicks"
+	}
+	direction, cmp := "DESC", "<"
+	if filter.Ascending {
+		direction, cmp = "ASC", ">"
+	}
+
+	args := []any{}
+	query := "SELECT " + urlColumns + " FROM short_urls WHERE true"
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !filter.AnyOwner {
+		query += " AND owner_id = " + arg(filter.OwnerID)
+	}
+
+	if filter.Host != "" {
+		query += " AND lower(substring(long_url from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^@/?#]*@)?([^:/?#]+)')) = lower(" + arg(filter.Host) + ")"
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query += " AND created_at >= " + arg(filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query += " AND created_at < " + arg(filter.CreatedTo)
+	}
+	if filter.After != nil {
+		var position any = filter.After.CreatedAt
+		if filter.SortBy == domain.LinkSortClicks {
+			position = filter.After.Clicks
+		}
+		query += fmt.Sprintf(" AND (%s, unique_id) %s (%s, %s)", sortColumn, cmp, arg(position), arg(filter.After.ID))
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, unique_id %s LIMIT %s", sortColumn, direction, direction, arg(filter.Limit))
+
+	rows, err := pg.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.ListLinks: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]domain.URL, 0, filter.Limit)
+	for rows.Next() {
+		link, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.ListLinks: %w", err)
+		}
+		links = append(links, *link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.ListLinks: %w", err)
+	}
+
+	return links, nil
+}
+
+func (pg *RepositoryPG) InsertClicks(ctx context.Context, events []domain.ClickEvent) error {
+	rows := make([][]any, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, []any{event.ShortURL, event.OccurredAt, event.Referrer, event.UserAgent, event.IPNetwork,
+			event.Browser, event.OS, event.Device, event.Bot, event.Country, event.Region})
+	}
+
+	_, err := pg.conn.CopyFrom(ctx, pgx.Identifier{"click_events"},
+		[]string{"short_url", "occurred_at", "referrer", "user_agent", "ip_network", "browser", "os", "device", "is_bot", "country", "region"},
+		pgx.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("storage.pg.InsertClicks: %w", err)
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) AddClicks(ctx context.Context, clicks map[string]int64) error {
+	// the rows are locked in the same order by every instance
+	shortURLs := make([]string, 0, len(clicks))
+	for shortURL := range clicks {
+		shortURLs = append(shortURLs, shortURL)
+	}
+	slices.Sort(shortURLs)
+	counts := make([]int64, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		counts[i] = clicks[shortURL]
+	}
+
+	_, err := pg.conn.Exec(ctx, `UPDATE short_urls s SET clicks = s.clicks + c.n
+		FROM unnest($1::text[], $2::bigint[]) AS c(short_url, n)
+		WHERE s.short_url = c.short_url AND s.max_clicks IS NULL`, shortURLs, counts)
+	if err != nil {
+		return fmt.Errorf("storage.pg.AddClicks: %w", err)
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) ClickStats(ctx context.Context, shortURL string, query domain.StatsQuery) ([]domain.StatsPoint, error) {
+	rows, err := pg.conn.Query(ctx, `SELECT date_trunc($2, occurred_at AT TIME ZONE 'UTC') AS bucket,
+			count(*), count(*) FILTER (WHERE NOT is_bot)
+		FROM click_events
+		WHERE short_url = $1 AND occurred_at >= $3 AND occurred_at < $4
+		GROUP BY bucket ORDER BY bucket`, shortURL, string(query.Bucket), query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.ClickStats: %w", err)
+	}
+	defer rows.Close()
+
+	points := make([]domain.StatsPoint, 0)
+	for rows.Next() {
+		var point domain.StatsPoint
+		err := rows.Scan(&point.Start, &point.Clicks, &point.HumanClicks)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.ClickStats: %w", err)
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.ClickStats: %w", err)
+	}
+
+	return points, nil
+}
+
+// breakdownColumns maps the stats dimensions to SQL expressions, it also
+// keeps the query text out of user input.
+var breakdownColumns = map[domain.StatsDimension]string{
+	domain.StatsDimensionBrowser: "browser",
+	domain.StatsDimensionOS:      "os",
+	domain.StatsDimensionDevice:  "device",
+	domain.StatsDimensionBot:     "CASE WHEN is_bot THEN 'bot' ELSE 'human' END",
+	domain.StatsDimensionCountry: "country",
+	domain.StatsDimensionRegion:  "region",
+}
+
+func (pg *RepositoryPG) ClickBreakdown(ctx context.Context, shortURL string, query domain.StatsQuery) ([]domain.BreakdownItem, error) {
+	column, ok := breakdownColumns[query.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("storage.pg.ClickBreakdown: %w: unknown dimension %q", domain.ErrInvalidStatsQuery, query.GroupBy)
+	}
+
+	rows, err := pg.conn.Query(ctx, `SELECT `+column+` AS value, count(*) AS clicks, count(*) FILTER (WHERE NOT is_bot)
+		FROM click_events
+		WHERE short_url = $1 AND occurred_at >= $2 AND occurred_at < $3
+		GROUP BY value ORDER BY clicks DESC, value`, shortURL, query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.ClickBreakdown: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]domain.BreakdownItem, 0)
+	for rows.Next() {
+		var item domain.BreakdownItem
+		err := rows.Scan(&item.Value, &item.Clicks, &item.HumanClicks)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.ClickBreakdown: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.ClickBreakdown: %w", err)
+	}
+
+	return items, nil
+}
+
+func (pg *RepositoryPG) ExportClicks(ctx context.Context, shortURL string, from, to time.Time, fn func(domain.ClickEvent) error) error {
+	rows, err := pg.conn.Query(ctx, `SELECT short_url, occurred_at, referrer, user_agent, ip_network,
+			browser, os, device, is_bot, country, region
+		FROM click_events
+		WHERE short_url = $1 AND occurred_at >= $2 AND occurred_at < $3
+		ORDER BY occurred_at, id`, shortURL, from, to)
+	if err != nil {
+		return fmt.Errorf("storage.pg.ExportClicks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event domain.ClickEvent
+		err := rows.Scan(&event.ShortURL, &event.OccurredAt, &event.Referrer, &event.UserAgent, &event.IPNetwork,
+			&event.Browser, &event.OS, &event.Device, &event.Bot, &event.Country, &event.Region)
+		if err != nil {
+			return fmt.Errorf("storage.pg.ExportClicks: %w", err)
+		}
+
+		err = fn(event)
+		if err != nil {
+			return err
+		}
 	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("storage.pg.ExportClicks: %w", err)
+	}
+
 	return nil
-  }
+}
 
 func (pg *RepositoryPG) SaveUser(ctx context.Context, user *domain.User) (string, error) {
-	row := pg.conn.QueryRow(ctx, "INSERT INTO users(nickname, password_hash) VALUES ($1, $2) RETURNING id", user.Nickname, user.PasswordHash)
+	row := pg.conn.QueryRow(ctx, "INSERT INTO users(nickname, password_hash, email) VALUES ($1, $2, $3) RETURNING id", user.Nickname, user.PasswordHash, nullIfEmpty(user.Email))
 
 	var id string
 	err := row.Scan(&id)
 	if err != nil {
 		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == usersEmailIndex {
+			return "", domain.ErrEmailAlreadyExist
+		}
 		if errors.As(err, &pgErr) && pgErr.ConstraintName != "" {
 			return "", domain.ErrNicknameAlreadyExist
 		}
@@ -92,10 +460,9 @@ func (pg *RepositoryPG) SaveUser(ctx context.Context, user *domain.User) (string
 }
 
 func (pg *RepositoryPG) GetUser(ctx context.Context, nickname string) (*domain.User, error) {
-	row := pg.conn.QueryRow(ctx, "SELECT id, nickname, password_hash FROM users WHERE nickname = $1", nickname)
+	row := pg.conn.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE nickname = $1", nickname)
 
-	var user domain.User
-	err := row.Scan(&user.ID, &user.Nickname, &user.PasswordHash)
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrUserNotFound
@@ -104,29 +471,1162 @@ func (pg *RepositoryPG) GetUser(ctx context.Context, nickname string) (*domain.U
 		return nil, fmt.Errorf("storage.pg.GetUser: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+func (pg *RepositoryPG) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	row := pg.conn.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE email = $1", email)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("storage.pg.GetUserByEmail: %w", err)
+	}
+
+	return user, nil
 }
 
-func (pg *RepositoryPG) SetSession(ctx context.Context, userID string, session *domain.Session) error {
-	_, err := pg.conn.Exec(ctx, "UPDATE users SET refresh_token = $1, expires_at = $2 WHERE id = $3", session.RefreshToken, session.ExpiresAt, userID)
+// SetEmail changes the email of the user, an empty email removes it.
+func (pg *RepositoryPG) SetEmail(ctx context.Context, userID, email string) error {
+	tag, err := pg.conn.Exec(ctx, "UPDATE users SET email = $2 WHERE id = $1", userID, nullIfEmpty(email))
 	if err != nil {
-		return fmt.Errorf("storage.pg.SetSession: %w", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == usersEmailIndex {
+			return domain.ErrEmailAlreadyExist
+		}
+
+		return fmt.Errorf("storage.pg.SetEmail: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (pg *RepositoryPG) GetBySession(ctx context.Context, refreshToken string) (*domain.User, error) {
-	row := pg.conn.QueryRow(ctx, "SELECT id, nickname FROM users WHERE refresh_token = $1", refreshToken)
+// userColumns is the column list read by scanUser.
+const userColumns = "id, nickname, password_hash, role, COALESCE(email, ''), totp_enabled, plan"
 
+// usersEmailIndex keeps the emails of users unique.
+const usersEmailIndex = "users_email_idx"
+
+func scanUser(row pgx.Row) (*domain.User, error) {
 	var user domain.User
-	err := row.Scan(&user.ID, &user.Nickname)
+	err := row.Scan(&user.ID, &user.Nickname, &user.PasswordHash, &user.Role, &user.Email, &user.TOTPEnabled, &user.Plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ListUsers returns up to limit users with an id greater than afterID, ordered by id.
+func (pg *RepositoryPG) ListUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error) {
+	var after int64
+	if afterID != "" {
+		var err error
+		after, err = strconv.ParseInt(afterID, 10, 64)
+		if err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+	}
+
+	rows, err := pg.conn.Query(ctx, "SELECT "+userColumns+" FROM users WHERE id > $1 ORDER BY id LIMIT $2", after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.ListUsers: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.ListUsers: %w", err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.ListUsers: %w", err)
+	}
+
+	return users, nil
+}
+
+func (pg *RepositoryPG) CountUsers(ctx context.Context) (int64, error) {
+	var count int64
+	err := pg.conn.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("storage.pg.CountUsers: %w", err)
+	}
+
+	return count, nil
+}
+
+func (pg *RepositoryPG) SetUserRole(ctx context.Context, userID string, role domain.Role) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tag, err := pg.conn.Exec(ctx, "UPDATE users SET role = $2 WHERE id = $1", id, string(role))
+	if err != nil {
+		return fmt.Errorf("storage.pg.SetUserRole: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetUserPlan gives domain.ErrPlanNotFound for plans missing in the plans table.
+func (pg *RepositoryPG) SetUserPlan(ctx context.Context, userID, plan string) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tag, err := pg.conn.Exec(ctx, "UPDATE users SET plan = $2 WHERE id = $1", id, plan)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "users_plan_fkey" {
+			return domain.ErrPlanNotFound
+		}
+		return fmt.Errorf("storage.pg.SetUserPlan: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// DeleteUser removes the user with their sessions and API keys, their links
+// are kept as anonymous links.
+func (pg *RepositoryPG) DeleteUser(ctx context.Context, userID string) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tag, err := pg.conn.Exec(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("storage.pg.DeleteUser: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	tag, err := pg.conn.Exec(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2", passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("storage.pg.UpdatePasswordHash: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	userID, ok := parseID(id)
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+
+	row := pg.conn.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", userID)
+
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrUserNotFound
 		}
-		return nil, fmt.Errorf("storage.pg.GetBySession: %w", err)
+
+		return nil, fmt.Errorf("storage.pg.GetUserByID: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+// CreatePasswordReset stores the hash of a reset token of the user, the
+// earlier tokens of the user stop working.
+func (pg *RepositoryPG) CreatePasswordReset(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreatePasswordReset: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM password_resets WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreatePasswordReset: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES ($1, $2, $3)", tokenHash, userID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreatePasswordReset: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreatePasswordReset: %w", err)
+	}
+
+	return nil
+}
+
+// UsePasswordReset marks the reset token as used and returns its user. Used
+// and expired tokens give domain.ErrInvalidResetToken.
+func (pg *RepositoryPG) UsePasswordReset(ctx context.Context, tokenHash string) (string, error) {
+	row := pg.conn.QueryRow(ctx, "UPDATE password_resets SET used_at = now() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() RETURNING user_id", tokenHash)
+
+	var userID string
+	err := row.Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", domain.ErrInvalidResetToken
+		}
+
+		return "", fmt.Errorf("storage.pg.UsePasswordReset: %w", err)
+	}
+
+	return userID, nil
+}
+
+// GetTOTP returns the two-factor state of the user.
+func (pg *RepositoryPG) GetTOTP(ctx context.Context, userID string) (*domain.TOTP, error) {
+	id, ok := parseID(userID)
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+
+	row := pg.conn.QueryRow(ctx, "SELECT COALESCE(totp_secret, ''), totp_enabled, totp_last_step FROM users WHERE id = $1", id)
+
+	var totp domain.TOTP
+	err := row.Scan(&totp.Secret, &totp.Enabled, &totp.LastStep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("storage.pg.GetTOTP: %w", err)
+	}
+
+	return &totp, nil
+}
+
+// SetTOTPSecret stores the secret of a started enrolment, it replaces the
+// secret of an earlier enrolment but not an enabled one.
+func (pg *RepositoryPG) SetTOTPSecret(ctx context.Context, userID, secret string) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tag, err := pg.conn.Exec(ctx, "UPDATE users SET totp_secret = $2, totp_last_step = 0 WHERE id = $1 AND NOT totp_enabled", id, secret)
+	if err != nil {
+		return fmt.Errorf("storage.pg.SetTOTPSecret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// EnableTOTP turns on two-factor authentication with the stored secret,
+// step is the time step of the confirmed code and codeHashes are the hashes
+// of the recovery codes.
+func (pg *RepositoryPG) EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.EnableTOTP: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET totp_enabled = true, totp_last_step = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND NOT totp_enabled`, id, step)
+	if err != nil {
+		return fmt.Errorf("storage.pg.EnableTOTP: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrMFAAlreadyEnabled
+	}
+
+	err = replaceRecoveryCodes(ctx, tx, id, codeHashes)
+	if err != nil {
+		return fmt.Errorf("storage.pg.EnableTOTP: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.EnableTOTP: %w", err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records step as the last accepted time step. A step that is not
+// after the recorded one gives domain.ErrInvalidMFACode, so of two logins
+// with the same code only one passes.
+func (pg *RepositoryPG) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tag, err := pg.conn.Exec(ctx, "UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_enabled AND totp_last_step < $2", id, step)
+	if err != nil {
+		return fmt.Errorf("storage.pg.UseTOTPStep: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// DisableTOTP removes the secret and the recovery codes of the user.
+func (pg *RepositoryPG) DisableTOTP(ctx context.Context, userID string) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.DisableTOTP: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "UPDATE users SET totp_secret = NULL, totp_enabled = false, totp_last_step = 0 WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("storage.pg.DisableTOTP: %w", err)
+	}
+
+	err = replaceRecoveryCodes(ctx, tx, id, nil)
+	if err != nil {
+		return fmt.Errorf("storage.pg.DisableTOTP: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.DisableTOTP: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes stores new recovery codes, the earlier codes of the
+// user stop working.
+func (pg *RepositoryPG) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.ReplaceRecoveryCodes: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = replaceRecoveryCodes(ctx, tx, id, codeHashes)
+	if err != nil {
+		return fmt.Errorf("storage.pg.ReplaceRecoveryCodes: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.ReplaceRecoveryCodes: %w", err)
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string) error {
+	_, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO recovery_codes (user_id, code_hash) SELECT $1::int, unnest($2::text[])", userID, codeHashes)
+	return err
+}
+
+// UseRecoveryCode spends a recovery code of the user, used and unknown codes
+// give domain.ErrInvalidMFACode.
+func (pg *RepositoryPG) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	tag, err := pg.conn.Exec(ctx, "UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", id, codeHash)
+	if err != nil {
+		return fmt.Errorf("storage.pg.UseRecoveryCode: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// RecordLoginLockout adds a lockout to the audit of locked logins.
+func (pg *RepositoryPG) RecordLoginLockout(ctx context.Context, lockout *domain.LoginLockout) error {
+	_, err := pg.conn.Exec(ctx, `INSERT INTO login_lockouts (nickname, ip, failures, locked_at, locked_until)
+		VALUES ($1, $2, $3, $4, $5)`, nullIfEmpty(lockout.Nickname), lockout.IP, lockout.Failures, lockout.LockedAt, lockout.LockedUntil)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RecordLoginLockout: %w", err)
+	}
+
+	return nil
+}
+
+// GetUsage returns the plan of the user with the links and clicks counted in month.
+func (pg *RepositoryPG) GetUsage(ctx context.Context, userID string, month time.Time) (*domain.Usage, error) {
+	id, ok := parseID(userID)
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+
+	var usage domain.Usage
+	err := pg.conn.QueryRow(ctx, `SELECT p.name, COALESCE(p.monthly_links, 0), COALESCE(p.monthly_clicks, 0), p.custom_aliases,
+			COALESCE(m.links, 0), COALESCE(m.clicks, 0)
+		FROM users u
+		JOIN plans p ON p.name = u.plan
+		LEFT JOIN monthly_usage m ON m.user_id = u.id AND m.month = $2
+		WHERE u.id = $1`, id, month).
+		Scan(&usage.Plan.Name, &usage.Plan.MonthlyLinks, &usage.Plan.MonthlyClicks, &usage.Plan.CustomAliases, &usage.Links, &usage.Clicks)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("storage.pg.GetUsage: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// AddLinkUsage counts n links of the user in month unless they do not fit
+// into limit, which gives domain.ErrQuotaExceeded. Zero limit is unlimited.
+func (pg *RepositoryPG) AddLinkUsage(ctx context.Context, userID string, month time.Time, n, limit int64) error {
+	tag, err := pg.conn.Exec(ctx, `INSERT INTO monthly_usage (user_id, month, links)
+		SELECT $1::int, $2::date, $3::int WHERE $4::bigint = 0 OR $3::int <= $4::bigint
+		ON CONFLICT (user_id, month) DO UPDATE SET links = monthly_usage.links + $3
+		WHERE $4::bigint = 0 OR monthly_usage.links + $3 <= $4::bigint`, userID, month, n, limit)
+	if err != nil {
+		return fmt.Errorf("storage.pg.AddLinkUsage: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) RemoveLinkUsage(ctx context.Context, userID string, month time.Time, n int64) error {
+	id, ok := parseID(userID)
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	_, err := pg.conn.Exec(ctx, "UPDATE monthly_usage SET links = GREATEST(links - $3, 0) WHERE user_id = $1 AND month = $2", id, month, n)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RemoveLinkUsage: %w", err)
+	}
+
+	return nil
+}
+
+// TrackClicks counts the clicks of the links in month against the plans of
+// their owners and returns how many clicks of every link are within the
+// quota. Clicks of anonymous and unknown links are all allowed.
+func (pg *RepositoryPG) TrackClicks(ctx context.Context, month time.Time, clicks map[string]int64) (map[string]int64, error) {
+	allowed := make(map[string]int64, len(clicks))
+	shortURLs := make([]string, 0, len(clicks))
+	for shortURL, n := range clicks {
+		allowed[shortURL] = n
+		shortURLs = append(shortURLs, shortURL)
+	}
+
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.TrackClicks: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	type owner struct {
+		limit int64
+		links []string
+	}
+	owners := make(map[int64]*owner)
+	rows, err := tx.Query(ctx, `SELECT s.short_url, s.owner_id, COALESCE(p.monthly_clicks, 0)
+		FROM short_urls s
+		JOIN users u ON u.id = s.owner_id
+		JOIN plans p ON p.name = u.plan
+		WHERE s.short_url = ANY($1)`, shortURLs)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.TrackClicks: %w", err)
+	}
+	for rows.Next() {
+		var shortURL string
+		var ownerID, limit int64
+		err = rows.Scan(&shortURL, &ownerID, &limit)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("storage.pg.TrackClicks: %w", err)
+		}
+		if owners[ownerID] == nil {
+			owners[ownerID] = &owner{limit: limit}
+		}
+		owners[ownerID].links = append(owners[ownerID].links, shortURL)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.TrackClicks: %w", err)
+	}
+
+	// the rows are locked in the order of the owners, so concurrent flushes
+	// do not deadlock
+	ids := make([]int64, 0, len(owners))
+	for id := range owners {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		// the no-op update locks the row of the month
+		var used int64
+		err = tx.QueryRow(ctx, `INSERT INTO monthly_usage (user_id, month) VALUES ($1, $2)
+			ON CONFLICT (user_id, month) DO UPDATE SET clicks = monthly_usage.clicks
+			RETURNING clicks`, id, month).Scan(&used)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.TrackClicks: %w", err)
+		}
+
+		o := owners[id]
+		left := o.limit - used
+		var tracked int64
+		for _, shortURL := range o.links {
+			n := clicks[shortURL]
+			if o.limit > 0 {
+				n = min(n, max(left, 0))
+				left -= n
+			}
+			allowed[shortURL] = n
+			tracked += n
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE monthly_usage SET clicks = clicks + $3 WHERE user_id = $1 AND month = $2", id, month, tracked)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.TrackClicks: %w", err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.TrackClicks: %w", err)
+	}
+
+	return allowed, nil
+}
+
+// CreateSession stores the session with its first refresh token and sets
+// session.ID. Dead sessions of the user are deleted on the way.
+func (pg *RepositoryPG) CreateSession(ctx context.Context, session *domain.Session, tokenHash string) error {
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateSession: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM sessions WHERE user_id = $1 AND (expires_at < now() OR revoked_at IS NOT NULL)", session.UserID)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateSession: %w", err)
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO sessions (user_id, user_agent, ip, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $4, $5) RETURNING id`, session.UserID, session.UserAgent, session.IP, session.CreatedAt, session.ExpiresAt)
+	err = row.Scan(&session.ID)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateSession: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO refresh_tokens (token_hash, session_id, created_at) VALUES ($1, $2, $3)", tokenHash, session.ID, session.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateSession: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateSession: %w", err)
+	}
+
+	return nil
+}
+
+// GetSessionByToken returns the session a refresh token was given to, rotated
+// tokens included.
+func (pg *RepositoryPG) GetSessionByToken(ctx context.Context, tokenHash string) (*domain.Session, error) {
+	row := pg.conn.QueryRow(ctx, "SELECT "+sessionColumns+" FROM sessions s JOIN refresh_tokens t ON t.session_id = s.id WHERE t.token_hash = $1", tokenHash)
+
+	session, err := scanSession(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrInvalidRefreshToken
+		}
+
+		return nil, fmt.Errorf("storage.pg.GetSessionByToken: %w", err)
+	}
+
+	return session, nil
+}
+
+// RotateSession replaces the current refresh token of the session and
+// updates its device and expiration. It returns domain.ErrRefreshTokenReused
+// when oldHash is not the current token, e.g. it was rotated concurrently.
+func (pg *RepositoryPG) RotateSession(ctx context.Context, session *domain.Session, oldHash, newHash string) error {
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RotateSession: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE refresh_tokens SET rotated_at = $3 WHERE token_hash = $1 AND session_id = $2 AND rotated_at IS NULL", oldHash, session.ID, session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RotateSession: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrRefreshTokenReused
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO refresh_tokens (token_hash, session_id, created_at) VALUES ($1, $2, $3)", newHash, session.ID, session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RotateSession: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE sessions SET user_agent = $2, ip = $3, last_used_at = $4, expires_at = $5 WHERE id = $1",
+		session.ID, session.UserAgent, session.IP, session.LastUsedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RotateSession: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RotateSession: %w", err)
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) RevokeSession(ctx context.Context, sessionID string) error {
+	_, err := pg.conn.Exec(ctx, "UPDATE sessions SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL", sessionID)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RevokeSession: %w", err)
+	}
+
+	return nil
+}
+
+// ListSessions returns the active sessions of the user, the most recently used first.
+func (pg *RepositoryPG) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	rows, err := pg.conn.Query(ctx, "SELECT "+sessionColumns+" FROM sessions s WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > now() ORDER BY s.last_used_at DESC, s.id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.ListSessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.ListSessions: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.ListSessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeUserSession revokes an active session of the user, other users'
+// sessions are reported as not found.
+func (pg *RepositoryPG) RevokeUserSession(ctx context.Context, userID, sessionID string) error {
+	id, ok := parseID(sessionID)
+	if !ok {
+		return domain.ErrSessionNotFound
+	}
+
+	tag, err := pg.conn.Exec(ctx, "UPDATE sessions SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > now()", id, userID)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RevokeUserSession: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions revokes the active sessions of the user except keepID
+// and returns their ids.
+func (pg *RepositoryPG) RevokeOtherSessions(ctx context.Context, userID, keepID string) ([]string, error) {
+	// no session has the id 0, so without keepID all of them are revoked
+	keep, _ := parseID(keepID)
+
+	rows, err := pg.conn.Query(ctx, "UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL AND expires_at > now() RETURNING id", userID, keep)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.RevokeOtherSessions: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("storage.pg.RevokeOtherSessions: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.RevokeOtherSessions: %w", err)
+	}
+
+	return ids, nil
+}
+
+// sessionColumns is the column list read by scanSession, sessions are aliased as s.
+const sessionColumns = "s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_used_at, s.expires_at, s.revoked_at"
+
+func scanSession(row pgx.Row) (*domain.Session, error) {
+	var session domain.Session
+	var revokedAt *time.Time
+	err := row.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt != nil {
+		session.RevokedAt = *revokedAt
+	}
+
+	return &session, nil
+}
+
+func (pg *RepositoryPG) CreateAPIKey(ctx context.Context, key *domain.APIKey, keyHash string) error {
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	row := pg.conn.QueryRow(ctx, `INSERT INTO api_keys (user_id, name, key_hash, prefix, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`, key.UserID, key.Name, keyHash, key.Prefix, scopes, key.CreatedAt, nullIfZeroTime(key.ExpiresAt))
+	err := row.Scan(&key.ID)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateAPIKey: %w", err)
+	}
+
+	return nil
+}
+
+// ListAPIKeys returns the keys of the user that are not revoked, the newest first.
+func (pg *RepositoryPG) ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	rows, err := pg.conn.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC, id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.ListAPIKeys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]domain.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.ListAPIKeys: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.ListAPIKeys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key of the user, other users' keys are reported as not found.
+func (pg *RepositoryPG) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	id, ok := parseID(keyID)
+	if !ok {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	tag, err := pg.conn.Exec(ctx, "UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return fmt.Errorf("storage.pg.RevokeAPIKey: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) GetAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	row := pg.conn.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", keyHash)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrAPIKeyNotFound
+		}
+
+		return nil, fmt.Errorf("storage.pg.GetAPIKeyByHash: %w", err)
+	}
+
+	return key, nil
+}
+
+func (pg *RepositoryPG) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	_, err := pg.conn.Exec(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", keyID, usedAt)
+	if err != nil {
+		return fmt.Errorf("storage.pg.TouchAPIKey: %w", err)
+	}
+
+	return nil
+}
+
+// apiKeyColumns is the column list read by scanAPIKey.
+const apiKeyColumns = "id, user_id, name, prefix, scopes, created_at, expires_at, last_used_at, revoked_at"
+
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	var key domain.APIKey
+	var scopes []string
+	var expiresAt, lastUsedAt, revokedAt *time.Time
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = make([]domain.Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, domain.Scope(scope))
+	}
+	if expiresAt != nil {
+		key.ExpiresAt = *expiresAt
+	}
+	if lastUsedAt != nil {
+		key.LastUsedAt = *lastUsedAt
+	}
+	if revokedAt != nil {
+		key.RevokedAt = *revokedAt
+	}
+
+	return &key, nil
+}
+
+// CreateImportJob saves the job with its rows in one transaction. The rows
+// that could not be parsed are saved as invalid.
+func (pg *RepositoryPG) CreateImportJob(ctx context.Context, job domain.ImportJob, rows []domain.ImportRow) error {
+	// COPY sends the values in the binary format, so the id must be a number
+	jobID, err := strconv.ParseInt(job.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateImportJob: %w", err)
+	}
+
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateImportJob: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO import_jobs (id, owner_id, status, total, processed, invalid, created_at, updated_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8)`,
+		jobID, job.OwnerID, job.Status, job.Total, job.Processed, job.Invalid, job.CreatedAt, nullIfZeroTime(job.FinishedAt))
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateImportJob: %w", err)
+	}
+
+	copyRows := make([][]any, 0, len(rows))
+	for _, row := range rows {
+		var status, rowErr any
+		if row.Err != nil {
+			status, rowErr = string(domain.BulkInvalid), row.Err.Error()
+		}
+		copyRows = append(copyRows, []any{jobID, row.Index, row.Params.LongURL, nullIfEmpty(row.Params.Alias),
+			nullIfZeroTime(row.Params.ExpiresAt), nullIfZero(row.Params.MaxClicks), status, rowErr})
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_job_rows"},
+		[]string{"job_id", "row_index", "long_url", "alias", "expires_at", "max_clicks", "status", "error"},
+		pgx.CopyFromRows(copyRows))
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateImportJob: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.CreateImportJob: %w", err)
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) GetImportJob(ctx context.Context, id string) (*domain.ImportJob, error) {
+	row := pg.conn.QueryRow(ctx, "SELECT "+importJobColumns+" FROM import_jobs WHERE id = $1", id)
+
+	job, err := scanImportJob(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrImportJobNotFound
+		}
+
+		return nil, fmt.Errorf("storage.pg.GetImportJob: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListImportErrors returns up to limit rows of the job that were not created
+// nor found, starting after the row index after.
+func (pg *RepositoryPG) ListImportErrors(ctx context.Context, id string, after, limit int) ([]domain.ImportRowResult, error) {
+	rows, err := pg.conn.Query(ctx, `SELECT row_index, status, COALESCE(short_url, ''), long_url, COALESCE(error, '') FROM import_job_rows
+		WHERE job_id = $1 AND status IN ($2, $3) AND row_index > $4
+		ORDER BY row_index LIMIT $5`, id, domain.BulkInvalid, domain.BulkQuotaExceeded, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.ListImportErrors: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]domain.ImportRowResult, 0)
+	for rows.Next() {
+		var result domain.ImportRowResult
+		err := rows.Scan(&result.Index, &result.Status, &result.ShortURL, &result.LongURL, &result.Error)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.ListImportErrors: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.ListImportErrors: %w", err)
+	}
+
+	return results, nil
+}
+
+// ClaimImportJob leases the oldest unfinished job that no one holds until
+// lockedUntil and counts the attempt. Without such a job it gives
+// domain.ErrImportJobNotFound.
+func (pg *RepositoryPG) ClaimImportJob(ctx context.Context, lockedUntil time.Time) (*domain.ImportJob, error) {
+	row := pg.conn.QueryRow(ctx, `UPDATE import_jobs SET status = $1, attempts = attempts + 1, locked_until = $2, updated_at = now()
+		WHERE id = (
+			SELECT id FROM import_jobs
+			WHERE status IN ($3, $1) AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY id LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+importJobColumns, domain.ImportRunning, lockedUntil, domain.ImportPending)
+
+	job, err := scanImportJob(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrImportJobNotFound
+		}
+
+		return nil, fmt.Errorf("storage.pg.ClaimImportJob: %w", err)
+	}
+
+	return job, nil
+}
+
+// NextImportRows returns up to limit rows of the job that have no result yet.
+func (pg *RepositoryPG) NextImportRows(ctx context.Context, id string, limit int) ([]domain.ImportRow, error) {
+	rows, err := pg.conn.Query(ctx, `SELECT row_index, long_url, COALESCE(alias, ''), expires_at, COALESCE(max_clicks, 0) FROM import_job_rows
+		WHERE job_id = $1 AND status IS NULL
+		ORDER BY row_index LIMIT $2`, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage.pg.NextImportRows: %w", err)
+	}
+	defer rows.Close()
+
+	importRows := make([]domain.ImportRow, 0, limit)
+	for rows.Next() {
+		var row domain.ImportRow
+		var expiresAt *time.Time
+		err := rows.Scan(&row.Index, &row.Params.LongURL, &row.Params.Alias, &expiresAt, &row.Params.MaxClicks)
+		if err != nil {
+			return nil, fmt.Errorf("storage.pg.NextImportRows: %w", err)
+		}
+		if expiresAt != nil {
+			row.Params.ExpiresAt = *expiresAt
+		}
+		importRows = append(importRows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.pg.NextImportRows: %w", err)
+	}
+
+	return importRows, nil
+}
+
+// SaveImportResults saves the results of rows of the job with its progress
+// and extends its lease to lockedUntil. The rows that already have a result
+// are left alone, so a chunk saved twice is counted once.
+func (pg *RepositoryPG) SaveImportResults(ctx context.Context, id string, results []domain.ImportRowResult, lockedUntil time.Time) error {
+	tx, err := pg.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.SaveImportResults: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, result := range results {
+		batch.Queue(`UPDATE import_job_rows SET status = $3, short_url = $4, error = $5
+			WHERE job_id = $1 AND row_index = $2 AND status IS NULL`,
+			id, result.Index, result.Status, nullIfEmpty(result.ShortURL), nullIfEmpty(result.Error))
+	}
+
+	batchResults := tx.SendBatch(ctx, batch)
+	counts := make(map[domain.BulkStatus]int)
+	for _, result := range results {
+		tag, err := batchResults.Exec()
+		if err != nil {
+			batchResults.Close()
+			return fmt.Errorf("storage.pg.SaveImportResults: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			counts[result.Status]++
+		}
+	}
+	err = batchResults.Close()
+	if err != nil {
+		return fmt.Errorf("storage.pg.SaveImportResults: %w", err)
+	}
+
+	processed := counts[domain.BulkCreated] + counts[domain.BulkExists] + counts[domain.BulkInvalid] + counts[domain.BulkQuotaExceeded]
+	_, err = tx.Exec(ctx, `UPDATE import_jobs SET processed = processed + $2, created = created + $3, existed = existed + $4,
+		invalid = invalid + $5, quota_exceeded = quota_exceeded + $6, attempts = 0, error = NULL, locked_until = $7, updated_at = now()
+		WHERE id = $1`,
+		id, processed, counts[domain.BulkCreated], counts[domain.BulkExists], counts[domain.BulkInvalid], counts[domain.BulkQuotaExceeded], lockedUntil)
+	if err != nil {
+		return fmt.Errorf("storage.pg.SaveImportResults: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.pg.SaveImportResults: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseImportJob records why the job failed and leaves it to be tried again
+// at retryAt.
+func (pg *RepositoryPG) ReleaseImportJob(ctx context.Context, id, reason string, retryAt time.Time) error {
+	_, err := pg.conn.Exec(ctx, "UPDATE import_jobs SET error = $2, locked_until = $3, updated_at = now() WHERE id = $1",
+		id, nullIfEmpty(reason), retryAt)
+	if err != nil {
+		return fmt.Errorf("storage.pg.ReleaseImportJob: %w", err)
+	}
+
+	return nil
+}
+
+func (pg *RepositoryPG) FinishImportJob(ctx context.Context, id string, status domain.ImportStatus, reason string) error {
+	_, err := pg.conn.Exec(ctx, `UPDATE import_jobs SET status = $2, error = $3, locked_until = NULL, finished_at = now(), updated_at = now()
+		WHERE id = $1`, id, status, nullIfEmpty(reason))
+	if err != nil {
+		return fmt.Errorf("storage.pg.FinishImportJob: %w", err)
+	}
+
+	return nil
+}
+
+// importJobColumns is the column list read by scanImportJob.
+const importJobColumns = "id::text, owner_id::text, status, total, processed, created, existed, invalid, quota_exceeded, attempts, COALESCE(error, ''), created_at, updated_at, finished_at"
+
+func scanImportJob(row pgx.Row) (*domain.ImportJob, error) {
+	var job domain.ImportJob
+	var finishedAt *time.Time
+	err := row.Scan(&job.ID, &job.OwnerID, &job.Status, &job.Total, &job.Processed, &job.Created, &job.Existed, &job.Invalid,
+		&job.QuotaExceeded, &job.Attempts, &job.Error, &job.CreatedAt, &job.UpdatedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if finishedAt != nil {
+		job.FinishedAt = *finishedAt
+	}
+
+	return &job, nil
+}
+
+// urlColumns is the column list read by scanURL.
+const urlColumns = "unique_id, short_url, long_url, COALESCE(owner_id::text, ''), created_at, clicks, expires_at, COALESCE(max_clicks, 0)"
+
+func scanURL(row pgx.Row) (*domain.URL, error) {
+	var link domain.URL
+	var expiresAt *time.Time
+	err := row.Scan(&link.Id, &link.ShortURL, &link.LongURL, &link.OwnerID, &link.CreatedAt, &link.Clicks, &expiresAt, &link.MaxClicks)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt != nil {
+		link.ExpiresAt = *expiresAt
+	}
+
+	return &link, nil
+}
+
+// parseID parses the id of a row, an id that is not a number matches no row.
+func parseID(id string) (int64, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// nullIfEmpty maps an empty optional id to SQL NULL.
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+func nullIfZero(n int64) any {
+	if n == 0 {
+		return nil
+	}
+
+	return n
+}
+
+func nullIfZeroTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
 }
@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+type clickMessage struct {
+	ShortURL   string    `json:"short_url"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Referrer   string    `json:"referrer"`
+	UserAgent  string    `json:"user_agent"`
+	IPNetwork  string    `json:"ip_network"`
+	Browser    string    `json:"browser"`
+	OS         string    `json:"os"`
+	Device     string    `json:"device"`
+	Bot        bool      `json:"bot"`
+	Country    string    `json:"country"`
+	Region     string    `json:"region"`
+}
+
+func clicksChannel(shortURL string) string {
+	return keyPrefix + "live:" + shortURL
+}
+
+func (r *Redis) PublishClick(ctx context.Context, event domain.ClickEvent) error {
+	payload, err := json.Marshal(clickMessage{
+		ShortURL:   event.ShortURL,
+		OccurredAt: event.OccurredAt,
+		Referrer:   event.Referrer,
+		UserAgent:  event.UserAgent,
+		IPNetwork:  event.IPNetwork,
+		Browser:    event.Browser,
+		OS:         event.OS,
+		Device:     event.Device,
+		Bot:        event.Bot,
+		Country:    event.Country,
+		Region:     event.Region,
+	})
+	if err != nil {
+		return fmt.Errorf("redis.PublishClick: %w", err)
+	}
+
+	err = r.client.Publish(ctx, clicksChannel(event.ShortURL), payload).Err()
+	if err != nil {
+		return fmt.Errorf("redis.PublishClick: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) SubscribeClicks(ctx context.Context, shortURL string) error {
+	err := r.clicks.Subscribe(ctx, clicksChannel(shortURL))
+	if err != nil {
+		return fmt.Errorf("redis.SubscribeClicks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) UnsubscribeClicks(ctx context.Context, shortURL string) error {
+	err := r.clicks.Unsubscribe(ctx, clicksChannel(shortURL))
+	if err != nil {
+		return fmt.Errorf("redis.UnsubscribeClicks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) ClickSubscribers(ctx context.Context) (map[string]int64, error) {
+	channels, err := r.client.PubSubChannels(ctx, clicksChannel("*")).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis.ClickSubscribers: %w", err)
+	}
+	if len(channels) == 0 {
+		return map[string]int64{}, nil
+	}
+
+	counts, err := r.client.PubSubNumSub(ctx, channels...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis.ClickSubscribers: %w", err)
+	}
+
+	subscribers := make(map[string]int64, len(counts))
+	for channel, count := range counts {
+		if count > 0 {
+			subscribers[strings.TrimPrefix(channel, clicksChannel(""))] = count
+		}
+	}
+
+	return subscribers, nil
+}
+
+func (r *Redis) ReceiveClicks(ctx context.Context, fn func(domain.ClickEvent)) error {
+	messages := r.clicks.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			var click clickMessage
+			err := json.Unmarshal([]byte(msg.Payload), &click)
+			if err != nil {
+				r.logger.Error("invalid click message", slog.String("channel", msg.Channel), slog.String("error", err.Error()))
+				continue
+			}
+
+			fn(domain.ClickEvent{
+				ShortURL:   click.ShortURL,
+				OccurredAt: click.OccurredAt,
+				Referrer:   click.Referrer,
+				UserAgent:  click.UserAgent,
+				IPNetwork:  click.IPNetwork,
+				Browser:    click.Browser,
+				OS:         click.OS,
+				Device:     click.Device,
+				Bot:        click.Bot,
+				Country:    click.Country,
+				Region:     click.Region,
+			})
+		}
+	}
+}
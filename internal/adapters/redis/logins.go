@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func loginFailuresKey(key string) string {
+	return keyPrefix + "login_failures:" + key
+}
+
+func loginLockKey(key string) string {
+	return keyPrefix + "login_lock:" + key
+}
+
+// addLoginFailureScript counts a failure and starts the window with the first
+// one in a single step, so a count is never left without an expiration.
+var addLoginFailureScript = redis.NewScript(`
+local failures = redis.call("INCR", KEYS[1])
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return failures
+`)
+
+// AddLoginFailure counts a failed login of key and returns the failures since
+// the first one of the window, the count is dropped when the window ends.
+func (r *Redis) AddLoginFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	failures, err := addLoginFailureScript.Run(ctx, r.client, []string{loginFailuresKey(key)}, window.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("redis.AddLoginFailure: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (r *Redis) ResetLoginFailures(ctx context.Context, key string) error {
+	err := r.client.Del(ctx, loginFailuresKey(key)).Err()
+	if err != nil {
+		return fmt.Errorf("redis.ResetLoginFailures: %w", err)
+	}
+
+	return nil
+}
+
+// lockLoginScript sets the lock unless a longer one is set, so that a delay
+// never shortens a lockout.
+var lockLoginScript = redis.NewScript(`
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[1]) then
+	redis.call("SET", KEYS[1], 1, "PX", ARGV[1])
+end
+return 0
+`)
+
+// LockLogin refuses the logins of key for ttl, a longer lock is kept.
+func (r *Redis) LockLogin(ctx context.Context, key string, ttl time.Duration) error {
+	err := lockLoginScript.Run(ctx, r.client, []string{loginLockKey(key)}, ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("redis.LockLogin: %w", err)
+	}
+
+	return nil
+}
+
+// LoginLockedFor returns how long the logins of key stay locked, zero when
+// they are not.
+func (r *Redis) LoginLockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, loginLockKey(key)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis.LoginLockedFor: %w", err)
+	}
+	// negative for missing keys and keys without expiration
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
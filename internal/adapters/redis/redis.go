@@ -16,6 +16,8 @@ const keyPrefix = "urlShortener:"
 type Redis struct {
 	client redis.UniversalClient
 	logger *slog.Logger
+	// clicks is the subscription of the live click stream.
+	clicks *redis.PubSub
 }
 
 func New(hosts []string, password string, logger *slog.Logger) (*Redis, *redis_rate.Limiter, error) {
@@ -40,10 +42,14 @@ func New(hosts []string, password string, logger *slog.Logger) (*Redis, *redis_r
 	return &Redis{
 		client: client,
 		logger: logger,
+		clicks: client.Subscribe(context.Background()),
 	}, rateLimiter, nil
 }
 
 func (rdb *Redis) Close() {
+	if err := rdb.clicks.Close(); err != nil {
+		rdb.logger.Error("error closing subscription:", slog.String("error", err.Error()))
+	}
 	if err := rdb.client.Close(); err != nil {
 		rdb.logger.Error("error closing connection:", slog.String("error", err.Error()))
 	}
@@ -66,6 +72,10 @@ func (r *Redis) Get(ctx context.Context, key any) (value any, err error) {
 	return url, nil
 }
 
+func (r *Redis) Delete(ctx context.Context, key any) error {
+	return r.client.Del(ctx, keyPrefix+anyToString(key)).Err()
+}
+
 func anyToString(v any) string {
 	return fmt.Sprintf("%v", v)
 }
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// revokedSessionKey marks a revoked session until its last access token expires.
+func revokedSessionKey(sessionID string) string {
+	return keyPrefix + "revoked_session:" + sessionID
+}
+
+// DenySessions rejects the access tokens of the sessions for ttl, which should
+// be the access token lifetime.
+func (r *Redis) DenySessions(ctx context.Context, sessionIDs []string, ttl time.Duration) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for _, id := range sessionIDs {
+		pipe.Set(ctx, revokedSessionKey(id), 1, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("redis.DenySessions: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := r.client.Exists(ctx, revokedSessionKey(sessionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis.IsSessionRevoked: %w", err)
+	}
+
+	return n > 0, nil
+}
@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// visitorsKey is the HyperLogLog of the visitors of a link for a UTC day. The
+// link is a hash tag, so all the days of a link share a cluster slot and can
+// be counted together.
+func visitorsKey(shortURL string, day time.Time) string {
+	return keyPrefix + "uv:{" + shortURL + "}:" + day.UTC().Format("20060102")
+}
+
+func (r *Redis) AddVisitors(ctx context.Context, events []domain.ClickEvent, ttl time.Duration) error {
+	visitors := make(map[string][]any)
+	for _, event := range events {
+		if event.VisitorID == "" {
+			continue
+		}
+		key := visitorsKey(event.ShortURL, event.OccurredAt)
+		visitors[key] = append(visitors[key], event.VisitorID)
+	}
+	if len(visitors) == 0 {
+		return nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, ids := range visitors {
+			pipe.PFAdd(ctx, key, ids...)
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis.AddVisitors: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) DailyVisitors(ctx context.Context, shortURL string, days []time.Time) ([]int64, error) {
+	cmds := make([]*redis.IntCmd, 0, len(days))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, day := range days {
+			cmds = append(cmds, pipe.PFCount(ctx, visitorsKey(shortURL, day)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis.DailyVisitors: %w", err)
+	}
+
+	counts := make([]int64, 0, len(cmds))
+	for _, cmd := range cmds {
+		counts = append(counts, cmd.Val())
+	}
+
+	return counts, nil
+}
+
+func (r *Redis) UniqueVisitors(ctx context.Context, shortURL string, days []time.Time) (int64, error) {
+	if len(days) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, 0, len(days))
+	for _, day := range days {
+		keys = append(keys, visitorsKey(shortURL, day))
+	}
+
+	count, err := r.client.PFCount(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis.UniqueVisitors: %w", err)
+	}
+
+	return count, nil
+}
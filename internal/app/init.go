@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 	"time"
@@ -14,14 +15,23 @@ import (
 	"url-shortener/internal/services/represent"
 	"url-shortener/internal/services/uniqueIdGenerator/go-snowflake-master"
 	"url-shortener/pkg/database"
-	"url-shortener/pkg/jwt"
+	"url-shortener/pkg/geoip"
 	"url-shortener/pkg/metrics"
+	"url-shortener/pkg/notify"
+	ratelimiter "url-shortener/pkg/rate-limiter/leaking_bucket"
+	"url-shortener/pkg/useragent"
 )
 
 type App struct {
-	Server   *httpserver.Server
-	Postgres *database.Postgres
-	Redis    *redis.Redis
+	Server     *httpserver.Server
+	Sweeper    *services.Sweeper
+	Imports    *services.Imports
+	Clicks     *services.ClickRecorder
+	Live       *services.ClickStream
+	Classifier *useragent.Classifier
+	GeoIP      *geoip.Reader
+	Postgres   *database.Postgres
+	Redis      *redis.Redis
 }
 
 func InitApp(cfg *config.Config, logger *slog.Logger, metrics *metrics.PrometheusMetrics, noDB *bool) (*App, error) {
@@ -30,40 +40,86 @@ func InitApp(cfg *config.Config, logger *slog.Logger, metrics *metrics.Prometheu
 		return nil, err
 	}
 
-	rds, limiter, err := redis.New(cfg.Redis.Hosts, cfg.Redis.Password, logger)
+	rds, redisLimiter, err := redis.New(cfg.Redis.Hosts, cfg.Redis.Password, logger)
 	if err != nil {
 		return nil, err
 	}
+	failureMode, err := ratelimiter.ParseMode(cfg.Server.RateLimit.FailureMode)
+	if err != nil {
+		return nil, err
+	}
+	limiter := ratelimiter.NewFallback(ratelimiter.NewRedis(redisLimiter), failureMode, cfg.Server.RateLimit.RetryInterval, logger, metrics)
 
 	snowflake.SetStartTime(time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC))
 	snowflake.SetMachineID(1)
-	var serviceURLShortener *services.URLShortener
+	var linksStorage services.Database
+	var clicksStorage services.ClickStorage
+	var visitors services.VisitorCounter
+	// the links of the clicks are looked up in Postgres, so without it the
+	// clicks are not limited by plans
+	var clickUsage services.UsageStorage
 	if *noDB {
-		local := local.New()
-		serviceURLShortener = services.New(logger, rds, local)
+		localRepo := local.New()
+		linksStorage, clicksStorage, visitors = localRepo, localRepo, localRepo
 	} else {
-		serviceURLShortener = services.New(logger, rds, pgrepo.NewRepositoruPG(postgres.GetConn()))
+		pgRepo := pgrepo.NewRepositoruPG(postgres.GetConn())
+		linksStorage, clicksStorage, visitors, clickUsage = pgRepo, pgRepo, rds, pgRepo
+	}
+	usersStorage := pgrepo.NewRepositoruPG(postgres.GetConn())
+	classifier, err := useragent.NewClassifier(cfg.Analytics.UARulesPath, logger)
+	if err != nil {
+		return nil, err
+	}
+	serviceURLShortener := services.New(&cfg.Shortener, logger, rds, linksStorage, usersStorage, classifier)
+	sweeper := services.NewSweeper(logger, linksStorage, cfg.Shortener.SweepInterval)
+	imports := services.NewImports(&cfg.Import, logger, usersStorage, serviceURLShortener)
+	// GeoIP is optional, without a database clicks have no location
+	var geoReader *geoip.Reader
+	var geo services.GeoLocator
+	if cfg.GeoIP.DBPath != "" {
+		geoReader, err = geoip.Open(cfg.GeoIP.DBPath, logger)
+		if err != nil {
+			return nil, err
+		}
+		geo = geoReader
 	}
+	enricher := services.NewClickEnricher(classifier, geo)
+	clickRecorder := services.NewClickRecorder(&cfg.Analytics, logger, clicksStorage, visitors, clickUsage, enricher, metrics)
+	clickStream := services.NewClickStream(logger, linksStorage, rds, enricher, metrics)
+	analytics := services.NewAnalytics(linksStorage, clicksStorage, visitors)
 	representer := represent.New(cfg.TemplatesPath, logger)
 
-	serviceAuth, err := services.NewAuth(&cfg.Auth, pgrepo.NewRepositoruPG(postgres.GetConn()))
+	notifier, err := newNotifier(&cfg.Notify, logger)
 	if err != nil {
 		return nil, err
 	}
-	tokenManager, err := jwt.NewManager(cfg.Auth.JWTSigningKey)
+	serviceAuth, err := services.NewAuth(&cfg.Auth, logger, usersStorage, rds, notifier, rds)
 	if err != nil {
 		return nil, err
 	}
+	tokenManager, err := services.NewTokenManager(&cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := services.NewAPIKeys(logger, usersStorage)
+	admin := services.NewAdmin(&cfg.Auth, logger, serviceURLShortener, usersStorage, rds)
 
-	httpServer, err := httpserver.NewHTTPServer(&cfg.Server, serviceAuth, logger, serviceURLShortener, representer, limiter, metrics, tokenManager)
+	httpServer, err := httpserver.NewHTTPServer(&cfg.Server, serviceAuth, logger, serviceURLShortener, representer, clickRecorder, clickStream, analytics, clickStream, limiter, metrics, tokenManager, rds, apiKeys, apiKeys, admin, imports)
 	if err != nil {
 		return nil, err
 	}
 
 	return &App{
-		Server:   httpServer,
-		Postgres: postgres,
-		Redis:    rds,
+		Server:     httpServer,
+		Sweeper:    sweeper,
+		Imports:    imports,
+		Clicks:     clickRecorder,
+		Live:       clickStream,
+		Classifier: classifier,
+		GeoIP:      geoReader,
+		Postgres:   postgres,
+		Redis:      rds,
 	}, nil
 
 }
@@ -74,6 +130,20 @@ func (a *App) Shutdown() {
 	a.Redis.Close()
 }
 
+// newNotifier chooses how password reset links reach the users.
+func newNotifier(cfg *config.NotifyConfig, logger *slog.Logger) (services.Notifier, error) {
+	switch cfg.Notifier {
+	case "smtp":
+		return notify.NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	case "file":
+		return notify.NewFile(cfg.FilePath), nil
+	case "log":
+		return notify.NewLog(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q, expected smtp, file or log", cfg.Notifier)
+	}
+}
+
 func InitLogger() *slog.Logger {
 	opts := &slog.HandlerOptions{
 		Level: slog.LevelDebug,
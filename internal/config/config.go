@@ -16,6 +16,11 @@ type Config struct {
 	Server        ServerConfig
 	TemplatesPath string `env:"TEMPLATES_PATH" env-required:"true"`
 	Auth          AuthConfig
+	Shortener     ShortenerConfig
+	Import        ImportConfig
+	Analytics     AnalyticsConfig
+	GeoIP         GeoIPConfig
+	Notify        NotifyConfig
 }
 
 type ServerConfig struct {
@@ -24,6 +29,32 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `yaml:"read_timeout" env:"READ_TIMEOUT" env-default:"30s"`
 	WriteTimeout    time.Duration `yaml:"wtite_timeout" env:"WRITE_TIMEOUT" env-default:"30s"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" env-default:"10s"`
+	// TrustedProxies are the addresses or CIDRs of the proxies in front of the
+	// service, X-Forwarded-For and X-Real-IP are only honoured from them.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" env-default:"127.0.0.1/32,::1/128"`
+	RateLimit      RateLimitConfig
+}
+
+// RateLimitConfig holds the policies of the route groups as
+// "<requests>/<period>", e.g. "100/1m", "off" turns a limit off. The requests
+// are counted per API key, user or client IP.
+type RateLimitConfig struct {
+	// Redirect covers the short links and the home page.
+	Redirect string `env:"RATE_LIMIT_REDIRECT" env-default:"600/1m"`
+	Create   string `env:"RATE_LIMIT_CREATE" env-default:"30/1m"`
+	// Auth covers registration, login, refresh and password reset.
+	Auth string `env:"RATE_LIMIT_AUTH" env-default:"20/1m"`
+	// API covers the other routes.
+	API string `env:"RATE_LIMIT_API" env-default:"300/1m"`
+	// Client covers the authenticated routes per client IP, before the token
+	// or the API key is checked.
+	Client string `env:"RATE_LIMIT_CLIENT" env-default:"1200/1m"`
+	// FailureMode is what the limiter does while Redis fails: "open" lets
+	// the requests through, "local" limits them per instance and "closed"
+	// refuses them.
+	FailureMode string `env:"RATE_LIMIT_FAILURE_MODE" env-default:"local"`
+	// RetryInterval is how often Redis is tried again while it fails.
+	RetryInterval time.Duration `env:"RATE_LIMIT_RETRY_INTERVAL" env-default:"5s"`
 }
 
 type PostgresConfig struct {
@@ -38,8 +69,102 @@ type RedisConfig struct {
 type AuthConfig struct {
 	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env-default:"15m"`
 	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
-	PasswordSalt    string        `env:"PASSWORD_SALT" env-required:"true"`
-	JWTSigningKey   string        `env:"JWT_SIGNING_KEY" env-required:"true"`
+	// PasswordSalt is only used to verify the legacy SHA1 password hashes,
+	// they are replaced with Argon2id hashes on the next login.
+	PasswordSalt string `env:"PASSWORD_SALT"`
+	// JWTSigningKey is the HS256 secret, it signs the tokens unless
+	// JWTSigningKeyID names a key of JWTKeysDir.
+	JWTSigningKey string `env:"JWT_SIGNING_KEY"`
+	// JWTPreviousSigningKeys are former HS256 secrets, their tokens are
+	// accepted until they expire.
+	JWTPreviousSigningKeys []string `env:"JWT_PREVIOUS_SIGNING_KEYS"`
+	// JWTKeysDir holds RSA and Ed25519 keys in PEM files named <kid>.pem,
+	// the public ones are published at /.well-known/jwks.json.
+	JWTKeysDir      string `env:"JWT_KEYS_DIR"`
+	JWTSigningKeyID string `env:"JWT_SIGNING_KEY_ID"`
+	// Argon2 memory is in KiB.
+	Argon2Memory      uint32 `env:"PASSWORD_ARGON2_MEMORY" env-default:"65536"`
+	Argon2Iterations  uint32 `env:"PASSWORD_ARGON2_ITERATIONS" env-default:"3"`
+	Argon2Parallelism uint8  `env:"PASSWORD_ARGON2_PARALLELISM" env-default:"2"`
+	// PasswordResetTTL is how long a password reset link works.
+	PasswordResetTTL time.Duration `env:"PASSWORD_RESET_TTL" env-default:"1h"`
+	// PasswordResetURL is the page of the reset link, the token is appended to it.
+	PasswordResetURL string `env:"PASSWORD_RESET_URL" env-default:"http://localhost:8080/reset-password?token="`
+	// TOTPIssuer names the service in authenticator apps.
+	TOTPIssuer string `env:"TOTP_ISSUER" env-default:"URL Shortener"`
+	// MFATokenTTL is how long the second step of a login with two-factor
+	// authentication may take.
+	MFATokenTTL time.Duration `env:"MFA_TOKEN_TTL" env-default:"5m"`
+	// LoginMaxFailures failed logins of a nickname within LoginFailureWindow
+	// lock its logins for LoginLockout, LoginMaxIPFailures does the same for
+	// the IP. The IP limit is higher as many users may share an address.
+	LoginMaxFailures   int64         `env:"LOGIN_MAX_FAILURES" env-default:"10"`
+	LoginMaxIPFailures int64         `env:"LOGIN_MAX_IP_FAILURES" env-default:"100"`
+	LoginFailureWindow time.Duration `env:"LOGIN_FAILURE_WINDOW" env-default:"15m"`
+	LoginLockout       time.Duration `env:"LOGIN_LOCKOUT" env-default:"15m"`
+	// After LoginDelayAfter failures of a nickname the next login waits a
+	// second, the wait doubles with every failure up to LoginMaxDelay.
+	LoginDelayAfter int64         `env:"LOGIN_DELAY_AFTER" env-default:"3"`
+	LoginMaxDelay   time.Duration `env:"LOGIN_MAX_DELAY" env-default:"30s"`
+}
+
+type ShortenerConfig struct {
+	// AllowAnonymous lets unauthenticated clients create links that have no owner.
+	AllowAnonymous bool `env:"ALLOW_ANONYMOUS_LINKS" env-default:"true"`
+	// ReservedAliases can not be used as custom aliases, they protect the service routes.
+	ReservedAliases []string `env:"RESERVED_ALIASES" env-default:"api,user,users,metrics,links,jobs,admin,me,static,assets,health,login,logout,register,favicon.ico,robots.txt,.well-known"`
+	// SweepInterval is how often expired links are moved out of short_urls.
+	SweepInterval time.Duration `env:"EXPIRED_LINKS_SWEEP_INTERVAL" env-default:"10m"`
+}
+
+// ImportConfig tunes the background processing of import jobs.
+type ImportConfig struct {
+	Workers int `env:"IMPORT_WORKERS" env-default:"2"`
+	// PollInterval is how often the workers look for jobs of other instances
+	// and jobs to resume, jobs uploaded here are picked up at once.
+	PollInterval time.Duration `env:"IMPORT_POLL_INTERVAL" env-default:"5s"`
+	// Lease is how long a job stays with a worker without progress, after
+	// it another instance may resume the job.
+	Lease time.Duration `env:"IMPORT_LEASE" env-default:"1m"`
+	// MaxAttempts failed tries in a row fail a job, a failed try is
+	// repeated after RetryDelay.
+	MaxAttempts int           `env:"IMPORT_MAX_ATTEMPTS" env-default:"5"`
+	RetryDelay  time.Duration `env:"IMPORT_RETRY_DELAY" env-default:"30s"`
+}
+
+type AnalyticsConfig struct {
+	// ClickBufferSize is how many click events may wait to be stored, the excess is dropped.
+	ClickBufferSize    int           `env:"CLICK_BUFFER_SIZE" env-default:"10000"`
+	ClickBatchSize     int           `env:"CLICK_BATCH_SIZE" env-default:"500"`
+	ClickFlushInterval time.Duration `env:"CLICK_FLUSH_INTERVAL" env-default:"1s"`
+	// VisitorHashSalt is mixed into visitor hashes, so they can not be matched
+	// back to an address. It must be the same on every instance.
+	VisitorHashSalt  string        `env:"VISITOR_HASH_SALT"`
+	VisitorRetention time.Duration `env:"VISITOR_RETENTION" env-default:"2160h"`
+	// UARulesPath is the user agent classification rules, the file is re-read
+	// every UARulesReloadInterval when it changes.
+	UARulesPath           string        `env:"UA_RULES_PATH" env-default:"config/ua_rules.json"`
+	UARulesReloadInterval time.Duration `env:"UA_RULES_RELOAD_INTERVAL" env-default:"1m"`
+}
+
+// GeoIPConfig enables the location of clicks. Without DBPath clicks have no location.
+type GeoIPConfig struct {
+	// DBPath is a database in the MaxMind DB format, e.g. GeoLite2-City.mmdb.
+	DBPath         string        `env:"GEOIP_DB_PATH"`
+	ReloadInterval time.Duration `env:"GEOIP_RELOAD_INTERVAL" env-default:"1m"`
+}
+
+// NotifyConfig chooses how messages reach the users.
+type NotifyConfig struct {
+	// Notifier is smtp, file or log. file and log are meant for development
+	// and tests, nothing is delivered.
+	Notifier     string `env:"NOTIFIER" env-default:"log"`
+	FilePath     string `env:"NOTIFY_FILE_PATH" env-default:"notifications.jsonl"`
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     int    `env:"SMTP_PORT" env-default:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+	SMTPFrom     string `env:"SMTP_FROM"`
 }
 
 func InitConfig() (*Config, error) {
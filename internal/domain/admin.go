@@ -2,18 +2,102 @@ package domain
 
 import "time"
 
+// Role grants a user access beyond their own links.
+type Role string
+
+const (
+	RoleUser Role = "user"
+	// RoleAdmin manages every link and user.
+	RoleAdmin Role = "admin"
+	// RoleAuditor sees what an admin sees but changes nothing.
+	RoleAuditor Role = "auditor"
+)
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAdmin || r == RoleAuditor
+}
+
 type User struct {
 	ID           string
 	Nickname     string
 	PasswordHash string
+	Role         Role
+	// Email is where password reset links are sent, empty when the user has none.
+	Email string
+	// TOTPEnabled asks for a one-time code after the password on login.
+	TOTPEnabled bool
+	// Plan is the name of the plan of the user.
+	Plan string
+}
+
+// TOTP is the two-factor state of a user. Secret is set on enrolment and
+// Enabled once a code of it is confirmed.
+type TOTP struct {
+	Secret  string
+	Enabled bool
+	// LastStep is the time step of the last accepted code, the codes up to it
+	// are refused.
+	LastStep int64
+}
+
+// LoginLockout records that the logins of a nickname or an IP were locked
+// after too many failures.
+type LoginLockout struct {
+	// Nickname is empty when the IP is locked, otherwise IP is the address of
+	// the failure that locked the nickname.
+	Nickname    string
+	IP          string
+	Failures    int64
+	LockedAt    time.Time
+	LockedUntil time.Time
+}
+
+// UserPage is a page of users ordered by id.
+type UserPage struct {
+	Users []User
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+// GlobalStats sums up the whole service.
+type GlobalStats struct {
+	Users  int64
+	Links  int64
+	Clicks int64
+	// TopLinks are the most clicked links.
+	TopLinks []URL
 }
 
 type Tokens struct {
 	AccessToken  string
 	RefreshToken string
+	// MFAToken is set instead of the other tokens when the user has two-factor
+	// authentication, it is exchanged for them together with a code.
+	MFAToken string
 }
 
+// Session is a login of a user on a device. The refresh token of a session is
+// replaced on every refresh, presenting a replaced token revokes the session.
 type Session struct {
-	RefreshToken string
-	ExpiresAt    time.Time
+	ID         string
+	UserID     string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	// RevokedAt is zero for sessions that are not revoked.
+	RevokedAt time.Time
+}
+
+// Active reports whether the session can still be refreshed at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
+
+// Device describes the client a session is created or refreshed from.
+type Device struct {
+	UserAgent string
+	IP        string
 }
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Scope is a permission of an API key. Access tokens of sessions have every scope.
+type Scope string
+
+const (
+	ScopeLinksRead  Scope = "links:read"
+	ScopeLinksWrite Scope = "links:write"
+	ScopeStatsRead  Scope = "stats:read"
+)
+
+// Scopes are all the scopes an API key can be given.
+var Scopes = []Scope{ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead}
+
+// Valid reports whether s is a known scope.
+func (s Scope) Valid() bool {
+	return slices.Contains(Scopes, s)
+}
+
+// APIKey lets scripts act on behalf of a user without a session. Only a hash
+// of the secret is stored, Prefix is kept to tell the keys apart.
+type APIKey struct {
+	ID     string
+	UserID string
+	Name   string
+	Prefix string
+	Scopes []Scope
+	// ExpiresAt, LastUsedAt and RevokedAt are zero when unset.
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  time.Time
+	CreatedAt  time.Time
+}
+
+// Active reports whether the key can be used at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt))
+}
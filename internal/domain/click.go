@@ -0,0 +1,123 @@
+package domain
+
+import "time"
+
+// ClickEvent is a single redirect of a short link.
+type ClickEvent struct {
+	ShortURL   string
+	OccurredAt time.Time
+	Referrer   string
+	UserAgent  string
+	// IP is the client address. It is only used to derive other fields and is never stored.
+	IP string
+	// IPNetwork is the anonymized client network, /24 for IPv4 and /48 for IPv6.
+	IPNetwork string
+	// VisitorID is a salted hash of the client address and user agent. It is
+	// only used to count unique visitors and is never stored with the event.
+	VisitorID string
+	// Browser, OS and Device are derived from UserAgent. For bots Browser is
+	// the bot name and Device is "bot".
+	Browser string
+	OS      string
+	Device  string
+	Bot     bool
+	// Country (ISO 3166-1) and Region (ISO 3166-2, e.g. "DE-BE") are resolved
+	// from IP, they are empty without a GeoIP database.
+	Country string
+	Region  string
+}
+
+type StatsBucket string
+
+const (
+	StatsBucketHour StatsBucket = "hour"
+	StatsBucketDay  StatsBucket = "day"
+	StatsBucketWeek StatsBucket = "week"
+)
+
+// Truncate returns the start of the bucket t belongs to, weeks start on Monday.
+func (b StatsBucket) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	switch b {
+	case StatsBucketHour:
+		return t.Truncate(time.Hour)
+	case StatsBucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Next returns the start of the bucket following the one starting at t.
+func (b StatsBucket) Next(t time.Time) time.Time {
+	switch b {
+	case StatsBucketHour:
+		return t.Add(time.Hour)
+	case StatsBucketWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// StatsDimension is a property of clicks the stats can be broken down by.
+type StatsDimension string
+
+const (
+	StatsDimensionBrowser StatsDimension = "browser"
+	StatsDimensionOS      StatsDimension = "os"
+	StatsDimensionDevice  StatsDimension = "device"
+	// StatsDimensionBot splits the clicks into "human" and "bot".
+	StatsDimensionBot     StatsDimension = "bot"
+	StatsDimensionCountry StatsDimension = "country"
+	StatsDimensionRegion  StatsDimension = "region"
+)
+
+// StatsQuery selects the clicks in [From, To) grouped by Bucket. A non-empty
+// GroupBy also breaks the clicks of the whole range down by that dimension.
+type StatsQuery struct {
+	From    time.Time
+	To      time.Time
+	Bucket  StatsBucket
+	GroupBy StatsDimension
+}
+
+// StatsPoint counts the clicks of a bucket, HumanClicks leaves bots out.
+type StatsPoint struct {
+	Start       time.Time
+	Clicks      int64
+	HumanClicks int64
+}
+
+// BreakdownItem counts the clicks with a value of the StatsQuery.GroupBy dimension.
+type BreakdownItem struct {
+	Value       string
+	Clicks      int64
+	HumanClicks int64
+}
+
+type LinkStats struct {
+	ShortURL string
+	// Query is the query with the defaults applied.
+	Query StatsQuery
+	// AllTimeClicks counts every redirect since the link was created.
+	AllTimeClicks int64
+	// TotalClicks counts the redirects within the queried range.
+	TotalClicks int64
+	// HumanClicks is TotalClicks without bots.
+	HumanClicks int64
+	Series      []StatsPoint
+	// Breakdown is filled when the query has GroupBy, most clicked values first.
+	Breakdown []BreakdownItem
+	// UniqueVisitors is the approximate number of distinct visitors within the
+	// days of the queried range.
+	UniqueVisitors int64
+	DailyVisitors  []VisitorsPoint
+}
+
+// VisitorsPoint is the approximate number of distinct visitors of a UTC day.
+type VisitorsPoint struct {
+	Day      time.Time
+	Visitors int64
+}
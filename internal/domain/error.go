@@ -1,11 +1,58 @@
 package domain
 
-import "errors"
-
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrInvalidCredentials   = errors.New("invalid credentials")
-	ErrNicknameAlreadyExist = errors.New("nickname already exist")
-	ErrUserNotFound         = errors.New("user not found by refresh token")
-	ErrOriginalURLNotFound = errors.New("url doesn't exist")
-)
\ No newline at end of file
+	ErrInvalidCredentials     = errors.New("invalid credentials")
+	ErrNicknameAlreadyExist   = errors.New("nickname already exist")
+	ErrUserNotFound           = errors.New("user not found by refresh token")
+	ErrInvalidRefreshToken    = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused     = errors.New("refresh token was already used, the session is revoked")
+	ErrSessionNotFound        = errors.New("session not found")
+	ErrEmailAlreadyExist      = errors.New("email already exist")
+	ErrInvalidResetToken      = errors.New("invalid or expired password reset token")
+	ErrLoginLocked            = errors.New("too many failed login attempts, try again later")
+	ErrInvalidMFAToken        = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode         = errors.New("invalid two-factor code")
+	ErrMFAAlreadyEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled          = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled         = errors.New("two-factor enrolment was not started")
+	ErrAPIKeyNotFound         = errors.New("api key not found")
+	ErrInvalidAPIKey          = errors.New("invalid api key")
+	ErrInvalidAPIKeyParams    = errors.New("invalid api key parameters")
+	ErrInvalidRole            = errors.New("invalid role")
+	ErrPlanNotFound           = errors.New("plan not found")
+	ErrQuotaExceeded          = errors.New("monthly quota exhausted")
+	ErrNotInPlan              = errors.New("not included in the plan")
+	ErrOriginalURLNotFound    = errors.New("url doesn't exist")
+	ErrAnonymousLinksDisabled = errors.New("anonymous links are disabled, sign in to shorten urls")
+	ErrNotLinkOwner           = errors.New("link belongs to another user")
+	ErrInvalidCursor          = errors.New("invalid cursor")
+	ErrShortURLAlreadyExist   = errors.New("short url already exist")
+	ErrInvalidAlias           = errors.New("invalid alias")
+	ErrInvalidURL             = errors.New("invalid url")
+	ErrInvalidBulk            = errors.New("invalid bulk request")
+	ErrAliasTaken             = errors.New("alias is already taken")
+	ErrImportJobNotFound      = errors.New("import job not found")
+	ErrInvalidImport          = errors.New("invalid import")
+	ErrInvalidExpiration      = errors.New("invalid expiration")
+	ErrLinkExpired            = errors.New("link expired")
+	ErrInvalidStatsQuery      = errors.New("invalid stats query")
+)
+
+// LoginLockedError is ErrLoginLocked with the time until the logins are
+// allowed again.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrLoginLocked
+}
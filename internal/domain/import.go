@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// MaxImportRows bounds the rows of an import job.
+const MaxImportRows = 1000000
+
+type ImportStatus string
+
+const (
+	ImportPending ImportStatus = "pending"
+	ImportRunning ImportStatus = "running"
+	ImportDone    ImportStatus = "done"
+	// ImportFailed is a job given up after repeated failures, the rows
+	// processed before stay created.
+	ImportFailed ImportStatus = "failed"
+)
+
+// ImportJob creates the links of an uploaded file in the background. Its
+// rows are stored with it, so a job is resumed after a restart.
+type ImportJob struct {
+	ID      string
+	OwnerID string
+	Status  ImportStatus
+	// Total is the number of rows, Processed those with a result.
+	Total         int
+	Processed     int
+	Created       int
+	Existed       int
+	Invalid       int
+	QuotaExceeded int
+	// Attempts counts the tries of the job since its last progress.
+	Attempts int
+	// Error is the last failure of the job.
+	Error      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt time.Time
+}
+
+// ImportRow is a row of an import job, Err is set when it could not be parsed.
+type ImportRow struct {
+	Index  int
+	Params LinkParams
+	Err    error
+}
+
+// ImportRowResult is the outcome of a row of an import job.
+type ImportRowResult struct {
+	Index    int
+	Status   BulkStatus
+	ShortURL string
+	LongURL  string
+	Error    string
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultPlan is the plan of new users.
+const DefaultPlan = "free"
+
+// Plan is what a user may do per calendar month (UTC). Zero limits are unlimited.
+type Plan struct {
+	Name          string
+	MonthlyLinks  int64
+	MonthlyClicks int64
+	// CustomAliases allows links with a chosen short code.
+	CustomAliases bool
+}
+
+// Usage is what a user used of their plan in a month.
+type Usage struct {
+	Plan Plan
+	// Month is the start of the month, ResetsAt the start of the next one.
+	Month    time.Time
+	ResetsAt time.Time
+	Links    int64
+	// Clicks counts the clicks of the links of the user that were tracked,
+	// clicks over the quota still redirect but are not stored.
+	Clicks int64
+}
+
+type Quota string
+
+const (
+	QuotaLinks  Quota = "links"
+	QuotaClicks Quota = "clicks"
+)
+
+// QuotaExceededError is ErrQuotaExceeded with the quota that is used up.
+type QuotaExceededError struct {
+	Quota    Quota
+	Limit    int64
+	ResetsAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s: the plan allows %d %s per month", ErrQuotaExceeded, e.Limit, e.Quota)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
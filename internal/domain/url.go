@@ -1,7 +1,112 @@
 package domain
 
-type URL struct{
-	Id string
+import "time"
+
+type URL struct {
+	Id       string
 	ShortURL string
+	LongURL  string
+	// OwnerID is the id of the user who created the link, empty for anonymous links.
+	OwnerID   string
+	CreatedAt time.Time
+	Clicks    int64
+	// ExpiresAt is zero for links that never expire.
+	ExpiresAt time.Time
+	// MaxClicks is the number of redirects the link serves, zero means unlimited.
+	MaxClicks int64
+}
+
+// Expired reports whether the link stopped redirecting at the given time.
+func (u *URL) Expired(now time.Time) bool {
+	if !u.ExpiresAt.IsZero() && !now.Before(u.ExpiresAt) {
+		return true
+	}
+
+	return u.MaxClicks > 0 && u.Clicks >= u.MaxClicks
+}
+
+// Expiring reports whether the link has any expiration rule.
+func (u *URL) Expiring() bool {
+	return !u.ExpiresAt.IsZero() || u.MaxClicks > 0
+}
+
+// LinkParams describes a link to create.
+type LinkParams struct {
+	OwnerID string
 	LongURL string
-}
\ No newline at end of file
+	// Alias is the requested short code, a code is generated when empty.
+	Alias     string
+	ExpiresAt time.Time
+	MaxClicks int64
+}
+
+// MaxBulkLinks bounds the items of a bulk creation.
+const MaxBulkLinks = 1000
+
+type BulkStatus string
+
+const (
+	BulkCreated BulkStatus = "created"
+	// BulkExists is a destination the owner already shortened, or a repeated item.
+	BulkExists        BulkStatus = "exists"
+	BulkInvalid       BulkStatus = "invalid"
+	BulkQuotaExceeded BulkStatus = "quota_exceeded"
+)
+
+// BulkResult is the outcome of an item of a bulk creation.
+type BulkResult struct {
+	Status BulkStatus
+	// Link is set for created and existing links.
+	Link *URL
+	// Err tells why the item was not created.
+	Err error
+}
+
+// LinkChange records a destination replaced by ChangedBy.
+type LinkChange struct {
+	ShortURL string
+	// LongURL is the destination before the change.
+	LongURL   string
+	ChangedBy string
+	ChangedAt time.Time
+}
+
+type LinkSort string
+
+const (
+	LinkSortCreatedAt LinkSort = "created_at"
+	LinkSortClicks    LinkSort = "clicks"
+)
+
+// LinkFilter selects a page of links owned by a single user.
+type LinkFilter struct {
+	OwnerID string
+	// AnyOwner ignores OwnerID and selects the links of every user, anonymous
+	// links included. It is meant for administrators.
+	AnyOwner bool
+	// Host matches the host of the destination url, case-insensitively.
+	Host string
+	// CreatedFrom and CreatedTo bound the creation time as [from, to), zero means unbounded.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	SortBy      LinkSort
+	Ascending   bool
+	Limit       int
+	// After continues the listing right after the given position.
+	After *LinkCursor
+}
+
+// LinkCursor is the position of the last link of a page in the requested ordering.
+type LinkCursor struct {
+	SortBy    LinkSort  `json:"s"`
+	Ascending bool      `json:"a"`
+	CreatedAt time.Time `json:"t,omitempty"`
+	Clicks    int64     `json:"c,omitempty"`
+	ID        string    `json:"i"`
+}
+
+type LinkPage struct {
+	Links []URL
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
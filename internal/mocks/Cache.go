@@ -14,6 +14,24 @@ type Cache struct {
 	mock.Mock
 }
 
+// Delete provides a mock function with given fields: ctx, key
+func (_m *Cache) Delete(ctx context.Context, key interface{}) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: ctx, key
 func (_m *Cache) Get(ctx context.Context, key interface{}) (interface{}, error) {
 	ret := _m.Called(ctx, key)
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 	domain "url-shortener/internal/domain"
 
 	mock "github.com/stretchr/testify/mock"
@@ -14,6 +15,62 @@ type Database struct {
 	mock.Mock
 }
 
+// ArchiveExpiredUrls provides a mock function with given fields: ctx, now
+func (_m *Database) ArchiveExpiredUrls(ctx context.Context, now time.Time) (int64, error) {
+	ret := _m.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ArchiveExpiredUrls")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountClicks provides a mock function with given fields: ctx
+func (_m *Database) CountClicks(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountClicks")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteShortUrl provides a mock function with given fields: ctx, shortURL
 func (_m *Database) DeleteShortUrl(ctx context.Context, shortURL string) error {
 	ret := _m.Called(ctx, shortURL)
@@ -32,9 +89,9 @@ func (_m *Database) DeleteShortUrl(ctx context.Context, shortURL string) error {
 	return r0
 }
 
-// GetByLongUrl provides a mock function with given fields: ctx, url
-func (_m *Database) GetByLongUrl(ctx context.Context, url string) (*domain.URL, error) {
-	ret := _m.Called(ctx, url)
+// GetByLongUrl provides a mock function with given fields: ctx, ownerID, url
+func (_m *Database) GetByLongUrl(ctx context.Context, ownerID string, url string) (*domain.URL, error) {
+	ret := _m.Called(ctx, ownerID, url)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetByLongUrl")
@@ -42,19 +99,49 @@ func (_m *Database) GetByLongUrl(ctx context.Context, url string) (*domain.URL,
 
 	var r0 *domain.URL
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*domain.URL, error)); ok {
-		return rf(ctx, url)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*domain.URL, error)); ok {
+		return rf(ctx, ownerID, url)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *domain.URL); ok {
-		r0 = rf(ctx, url)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *domain.URL); ok {
+		r0 = rf(ctx, ownerID, url)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*domain.URL)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, url)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, ownerID, url)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByLongUrls provides a mock function with given fields: ctx, ownerID, urls
+func (_m *Database) GetByLongUrls(ctx context.Context, ownerID string, urls []string) (map[string]domain.URL, error) {
+	ret := _m.Called(ctx, ownerID, urls)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByLongUrls")
+	}
+
+	var r0 map[string]domain.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) (map[string]domain.URL, error)); ok {
+		return rf(ctx, ownerID, urls)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) map[string]domain.URL); ok {
+		r0 = rf(ctx, ownerID, urls)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]domain.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, ownerID, urls)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -90,6 +177,36 @@ func (_m *Database) GetCountShortUrls(ctx context.Context) (int, error) {
 	return r0, r1
 }
 
+// GetLinkHistory provides a mock function with given fields: ctx, shortURL
+func (_m *Database) GetLinkHistory(ctx context.Context, shortURL string) ([]domain.LinkChange, error) {
+	ret := _m.Called(ctx, shortURL)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLinkHistory")
+	}
+
+	var r0 []domain.LinkChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]domain.LinkChange, error)); ok {
+		return rf(ctx, shortURL)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []domain.LinkChange); ok {
+		r0 = rf(ctx, shortURL)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.LinkChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, shortURL)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetShortUrl provides a mock function with given fields: ctx, url
 func (_m *Database) GetShortUrl(ctx context.Context, url string) (*domain.URL, error) {
 	ret := _m.Called(ctx, url)
@@ -120,6 +237,24 @@ func (_m *Database) GetShortUrl(ctx context.Context, url string) (*domain.URL, e
 	return r0, r1
 }
 
+// IncrementClicks provides a mock function with given fields: ctx, shortURL
+func (_m *Database) IncrementClicks(ctx context.Context, shortURL string) error {
+	ret := _m.Called(ctx, shortURL)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrementClicks")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, shortURL)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // InsertUrl provides a mock function with given fields: ctx, url
 func (_m *Database) InsertUrl(ctx context.Context, url domain.URL) error {
 	ret := _m.Called(ctx, url)
@@ -138,6 +273,96 @@ func (_m *Database) InsertUrl(ctx context.Context, url domain.URL) error {
 	return r0
 }
 
+// InsertUrls provides a mock function with given fields: ctx, urls
+func (_m *Database) InsertUrls(ctx context.Context, urls []domain.URL) ([]string, error) {
+	ret := _m.Called(ctx, urls)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertUrls")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.URL) ([]string, error)); ok {
+		return rf(ctx, urls)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.URL) []string); ok {
+		r0 = rf(ctx, urls)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []domain.URL) error); ok {
+		r1 = rf(ctx, urls)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLinks provides a mock function with given fields: ctx, filter
+func (_m *Database) ListLinks(ctx context.Context, filter domain.LinkFilter) ([]domain.URL, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListLinks")
+	}
+
+	var r0 []domain.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.LinkFilter) ([]domain.URL, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.LinkFilter) []domain.URL); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.LinkFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLongUrl provides a mock function with given fields: ctx, shortURL, longURL, changedBy
+func (_m *Database) UpdateLongUrl(ctx context.Context, shortURL string, longURL string, changedBy string) (*domain.URL, error) {
+	ret := _m.Called(ctx, shortURL, longURL, changedBy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateLongUrl")
+	}
+
+	var r0 *domain.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*domain.URL, error)); ok {
+		return rf(ctx, shortURL, longURL, changedBy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *domain.URL); ok {
+		r0 = rf(ctx, shortURL, longURL, changedBy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, shortURL, longURL, changedBy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewDatabase creates a new instance of Database. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewDatabase(t interface {
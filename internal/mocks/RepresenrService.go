@@ -13,6 +13,11 @@ type RepresenrService struct {
 	mock.Mock
 }
 
+// Expired provides a mock function with given fields: _a0
+func (_m *RepresenrService) Expired(_a0 http.ResponseWriter) {
+	_m.Called(_a0)
+}
+
 // Home provides a mock function with given fields: _a0
 func (_m *RepresenrService) Home(_a0 http.ResponseWriter) {
 	_m.Called(_a0)
@@ -14,9 +14,9 @@ type URLShortenerService struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: ctx, url
-func (_m *URLShortenerService) Create(ctx context.Context, url string) (*domain.URL, int, error) {
-	ret := _m.Called(ctx, url)
+// Create provides a mock function with given fields: ctx, params
+func (_m *URLShortenerService) Create(ctx context.Context, params domain.LinkParams) (*domain.URL, int, error) {
+	ret := _m.Called(ctx, params)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
@@ -25,25 +25,25 @@ func (_m *URLShortenerService) Create(ctx context.Context, url string) (*domain.
 	var r0 *domain.URL
 	var r1 int
 	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*domain.URL, int, error)); ok {
-		return rf(ctx, url)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.LinkParams) (*domain.URL, int, error)); ok {
+		return rf(ctx, params)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *domain.URL); ok {
-		r0 = rf(ctx, url)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.LinkParams) *domain.URL); ok {
+		r0 = rf(ctx, params)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*domain.URL)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) int); ok {
-		r1 = rf(ctx, url)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.LinkParams) int); ok {
+		r1 = rf(ctx, params)
 	} else {
 		r1 = ret.Get(1).(int)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
-		r2 = rf(ctx, url)
+	if rf, ok := ret.Get(2).(func(context.Context, domain.LinkParams) error); ok {
+		r2 = rf(ctx, params)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -51,17 +51,54 @@ func (_m *URLShortenerService) Create(ctx context.Context, url string) (*domain.
 	return r0, r1, r2
 }
 
-// DeleteShortUrl provides a mock function with given fields: ctx, shortUrl
-func (_m *URLShortenerService) DeleteShortUrl(ctx context.Context, shortUrl string) error {
-	ret := _m.Called(ctx, shortUrl)
+// CreateBulk provides a mock function with given fields: ctx, ownerID, params
+func (_m *URLShortenerService) CreateBulk(ctx context.Context, ownerID string, params []domain.LinkParams) ([]domain.BulkResult, int, error) {
+	ret := _m.Called(ctx, ownerID, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBulk")
+	}
+
+	var r0 []domain.BulkResult
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []domain.LinkParams) ([]domain.BulkResult, int, error)); ok {
+		return rf(ctx, ownerID, params)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []domain.LinkParams) []domain.BulkResult); ok {
+		r0 = rf(ctx, ownerID, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.BulkResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []domain.LinkParams) int); ok {
+		r1 = rf(ctx, ownerID, params)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, []domain.LinkParams) error); ok {
+		r2 = rf(ctx, ownerID, params)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// DeleteShortUrl provides a mock function with given fields: ctx, userID, shortUrl
+func (_m *URLShortenerService) DeleteShortUrl(ctx context.Context, userID string, shortUrl string) error {
+	ret := _m.Called(ctx, userID, shortUrl)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteShortUrl")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, shortUrl)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, shortUrl)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -69,9 +106,9 @@ func (_m *URLShortenerService) DeleteShortUrl(ctx context.Context, shortUrl stri
 	return r0
 }
 
-// GetOriginalURL provides a mock function with given fields: ctx, shortUrl
-func (_m *URLShortenerService) GetOriginalURL(ctx context.Context, shortUrl string) (string, error) {
-	ret := _m.Called(ctx, shortUrl)
+// GetOriginalURL provides a mock function with given fields: ctx, shortUrl, userAgent
+func (_m *URLShortenerService) GetOriginalURL(ctx context.Context, shortUrl string, userAgent string) (string, error) {
+	ret := _m.Called(ctx, shortUrl, userAgent)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetOriginalURL")
@@ -79,17 +116,137 @@ func (_m *URLShortenerService) GetOriginalURL(ctx context.Context, shortUrl stri
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
-		return rf(ctx, shortUrl)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, shortUrl, userAgent)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = rf(ctx, shortUrl)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, shortUrl, userAgent)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, shortUrl, userAgent)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkHistory provides a mock function with given fields: ctx, userID, shortUrl
+func (_m *URLShortenerService) LinkHistory(ctx context.Context, userID string, shortUrl string) ([]domain.LinkChange, error) {
+	ret := _m.Called(ctx, userID, shortUrl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LinkHistory")
+	}
+
+	var r0 []domain.LinkChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]domain.LinkChange, error)); ok {
+		return rf(ctx, userID, shortUrl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []domain.LinkChange); ok {
+		r0 = rf(ctx, userID, shortUrl)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.LinkChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userID, shortUrl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLinks provides a mock function with given fields: ctx, filter, cursor
+func (_m *URLShortenerService) ListLinks(ctx context.Context, filter domain.LinkFilter, cursor string) (*domain.LinkPage, error) {
+	ret := _m.Called(ctx, filter, cursor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListLinks")
+	}
+
+	var r0 *domain.LinkPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.LinkFilter, string) (*domain.LinkPage, error)); ok {
+		return rf(ctx, filter, cursor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.LinkFilter, string) *domain.LinkPage); ok {
+		r0 = rf(ctx, filter, cursor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.LinkPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.LinkFilter, string) error); ok {
+		r1 = rf(ctx, filter, cursor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLongURL provides a mock function with given fields: ctx, userID, shortUrl, longURL
+func (_m *URLShortenerService) UpdateLongURL(ctx context.Context, userID string, shortUrl string, longURL string) (*domain.URL, error) {
+	ret := _m.Called(ctx, userID, shortUrl, longURL)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateLongURL")
+	}
+
+	var r0 *domain.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*domain.URL, error)); ok {
+		return rf(ctx, userID, shortUrl, longURL)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *domain.URL); ok {
+		r0 = rf(ctx, userID, shortUrl, longURL)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, userID, shortUrl, longURL)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Usage provides a mock function with given fields: ctx, userID
+func (_m *URLShortenerService) Usage(ctx context.Context, userID string) (*domain.Usage, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Usage")
+	}
+
+	var r0 *domain.Usage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*domain.Usage, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *domain.Usage); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Usage)
+		}
+	}
+
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, shortUrl)
+		r1 = rf(ctx, userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,228 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/ports/httpServer/request"
+	"url-shortener/internal/ports/httpServer/response"
+)
+
+type AdminService interface {
+	ListLinks(ctx context.Context, filter domain.LinkFilter, cursor string) (*domain.LinkPage, error)
+	UpdateLink(ctx context.Context, adminID string, shortUrl string, longURL string) (*domain.URL, error)
+	DeleteLink(ctx context.Context, shortUrl string) error
+	LinkHistory(ctx context.Context, shortUrl string) ([]domain.LinkChange, error)
+	ListUsers(ctx context.Context, cursor string, limit int) (*domain.UserPage, error)
+	SetRole(ctx context.Context, adminID, userID string, role domain.Role) error
+	SetPlan(ctx context.Context, userID, plan string) error
+	DeleteUser(ctx context.Context, adminID, userID string) error
+	Stats(ctx context.Context) (*domain.GlobalStats, error)
+}
+
+// AdminHandler serves the endpoints of admins and auditors, the role is
+// checked by the router.
+type AdminHandler struct {
+	logger *slog.Logger
+	admin  AdminService
+}
+
+func NewAdminHandler(logger *slog.Logger, admin AdminService) *AdminHandler {
+	return &AdminHandler{
+		logger: logger,
+		admin:  admin,
+	}
+}
+
+// ListLinks returns the links of every user, the owner query parameter narrows them to one user.
+func (h *AdminHandler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseLinkFilter(r)
+	if err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	filter.OwnerID = r.URL.Query().Get("owner")
+
+	page, err := h.admin.ListLinks(r.Context(), filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		h.adminError(w, "failed to list links", err)
+		return
+	}
+
+	links := make([]adminLinkResponse, 0, len(page.Links))
+	for _, link := range page.Links {
+		links = append(links, newAdminLinkResponse(link))
+	}
+
+	body := map[string]any{
+		"links":       links,
+		"next_cursor": page.NextCursor,
+	}
+	response.ResultJSON(w, http.StatusOK, body)
+}
+
+func (h *AdminHandler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	var input request.UpdateLinkRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	if input.URL == "" {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": "field url is a required field"})
+		return
+	}
+
+	link, err := h.admin.UpdateLink(r.Context(), r.Header.Get("user_id"), r.PathValue("shortUrl"), input.URL)
+	if err != nil {
+		h.adminError(w, "failed to update link", err)
+		return
+	}
+
+	response.ResultJSON(w, http.StatusOK, map[string]any{"link": newAdminLinkResponse(*link)})
+}
+
+func (h *AdminHandler) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	err := h.admin.DeleteLink(r.Context(), r.PathValue("shortUrl"))
+	if err != nil {
+		h.adminError(w, "failed to delete link", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) LinkHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := h.admin.LinkHistory(r.Context(), r.PathValue("shortUrl"))
+	if err != nil {
+		h.adminError(w, "failed to get link history", err)
+		return
+	}
+
+	changes := make([]linkChangeResponse, 0, len(history))
+	for _, change := range history {
+		changes = append(changes, linkChangeResponse{
+			OriginalURL: change.LongURL,
+			ChangedBy:   change.ChangedBy,
+			ChangedAt:   change.ChangedAt,
+		})
+	}
+
+	response.ResultJSON(w, http.StatusOK, map[string]any{"history": changes})
+}
+
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit := defaultLinksPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 100 {
+			response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": "limit must be a number between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	page, err := h.admin.ListUsers(r.Context(), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		h.adminError(w, "failed to list users", err)
+		return
+	}
+
+	users := make([]userResponse, 0, len(page.Users))
+	for _, user := range page.Users {
+		users = append(users, userResponse{ID: user.ID, Nickname: user.Nickname, Role: string(user.Role), Plan: user.Plan})
+	}
+
+	body := map[string]any{
+		"users":       users,
+		"next_cursor": page.NextCursor,
+	}
+	response.ResultJSON(w, http.StatusOK, body)
+}
+
+// SetRole changes the role of a user, the user has to log in again.
+func (h *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	var input request.SetRoleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	err := h.admin.SetRole(r.Context(), r.Header.Get("user_id"), r.PathValue("userID"), domain.Role(input.Role))
+	if err != nil {
+		h.adminError(w, "failed to set role", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetPlan changes the plan of a user, it applies to their next links and clicks.
+func (h *AdminHandler) SetPlan(w http.ResponseWriter, r *http.Request) {
+	var input request.SetPlanRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	err := h.admin.SetPlan(r.Context(), r.PathValue("userID"), input.Plan)
+	if err != nil {
+		h.adminError(w, "failed to set plan", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	err := h.admin.DeleteUser(r.Context(), r.Header.Get("user_id"), r.PathValue("userID"))
+	if err != nil {
+		h.adminError(w, "failed to delete user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.admin.Stats(r.Context())
+	if err != nil {
+		h.adminError(w, "failed to get stats", err)
+		return
+	}
+
+	top := make([]adminLinkResponse, 0, len(stats.TopLinks))
+	for _, link := range stats.TopLinks {
+		top = append(top, newAdminLinkResponse(link))
+	}
+
+	body := map[string]any{
+		"users":     stats.Users,
+		"links":     stats.Links,
+		"clicks":    stats.Clicks,
+		"top_links": top,
+	}
+	response.ResultJSON(w, http.StatusOK, body)
+}
+
+func (h *AdminHandler) adminError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCursor), errors.Is(err, domain.ErrInvalidRole),
+		errors.Is(err, domain.ErrPlanNotFound):
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+	case errors.Is(err, domain.ErrOriginalURLNotFound):
+		response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": err.Error()})
+	case errors.Is(err, domain.ErrUserNotFound):
+		response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": "user not found"})
+	default:
+		h.logger.Error(msg, slog.String("error", err.Error()))
+		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": msg})
+	}
+}
@@ -0,0 +1,192 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/ports/httpServer/response"
+	"url-shortener/pkg/metrics"
+)
+
+type AnalyticsService interface {
+	Stats(ctx context.Context, userID string, shortURL string, query domain.StatsQuery) (*domain.LinkStats, error)
+	ExportClicks(ctx context.Context, userID string, shortURL string, from, to time.Time, fn func(domain.ClickEvent) error) error
+	ExportLinks(ctx context.Context, userID string, fn func(domain.URL) error) error
+}
+
+type ClickStreamService interface {
+	Subscribe(ctx context.Context, userID string, shortURL string) (<-chan domain.ClickEvent, func(), error)
+}
+
+type AnalyticsHandler struct {
+	logger    *slog.Logger
+	analytics AnalyticsService
+	stream    ClickStreamService
+	metrics   *metrics.PrometheusMetrics
+}
+
+func NewAnalyticsHandler(logger *slog.Logger, analytics AnalyticsService, stream ClickStreamService, metrics *metrics.PrometheusMetrics) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		logger:    logger,
+		analytics: analytics,
+		stream:    stream,
+		metrics:   metrics,
+	}
+}
+
+// Stats returns click totals and a time series of a link of the authenticated user.
+func (h *AnalyticsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	query, err := parseStatsQuery(r)
+	if err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	stats, err := h.analytics.Stats(r.Context(), r.Header.Get("user_id"), r.PathValue("shortUrl"), query)
+	if err != nil {
+		h.analyticsError(w, "failed to get link stats", err)
+		return
+	}
+
+	series := make([]statsPointResponse, 0, len(stats.Series))
+	for _, point := range stats.Series {
+		series = append(series, statsPointResponse{Start: point.Start, Clicks: point.Clicks, HumanClicks: point.HumanClicks})
+	}
+
+	daily := make([]visitorsPointResponse, 0, len(stats.DailyVisitors))
+	for _, point := range stats.DailyVisitors {
+		daily = append(daily, visitorsPointResponse{Day: point.Day.Format(time.DateOnly), Visitors: point.Visitors})
+	}
+
+	var breakdown []breakdownItemResponse
+	if stats.Breakdown != nil {
+		breakdown = make([]breakdownItemResponse, 0, len(stats.Breakdown))
+		for _, item := range stats.Breakdown {
+			breakdown = append(breakdown, breakdownItemResponse{Value: item.Value, Clicks: item.Clicks, HumanClicks: item.HumanClicks})
+		}
+	}
+
+	res := statsResponse{
+		ShortURL:       stats.ShortURL,
+		AllTimeClicks:  stats.AllTimeClicks,
+		TotalClicks:    stats.TotalClicks,
+		HumanClicks:    stats.HumanClicks,
+		Bucket:         string(stats.Query.Bucket),
+		From:           stats.Query.From,
+		To:             stats.Query.To,
+		Series:         series,
+		UniqueVisitors: stats.UniqueVisitors,
+		DailyVisitors:  daily,
+		GroupBy:        string(stats.Query.GroupBy),
+		Breakdown:      breakdown,
+	}
+
+	h.metrics.SuccessRequest.Inc()
+	response.ResultJSON(w, http.StatusOK, map[string]any{"stats": res})
+}
+
+// ExportClicks streams the click events of a link of the authenticated user as CSV or JSON Lines.
+func (h *AnalyticsHandler) ExportClicks(w http.ResponseWriter, r *http.Request) {
+	format, err := parseExportFormat(r)
+	if err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseTimeParam(query.Get("from"), false)
+	if err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": "from: " + err.Error()})
+		return
+	}
+	to, err := parseTimeParam(query.Get("to"), true)
+	if err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": "to: " + err.Error()})
+		return
+	}
+
+	shortURL := r.PathValue("shortUrl")
+	out := newExportWriter(w, format, shortURL+"-clicks", clickExportHeader)
+	err = h.analytics.ExportClicks(r.Context(), r.Header.Get("user_id"), shortURL, from, to, func(event domain.ClickEvent) error {
+		return writeClick(out, event)
+	})
+	if err == nil {
+		err = out.finish()
+	}
+	if err != nil {
+		h.exportError(w, out, "failed to export clicks", err)
+		return
+	}
+
+	h.metrics.SuccessRequest.Inc()
+}
+
+// ExportLinks streams all the links of the authenticated user with their click totals.
+func (h *AnalyticsHandler) ExportLinks(w http.ResponseWriter, r *http.Request) {
+	format, err := parseExportFormat(r)
+	if err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	out := newExportWriter(w, format, "links", linkExportHeader)
+	err = h.analytics.ExportLinks(r.Context(), r.Header.Get("user_id"), func(link domain.URL) error {
+		return writeLink(out, link)
+	})
+	if err == nil {
+		err = out.finish()
+	}
+	if err != nil {
+		h.exportError(w, out, "failed to export links", err)
+		return
+	}
+
+	h.metrics.SuccessRequest.Inc()
+}
+
+// exportError answers with an error while nothing is sent yet, after that the
+// status is gone and the export is just cut short.
+func (h *AnalyticsHandler) exportError(w http.ResponseWriter, out *exportWriter, msg string, err error) {
+	if !out.started {
+		h.analyticsError(w, msg, err)
+		return
+	}
+
+	h.logger.Error(msg, slog.Int("rows", out.rows), slog.String("error", err.Error()))
+}
+
+func (h *AnalyticsHandler) analyticsError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidStatsQuery):
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+	case errors.Is(err, domain.ErrOriginalURLNotFound):
+		response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": err.Error()})
+	case errors.Is(err, domain.ErrNotLinkOwner):
+		response.ResultJSON(w, http.StatusForbidden, map[string]any{"message": err.Error()})
+	default:
+		h.logger.Error(msg, slog.String("error", err.Error()))
+		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
+	}
+}
+
+func parseStatsQuery(r *http.Request) (domain.StatsQuery, error) {
+	values := r.URL.Query()
+	query := domain.StatsQuery{
+		Bucket:  domain.StatsBucket(values.Get("bucket")),
+		GroupBy: domain.StatsDimension(values.Get("group_by")),
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(values.Get("from"), false); err != nil {
+		return query, fmt.Errorf("from: %w", err)
+	}
+	if query.To, err = parseTimeParam(values.Get("to"), true); err != nil {
+		return query, fmt.Errorf("to: %w", err)
+	}
+
+	return query, nil
+}
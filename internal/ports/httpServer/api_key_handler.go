@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/ports/httpServer/request"
+	"url-shortener/internal/ports/httpServer/response"
+)
+
+type APIKeyService interface {
+	Create(ctx context.Context, userID, name string, scopes []domain.Scope, expiresAt time.Time) (*domain.APIKey, string, error)
+	List(ctx context.Context, userID string) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, userID, keyID string) error
+}
+
+type APIKeyHandler struct {
+	logger *slog.Logger
+	keys   APIKeyService
+}
+
+func NewAPIKeyHandler(logger *slog.Logger, keys APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		logger: logger,
+		keys:   keys,
+	}
+}
+
+// Create issues an API key for the authenticated user, the key is only returned here.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input request.CreateAPIKeyRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	scopes := make([]domain.Scope, 0, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		scopes = append(scopes, domain.Scope(scope))
+	}
+	var expiresAt time.Time
+	if input.ExpiresAt != nil {
+		expiresAt = *input.ExpiresAt
+	}
+
+	key, secret, err := h.keys.Create(r.Context(), r.Header.Get("user_id"), input.Name, scopes, expiresAt)
+	if err != nil {
+		h.apiKeyError(w, "failed to create api key", err)
+		return
+	}
+
+	body := map[string]any{
+		"api_key": newAPIKeyResponse(*key),
+		"key":     secret,
+	}
+	response.ResultJSON(w, http.StatusCreated, body)
+}
+
+// List returns the API keys of the authenticated user without their secrets.
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.List(r.Context(), r.Header.Get("user_id"))
+	if err != nil {
+		h.apiKeyError(w, "failed to list api keys", err)
+		return
+	}
+
+	res := make([]apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, newAPIKeyResponse(key))
+	}
+
+	response.ResultJSON(w, http.StatusOK, map[string]any{"api_keys": res})
+}
+
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	err := h.keys.Revoke(r.Context(), r.Header.Get("user_id"), r.PathValue("keyID"))
+	if err != nil {
+		h.apiKeyError(w, "failed to revoke api key", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIKeyHandler) apiKeyError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidAPIKeyParams):
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+	case errors.Is(err, domain.ErrAPIKeyNotFound):
+		response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": domain.ErrAPIKeyNotFound.Error()})
+	default:
+		h.logger.Error(msg, slog.String("error", err.Error()))
+		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": msg})
+	}
+}
@@ -6,16 +6,34 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"url-shortener/internal/domain"
+	"url-shortener/pkg/totp"
 
 	"github.com/go-playground/validator/v10"
 )
 
 type ServiceAuth interface {
-	Register(ctx context.Context, nickname, password string) error
-	Login(ctx context.Context, nickname, password string) (*domain.Tokens, *domain.User, error)
-	Refresh(ctx context.Context, token string) (*domain.Tokens, error)
+	Register(ctx context.Context, nickname, password, email string) error
+	// Login answers with domain.Tokens.MFAToken only when the user has
+	// two-factor authentication, LoginMFA then finishes the login.
+	Login(ctx context.Context, nickname, password string, device domain.Device) (*domain.Tokens, *domain.User, error)
+	LoginMFA(ctx context.Context, mfaToken, code string, device domain.Device) (*domain.Tokens, *domain.User, error)
+	Refresh(ctx context.Context, token string, device domain.Device) (*domain.Tokens, error)
+	Logout(ctx context.Context, userID, sessionID string) error
+	ListSessions(ctx context.Context, userID string) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID, currentID string) (int, error)
+	ChangePassword(ctx context.Context, userID, sessionID, oldPassword, newPassword string) error
+	SetEmail(ctx context.Context, userID, password, email string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	EnrollTOTP(ctx context.Context, userID, password string) (*totp.Key, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID, password, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error)
 }
 
 type AuthHandler struct {
@@ -53,12 +71,16 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.auth.Register(r.Context(), register.Nickname, register.Password)
+	err = h.auth.Register(r.Context(), register.Nickname, register.Password, register.Email)
 	if err != nil {
 		if errors.Is(err, domain.ErrNicknameAlreadyExist) {
 			ProcessError(w, domain.ErrNicknameAlreadyExist.Error(), http.StatusBadRequest)
 			return
 		}
+		if errors.Is(err, domain.ErrEmailAlreadyExist) {
+			ProcessError(w, domain.ErrEmailAlreadyExist.Error(), http.StatusBadRequest)
+			return
+		}
 
 		h.logger.Error("failed to register user", slog.String("error", err.Error()))
 		ProcessError(w, "failed to register user", http.StatusInternalServerError)
@@ -93,8 +115,11 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, user, err := h.auth.Login(r.Context(), register.Nickname, register.Password)
+	tokens, user, err := h.auth.Login(r.Context(), register.Nickname, register.Password, requestDevice(r))
 	if err != nil {
+		if loginLocked(w, err) {
+			return
+		}
 		if errors.Is(err, domain.ErrInvalidCredentials) {
 			ProcessError(w, domain.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
 			return
@@ -105,14 +130,17 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenResp := tokenResponse{
+	var resp any = tokenResponse{
 		UserID:       user.ID,
 		Nickname:     user.Nickname,
 		AccessToken:  tokens.AccessToken,
 		RefreshToken: tokens.RefreshToken,
 	}
+	if tokens.MFAToken != "" {
+		resp = mfaChallengeResponse{MFARequired: true, MFAToken: tokens.MFAToken}
+	}
 
-	payload, err := json.Marshal(tokenResp)
+	payload, err := json.Marshal(resp)
 	if err != nil {
 		h.logger.Error("can not marshal response body", slog.String("error", err.Error()))
 		ProcessError(w, "can not marshal response body", http.StatusInternalServerError)
@@ -148,10 +176,15 @@ func (h *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.auth.Refresh(r.Context(), refresh.RefreshToken)
+	tokens, err := h.auth.Refresh(r.Context(), refresh.RefreshToken, requestDevice(r))
 	if err != nil {
-		if errors.Is(err, domain.ErrUserNotFound) {
-			ProcessError(w, "token not found", http.StatusUnauthorized)
+		if errors.Is(err, domain.ErrInvalidRefreshToken) || errors.Is(err, domain.ErrUserNotFound) {
+			ProcessError(w, domain.ErrInvalidRefreshToken.Error(), http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, domain.ErrRefreshTokenReused) {
+			h.logger.Warn("refresh token reuse, session revoked", slog.String("ip", clientIP(r)))
+			ProcessError(w, domain.ErrRefreshTokenReused.Error(), http.StatusUnauthorized)
 			return
 		}
 
@@ -175,3 +208,23 @@ func (h *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(payload)
 	w.WriteHeader(http.StatusOK)
 }
+
+// loginLocked answers 429 with Retry-After when err is a lock of failed logins.
+func loginLocked(w http.ResponseWriter, err error) bool {
+	var locked *domain.LoginLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	ProcessError(w, domain.ErrLoginLocked.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+// requestDevice describes the client of r for its session.
+func requestDevice(r *http.Request) domain.Device {
+	return domain.Device{
+		UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+		IP:        clientIP(r),
+	}
+}
@@ -0,0 +1,247 @@
+package httpserver
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/ports/httpServer/request"
+	"url-shortener/internal/ports/httpServer/response"
+)
+
+// maxBulkBodySize bounds the body of a bulk creation.
+const maxBulkBodySize = 10 << 20
+
+var bulkCSVHeader = []string{"url", "alias", "expires_at", "max_clicks"}
+
+// bulkItem is a parsed item of a bulk creation, err tells why it could not be
+// parsed.
+type bulkItem struct {
+	params domain.LinkParams
+	err    error
+}
+
+type bulkItemResponse struct {
+	Index       int               `json:"index"`
+	Status      domain.BulkStatus `json:"status"`
+	ShortURL    string            `json:"short_url,omitempty"`
+	OriginalURL string            `json:"original_url,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// CreateLinksBulk creates links of the authenticated user from a JSON array
+// or a CSV upload. Every item is answered on its own, a bad item does not
+// fail the others.
+func (h *Handler) CreateLinksBulk(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkBodySize)
+
+	items, err := parseBulkItems(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.ResultJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"message": err.Error()})
+			return
+		}
+
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	if len(items) == 0 || len(items) > domain.MaxBulkLinks {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": fmt.Sprintf("%s: expected 1 to %d links", domain.ErrInvalidBulk, domain.MaxBulkLinks)})
+		return
+	}
+
+	ownerID := r.Header.Get("user_id")
+	results := make([]domain.BulkResult, len(items))
+	var params []domain.LinkParams
+	var parsed []int
+	for i, item := range items {
+		if item.err != nil {
+			results[i] = domain.BulkResult{Status: domain.BulkInvalid, Err: item.err}
+			continue
+		}
+		item.params.OwnerID = ownerID
+		params = append(params, item.params)
+		parsed = append(parsed, i)
+	}
+
+	if len(params) > 0 {
+		created, count, err := h.urlshortener.CreateBulk(r.Context(), ownerID, params)
+		if err != nil {
+			if errors.Is(err, domain.ErrAnonymousLinksDisabled) {
+				response.ResultJSON(w, http.StatusUnauthorized, map[string]any{"message": err.Error()})
+				return
+			}
+
+			if errors.Is(err, domain.ErrInvalidBulk) {
+				response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+				return
+			}
+
+			h.logger.Error("failed to create links in bulk", slog.String("error", err.Error()))
+			response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
+			return
+		}
+
+		for j, i := range parsed {
+			results[i] = created[j]
+		}
+		if count > 0 {
+			h.metrics.UrlsTotal.Set(float64(count))
+		}
+	}
+
+	counts := make(map[domain.BulkStatus]int)
+	answers := make([]bulkItemResponse, len(results))
+	for i, result := range results {
+		counts[result.Status]++
+		answers[i] = bulkItemResponse{
+			Index:       i,
+			Status:      result.Status,
+			OriginalURL: items[i].params.LongURL,
+		}
+		if result.Link != nil {
+			answers[i].ShortURL = result.Link.ShortURL
+			answers[i].OriginalURL = result.Link.LongURL
+		}
+		if result.Err != nil {
+			answers[i].Error = result.Err.Error()
+		}
+	}
+
+	body := map[string]any{
+		"results":        answers,
+		"created":        counts[domain.BulkCreated],
+		"existed":        counts[domain.BulkExists],
+		"invalid":        counts[domain.BulkInvalid],
+		"quota_exceeded": counts[domain.BulkQuotaExceeded],
+	}
+
+	h.metrics.SuccessRequest.Inc()
+	response.ResultJSON(w, http.StatusOK, body)
+}
+
+// parseBulkItems reads the items of a JSON array, a CSV body or a CSV file
+// uploaded as the "file" field of a multipart form.
+func parseBulkItems(r *http.Request) ([]bulkItem, error) {
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content type: %w", err)
+		}
+	}
+
+	switch mediaType {
+	case "application/json":
+		return parseBulkJSON(r.Body)
+	case "text/csv":
+		return parseBulkCSV(r.Body)
+	case "multipart/form-data":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("file: %w", err)
+		}
+		defer file.Close()
+
+		return parseBulkCSV(file)
+	default:
+		return nil, fmt.Errorf("content type must be one of: application/json, text/csv, multipart/form-data")
+	}
+}
+
+func parseBulkJSON(body io.Reader) ([]bulkItem, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	items := make([]bulkItem, len(raw))
+	for i, message := range raw {
+		var input request.UrlRequest
+		if err := json.Unmarshal(message, &input); err != nil {
+			items[i].err = fmt.Errorf("%w: %s", domain.ErrInvalidURL, err)
+			continue
+		}
+
+		items[i].params = domain.LinkParams{
+			LongURL:   input.URL,
+			Alias:     input.Alias,
+			MaxClicks: input.MaxClicks,
+		}
+		if input.ExpiresAt != nil {
+			items[i].params.ExpiresAt = *input.ExpiresAt
+		}
+	}
+
+	return items, nil
+}
+
+// parseBulkCSV reads rows of url, alias, expires_at and max_clicks, only the
+// url is required. The header row is optional.
+func parseBulkCSV(body io.Reader) ([]bulkItem, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var items []bulkItem
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			items = append(items, bulkItem{err: err})
+			continue
+		}
+		if row == 0 && strings.EqualFold(strings.TrimSpace(record[0]), bulkCSVHeader[0]) {
+			continue
+		}
+
+		items = append(items, parseBulkRecord(record))
+	}
+}
+
+func parseBulkRecord(record []string) bulkItem {
+	if len(record) > len(bulkCSVHeader) {
+		return bulkItem{err: fmt.Errorf("%w: expected at most %d columns: %s", domain.ErrInvalidURL, len(bulkCSVHeader), strings.Join(bulkCSVHeader, ", "))}
+	}
+
+	fields := make([]string, len(bulkCSVHeader))
+	for i, field := range record {
+		fields[i] = strings.TrimSpace(field)
+	}
+
+	item := bulkItem{params: domain.LinkParams{LongURL: fields[0], Alias: fields[1]}}
+	if fields[2] != "" {
+		expiresAt, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			item.err = fmt.Errorf("%w: expires_at must be an RFC 3339 time", domain.ErrInvalidExpiration)
+			return item
+		}
+		item.params.ExpiresAt = expiresAt
+	}
+	if fields[3] != "" {
+		maxClicks, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			item.err = fmt.Errorf("%w: max_clicks must be an integer", domain.ErrInvalidExpiration)
+			return item
+		}
+		item.params.MaxClicks = maxClicks
+	}
+
+	return item
+}
@@ -3,8 +3,10 @@ package httpserver
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -37,3 +39,33 @@ func ValidationError(errs validator.ValidationErrors) string {
 
 	return strings.Join(errMsgs, ", ")
 }
+
+const (
+	maxReferrerLength  = 2048
+	maxUserAgentLength = 512
+)
+
+// clientIP returns the address of the peer of the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// truncate cuts s to at most n bytes of valid UTF-8, the clicks are copied
+// to Postgres which rejects invalid sequences.
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
@@ -0,0 +1,231 @@
+package httpserver
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+const (
+	exportFormatCSV   = "csv"
+	exportFormatJSONL = "jsonl"
+	// exportFlushRows is how many rows are sent to the client at once.
+	exportFlushRows = 500
+	// exportWriteTimeout replaces the server write timeout, it is extended on
+	// every flush, so long exports are not cut while slow clients still are.
+	exportWriteTimeout = 30 * time.Second
+)
+
+var (
+	clickExportHeader = []string{"occurred_at", "referrer", "user_agent", "ip_network", "browser", "os", "device", "bot", "country", "region"}
+	linkExportHeader  = []string{"short_url", "long_url", "created_at", "expires_at", "max_clicks", "clicks"}
+)
+
+func parseExportFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", exportFormatCSV:
+		return exportFormatCSV, nil
+	case exportFormatJSONL:
+		return exportFormatJSONL, nil
+	default:
+		return "", fmt.Errorf("format must be one of: csv, jsonl")
+	}
+}
+
+// exportWriter streams the rows of an export. The response starts with the
+// first row, so an error before it can still be answered with a status.
+type exportWriter struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	format   string
+	filename string
+	header   []string
+	csv      *csv.Writer
+	json     *json.Encoder
+	started  bool
+	rows     int
+}
+
+func newExportWriter(w http.ResponseWriter, format string, filename string, header []string) *exportWriter {
+	return &exportWriter{
+		w:        w,
+		rc:       http.NewResponseController(w),
+		format:   format,
+		filename: filename,
+		header:   header,
+	}
+}
+
+func (e *exportWriter) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	err := e.extendDeadline()
+	if err != nil {
+		return err
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if e.format == exportFormatJSONL {
+		contentType = "application/jsonl"
+	}
+	e.w.Header().Set("Content-Type", contentType)
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename+"."+e.format))
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format == exportFormatJSONL {
+		e.json = json.NewEncoder(e.w)
+		return nil
+	}
+
+	e.csv = csv.NewWriter(e.w)
+	return e.csv.Write(e.header)
+}
+
+// write adds a row, record is used for CSV and row for JSON Lines.
+func (e *exportWriter) write(record []string, row any) error {
+	err := e.start()
+	if err != nil {
+		return err
+	}
+
+	if e.csv != nil {
+		for i := range record {
+			record[i] = csvSafe(record[i])
+		}
+		err = e.csv.Write(record)
+	} else {
+		err = e.json.Encode(row)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// finish sends the rest of the export, an empty export still gets the CSV header.
+func (e *exportWriter) finish() error {
+	err := e.start()
+	if err != nil {
+		return err
+	}
+
+	return e.flush()
+}
+
+func (e *exportWriter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	err := e.rc.Flush()
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return e.extendDeadline()
+}
+
+func (e *exportWriter) extendDeadline() error {
+	err := e.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// csvSafe keeps spreadsheets from evaluating user controlled values, such as
+// referrers, as formulas.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+type clickExportRow struct {
+	OccurredAt time.Time `json:"occurred_at"`
+	Referrer   string    `json:"referrer"`
+	UserAgent  string    `json:"user_agent"`
+	IPNetwork  string    `json:"ip_network"`
+	Browser    string    `json:"browser"`
+	OS         string    `json:"os"`
+	Device     string    `json:"device"`
+	Bot        bool      `json:"bot"`
+	Country    string    `json:"country"`
+	Region     string    `json:"region"`
+}
+
+func writeClick(out *exportWriter, event domain.ClickEvent) error {
+	record := []string{
+		event.OccurredAt.UTC().Format(time.RFC3339), event.Referrer, event.UserAgent, event.IPNetwork,
+		event.Browser, event.OS, event.Device, strconv.FormatBool(event.Bot), event.Country, event.Region,
+	}
+
+	return out.write(record, newClickExportRow(event))
+}
+
+func newClickExportRow(event domain.ClickEvent) clickExportRow {
+	return clickExportRow{
+		OccurredAt: event.OccurredAt.UTC(),
+		Referrer:   event.Referrer,
+		UserAgent:  event.UserAgent,
+		IPNetwork:  event.IPNetwork,
+		Browser:    event.Browser,
+		OS:         event.OS,
+		Device:     event.Device,
+		Bot:        event.Bot,
+		Country:    event.Country,
+		Region:     event.Region,
+	}
+}
+
+type linkExportRow struct {
+	ShortURL  string     `json:"short_url"`
+	LongURL   string     `json:"long_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks int64      `json:"max_clicks"`
+	Clicks    int64      `json:"clicks"`
+}
+
+func writeLink(out *exportWriter, link domain.URL) error {
+	row := linkExportRow{
+		ShortURL:  link.ShortURL,
+		LongURL:   link.LongURL,
+		CreatedAt: link.CreatedAt.UTC(),
+		MaxClicks: link.MaxClicks,
+		Clicks:    link.Clicks,
+	}
+	expiresAt := ""
+	if !link.ExpiresAt.IsZero() {
+		t := link.ExpiresAt.UTC()
+		row.ExpiresAt = &t
+		expiresAt = t.Format(time.RFC3339)
+	}
+
+	record := []string{
+		link.ShortURL, link.LongURL, row.CreatedAt.Format(time.RFC3339), expiresAt,
+		strconv.FormatInt(link.MaxClicks, 10), strconv.FormatInt(link.Clicks, 10),
+	}
+
+	return out.write(record, row)
+}
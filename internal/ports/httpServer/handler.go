@@ -3,27 +3,45 @@ package httpserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"url-shortener/internal/domain"
 	"url-shortener/internal/ports/httpServer/request"
 	"url-shortener/internal/ports/httpServer/response"
 	"url-shortener/pkg/metrics"
 )
 
-
 type URLShortenerService interface {
-	Create(ctx context.Context, url string) (*domain.URL, int, error)
-	GetOriginalURL(ctx context.Context, shortUrl string) (string, error)
-	DeleteShortUrl(ctx context.Context, shortUrl string) (error) 
+	Create(ctx context.Context, params domain.LinkParams) (*domain.URL, int, error)
+	CreateBulk(ctx context.Context, ownerID string, params []domain.LinkParams) ([]domain.BulkResult, int, error)
+	GetOriginalURL(ctx context.Context, shortUrl string, userAgent string) (string, error)
+	DeleteShortUrl(ctx context.Context, userID string, shortUrl string) error
+	ListLinks(ctx context.Context, filter domain.LinkFilter, cursor string) (*domain.LinkPage, error)
+	UpdateLongURL(ctx context.Context, userID string, shortUrl string, longURL string) (*domain.URL, error)
+	LinkHistory(ctx context.Context, userID string, shortUrl string) ([]domain.LinkChange, error)
+	Usage(ctx context.Context, userID string) (*domain.Usage, error)
 }
 
 type EncoderService interface {
 	Base62Encode(number uint64) string
 }
 
+type ClickRecorder interface {
+	Record(event domain.ClickEvent)
+}
+
+type ClickPublisher interface {
+	Publish(event domain.ClickEvent)
+}
+
 type RepresenrService interface {
 	Home(http.ResponseWriter)
+	Expired(http.ResponseWriter)
 }
 
 type Handler struct {
@@ -31,14 +49,18 @@ type Handler struct {
 	logger       *slog.Logger
 	render       RepresenrService
 	metrics      *metrics.PrometheusMetrics
+	clicks       ClickRecorder
+	live         ClickPublisher
 }
 
-func NewHandler(logger *slog.Logger, urlshortener URLShortenerService, render RepresenrService, metrics *metrics.PrometheusMetrics) *Handler {
+func NewHandler(logger *slog.Logger, urlshortener URLShortenerService, render RepresenrService, metrics *metrics.PrometheusMetrics, clicks ClickRecorder, live ClickPublisher) *Handler {
 	return &Handler{
 		logger:       logger,
 		urlshortener: urlshortener,
 		render:       render,
 		metrics:      metrics,
+		clicks:       clicks,
+		live:         live,
 	}
 }
 
@@ -54,8 +76,42 @@ func (h *Handler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newUrl, count, err := h.urlshortener.Create(r.Context(), input.URL)
+	params := domain.LinkParams{
+		OwnerID:   r.Header.Get("user_id"),
+		LongURL:   input.URL,
+		Alias:     input.Alias,
+		MaxClicks: input.MaxClicks,
+	}
+	if input.ExpiresAt != nil {
+		params.ExpiresAt = *input.ExpiresAt
+	}
+
+	newUrl, count, err := h.urlshortener.Create(r.Context(), params)
 	if err != nil {
+		if errors.Is(err, domain.ErrAnonymousLinksDisabled) {
+			response.ResultJSON(w, http.StatusUnauthorized, map[string]any{"message": err.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrInvalidAlias) || errors.Is(err, domain.ErrInvalidExpiration) {
+			response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrAliasTaken) {
+			response.ResultJSON(w, http.StatusConflict, map[string]any{"message": err.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrNotInPlan) {
+			response.ResultJSON(w, http.StatusPaymentRequired, map[string]any{"message": err.Error()})
+			return
+		}
+
+		if quotaExceeded(w, err) {
+			return
+		}
+
 		h.logger.Error("failed to create short url", slog.String("error", err.Error()))
 		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
 		return
@@ -66,25 +122,76 @@ func (h *Handler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 		"short_url":    newUrl.ShortURL,
 		"original_url": newUrl.LongURL,
 	}
+	if !newUrl.ExpiresAt.IsZero() {
+		body["expires_at"] = newUrl.ExpiresAt
+	}
+	if newUrl.MaxClicks > 0 {
+		body["max_clicks"] = newUrl.MaxClicks
+	}
 	h.metrics.SuccessRequest.Inc()
 	response.ResultJSON(w, http.StatusOK, body)
 
 }
 
+// quotaExceeded answers 429 with the exhausted quota and when it resets if
+// err is a *domain.QuotaExceededError.
+func quotaExceeded(w http.ResponseWriter, err error) bool {
+	var quotaErr *domain.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quotaErr.ResetsAt).Seconds()))))
+	response.ResultJSON(w, http.StatusTooManyRequests, map[string]any{
+		"message":   err.Error(),
+		"quota":     quotaErr.Quota,
+		"limit":     quotaErr.Limit,
+		"resets_at": quotaErr.ResetsAt,
+	})
+
+	return true
+}
+
 func (h *Handler) RedirectionToUrl(w http.ResponseWriter, r *http.Request) {
 	shortUrl := r.PathValue("shortUrl")
-	original_url, err := h.urlshortener.GetOriginalURL(r.Context(), shortUrl)
+	original_url, err := h.urlshortener.GetOriginalURL(r.Context(), shortUrl, r.UserAgent())
 	if err != nil {
+		if errors.Is(err, domain.ErrOriginalURLNotFound) {
+			response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": err.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrLinkExpired) {
+			if strings.Contains(r.Header.Get("Accept"), "text/html") {
+				h.render.Expired(w)
+				return
+			}
+			response.ResultJSON(w, http.StatusGone, map[string]any{"message": err.Error()})
+			return
+		}
+
 		h.logger.Error("failed to get url", slog.String("error", err.Error()))
 		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
+		return
 	}
+	click := domain.ClickEvent{
+		ShortURL:   shortUrl,
+		OccurredAt: time.Now(),
+		Referrer:   truncate(r.Referer(), maxReferrerLength),
+		UserAgent:  truncate(r.UserAgent(), maxUserAgentLength),
+		IP:         clientIP(r),
+	}
+	h.clicks.Record(click)
+	h.live.Publish(click)
 	h.metrics.RedirectsTotal.Inc()
 	h.metrics.Redirects.WithLabelValues(original_url).Inc()
-	http.Redirect(w, r, original_url, http.StatusMovedPermanently)
+	// a cached redirect would outlive a change of the destination and skip
+	// the clicks, expiration and max_clicks
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.Redirect(w, r, original_url, http.StatusFound)
 
 }
 
-
 func (h *Handler) DeleteShortURL(w http.ResponseWriter, r *http.Request) {
 	var input request.UrlRequest
 
@@ -93,17 +200,27 @@ func (h *Handler) DeleteShortURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	 err := h.urlshortener.DeleteShortUrl(r.Context(), input.URL)
+	err := h.urlshortener.DeleteShortUrl(r.Context(), r.Header.Get("user_id"), input.URL)
 	if err != nil {
-		h.logger.Error("failed to create short url", slog.String("error", err.Error()))
+		if errors.Is(err, domain.ErrOriginalURLNotFound) {
+			response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": err.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrNotLinkOwner) {
+			response.ResultJSON(w, http.StatusForbidden, map[string]any{"message": err.Error()})
+			return
+		}
+
+		h.logger.Error("failed to delete short url", slog.String("error", err.Error()))
 		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
 		return
 	}
 
 	body := map[string]any{
-		"result of deleting":   "ok",
+		"result of deleting": "ok",
 	}
 	h.metrics.SuccessRequest.Inc()
 	response.ResultJSON(w, http.StatusOK, body)
 
-}
\ No newline at end of file
+}
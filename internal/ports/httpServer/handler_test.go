@@ -4,15 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+	"url-shortener/internal/config"
 	"url-shortener/internal/domain"
 	urlMocks "url-shortener/internal/mocks"
 	"url-shortener/internal/ports/httpServer/request"
 	"url-shortener/pkg/metrics"
+	"url-shortener/pkg/totp"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
@@ -26,13 +33,15 @@ func TestHandler_CreateShortURL(t *testing.T) {
 		logger := &slog.Logger{}
 		urlshortener := urlMocks.NewURLShortenerService(t)
 		render := urlMocks.NewRepresenrService(t)
-		handler := NewHandler(logger, urlshortener, render, m)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
 
 		input := request.UrlRequest{URL: "https://example.com"}
 		jsonInput, _ := json.Marshal(input)
 
 		newURL := &domain.URL{ShortURL: "shortURL", LongURL: "https://example.com"}
-		urlshortener.On("Create", mock.Anything, "https://example.com").Return(newURL, 10, nil)
+		urlshortener.On("Create", mock.Anything, domain.LinkParams{LongURL: "https://example.com"}).Return(newURL, 10, nil)
 
 		req := httptest.NewRequest(http.MethodPost, "/api/v1/data/shorten", bytes.NewReader(jsonInput))
 		rr := httptest.NewRecorder()
@@ -55,7 +64,9 @@ func TestHandler_CreateShortURL(t *testing.T) {
 		logger := &slog.Logger{}
 		urlshortener := urlMocks.NewURLShortenerService(t)
 		render := urlMocks.NewRepresenrService(t)
-		handler := NewHandler(logger, urlshortener, render, m)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
 
 		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader([]byte("invalid json")))
 		rr := httptest.NewRecorder()
@@ -78,12 +89,14 @@ func TestHandler_CreateShortURL(t *testing.T) {
 		logger := slog.New(logHandler)
 		urlshortener := urlMocks.NewURLShortenerService(t)
 		render := urlMocks.NewRepresenrService(t)
-		handler := NewHandler(logger, urlshortener, render, m)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
 
 		input := request.UrlRequest{URL: "https://example.com"}
 		jsonInput, _ := json.Marshal(input)
 
-		urlshortener.On("Create", mock.Anything, "https://example.com").Return(nil, 0, errors.New("database error"))
+		urlshortener.On("Create", mock.Anything, domain.LinkParams{LongURL: "https://example.com"}).Return(nil, 0, errors.New("database error"))
 
 		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader(jsonInput))
 		rr := httptest.NewRecorder()
@@ -100,28 +113,446 @@ func TestHandler_CreateShortURL(t *testing.T) {
 	})
 }
 
+func TestHandler_CreateShortURL_Anonymous(t *testing.T) {
+	t.Run("Anonymous links disabled", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		input := request.UrlRequest{URL: "https://example.com"}
+		jsonInput, _ := json.Marshal(input)
+
+		urlshortener.On("Create", mock.Anything, domain.LinkParams{LongURL: "https://example.com"}).Return(nil, 0, domain.ErrAnonymousLinksDisabled)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/data/shorten", bytes.NewReader(jsonInput))
+		rr := httptest.NewRecorder()
+
+		handler.CreateShortURL(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		urlshortener.AssertExpectations(t)
+	})
+}
+
+func TestHandler_CreateShortURL_Alias(t *testing.T) {
+	t.Run("Alias taken", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		jsonInput, _ := json.Marshal(request.UrlRequest{URL: "https://example.com", Alias: "spring-sale"})
+		params := domain.LinkParams{OwnerID: "42", LongURL: "https://example.com", Alias: "spring-sale"}
+		urlshortener.On("Create", mock.Anything, params).Return(nil, 0, domain.ErrAliasTaken)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/data/shorten", bytes.NewReader(jsonInput))
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.CreateShortURL(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		urlshortener.AssertExpectations(t)
+	})
+
+	t.Run("Invalid alias", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		jsonInput, _ := json.Marshal(request.UrlRequest{URL: "https://example.com", Alias: "api"})
+		urlshortener.On("Create", mock.Anything, mock.Anything).Return(nil, 0, fmt.Errorf("%w: reserved", domain.ErrInvalidAlias))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/data/shorten", bytes.NewReader(jsonInput))
+		rr := httptest.NewRecorder()
+
+		handler.CreateShortURL(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		urlshortener.AssertExpectations(t)
+	})
+}
+
+func TestHandler_CreateShortURL_Quota(t *testing.T) {
+	t.Run("Links quota exhausted", func(t *testing.T) {
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		handler := NewHandler(&slog.Logger{}, urlshortener, urlMocks.NewRepresenrService(t), m, urlMocks.NewClickRecorder(t), urlMocks.NewClickPublisher(t))
+
+		resetsAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		jsonInput, _ := json.Marshal(request.UrlRequest{URL: "https://example.com"})
+		urlshortener.On("Create", mock.Anything, domain.LinkParams{OwnerID: "42", LongURL: "https://example.com"}).
+			Return(nil, 0, &domain.QuotaExceededError{Quota: domain.QuotaLinks, Limit: 100, ResetsAt: resetsAt})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/data/shorten", bytes.NewReader(jsonInput))
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.CreateShortURL(rr, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "links", body["quota"])
+		assert.Equal(t, float64(100), body["limit"])
+		assert.Equal(t, resetsAt.Format(time.RFC3339), body["resets_at"])
+		assert.Contains(t, body["message"], "monthly quota exhausted")
+	})
+
+	t.Run("Alias not in plan", func(t *testing.T) {
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		handler := NewHandler(&slog.Logger{}, urlshortener, urlMocks.NewRepresenrService(t), m, urlMocks.NewClickRecorder(t), urlMocks.NewClickPublisher(t))
+
+		jsonInput, _ := json.Marshal(request.UrlRequest{URL: "https://example.com", Alias: "sale"})
+		urlshortener.On("Create", mock.Anything, mock.Anything).Return(nil, 0, fmt.Errorf("custom aliases are %w %q", domain.ErrNotInPlan, "free"))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/data/shorten", bytes.NewReader(jsonInput))
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.CreateShortURL(rr, req)
+
+		assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	})
+}
+
+func TestHandler_CreateLinksBulk(t *testing.T) {
+	t.Run("JSON array", func(t *testing.T) {
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		handler := NewHandler(&slog.Logger{}, urlshortener, urlMocks.NewRepresenrService(t), m, urlMocks.NewClickRecorder(t), urlMocks.NewClickPublisher(t))
+
+		urlshortener.On("CreateBulk", mock.Anything, "42", []domain.LinkParams{
+			{OwnerID: "42", LongURL: "https://new.com"},
+			{OwnerID: "42", LongURL: "https://old.com"},
+		}).Return([]domain.BulkResult{
+			{Status: domain.BulkCreated, Link: &domain.URL{ShortURL: "abc", LongURL: "https://new.com"}},
+			{Status: domain.BulkExists, Link: &domain.URL{ShortURL: "def", LongURL: "https://old.com"}},
+		}, 10, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links/bulk", strings.NewReader(`[{"url":"https://new.com"},{"url":42},{"url":"https://old.com"}]`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.CreateLinksBulk(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Results       []bulkItemResponse `json:"results"`
+			Created       int                `json:"created"`
+			Existed       int                `json:"existed"`
+			Invalid       int                `json:"invalid"`
+			QuotaExceeded int                `json:"quota_exceeded"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, 1, body.Created)
+		assert.Equal(t, 1, body.Existed)
+		assert.Equal(t, 1, body.Invalid)
+		assert.Equal(t, 0, body.QuotaExceeded)
+		assert.Equal(t, bulkItemResponse{Index: 0, Status: domain.BulkCreated, ShortURL: "abc", OriginalURL: "https://new.com"}, body.Results[0])
+		assert.Equal(t, domain.BulkInvalid, body.Results[1].Status)
+		assert.NotEmpty(t, body.Results[1].Error)
+		assert.Equal(t, bulkItemResponse{Index: 2, Status: domain.BulkExists, ShortURL: "def", OriginalURL: "https://old.com"}, body.Results[2])
+	})
+
+	t.Run("CSV upload", func(t *testing.T) {
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		handler := NewHandler(&slog.Logger{}, urlshortener, urlMocks.NewRepresenrService(t), m, urlMocks.NewClickRecorder(t), urlMocks.NewClickPublisher(t))
+
+		expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		urlshortener.On("CreateBulk", mock.Anything, "42", []domain.LinkParams{
+			{OwnerID: "42", LongURL: "https://a.com", Alias: "spring-sale"},
+			{OwnerID: "42", LongURL: "https://b.com", ExpiresAt: expiresAt, MaxClicks: 10},
+		}).Return([]domain.BulkResult{
+			{Status: domain.BulkCreated, Link: &domain.URL{ShortURL: "spring-sale", LongURL: "https://a.com"}},
+			{Status: domain.BulkQuotaExceeded, Err: domain.ErrQuotaExceeded},
+		}, 10, nil)
+
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		file, _ := form.CreateFormFile("file", "links.csv")
+		file.Write([]byte("url,alias,expires_at,max_clicks\nhttps://a.com,spring-sale\nhttps://b.com,,2030-01-02T03:04:05Z,10\nhttps://c.com,,tomorrow\n"))
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links/bulk", &buf)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.CreateLinksBulk(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, float64(1), body["created"])
+		assert.Equal(t, float64(1), body["quota_exceeded"])
+		assert.Equal(t, float64(1), body["invalid"])
+		results := body["results"].([]any)
+		assert.Len(t, results, 3)
+		assert.Equal(t, "https://c.com", results[2].(map[string]any)["original_url"])
+	})
+
+	t.Run("Empty batch", func(t *testing.T) {
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		handler := NewHandler(&slog.Logger{}, urlMocks.NewURLShortenerService(t), urlMocks.NewRepresenrService(t), m, urlMocks.NewClickRecorder(t), urlMocks.NewClickPublisher(t))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links/bulk", strings.NewReader("url\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.CreateLinksBulk(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Unsupported content type", func(t *testing.T) {
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		handler := NewHandler(&slog.Logger{}, urlMocks.NewURLShortenerService(t), urlMocks.NewRepresenrService(t), m, urlMocks.NewClickRecorder(t), urlMocks.NewClickPublisher(t))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links/bulk", strings.NewReader("https://a.com"))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.CreateLinksBulk(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestImportHandler(t *testing.T) {
+	t.Run("Create job", func(t *testing.T) {
+		imports := urlMocks.NewImportService(t)
+		handler := NewImportHandler(&slog.Logger{}, imports)
+
+		imports.On("Create", mock.Anything, "42", mock.MatchedBy(func(rows []domain.ImportRow) bool {
+			return len(rows) == 2 && rows[0].Params.LongURL == "https://a.com" && rows[0].Err == nil &&
+				rows[1].Index == 1 && rows[1].Err != nil
+		})).Return(&domain.ImportJob{ID: "7", OwnerID: "42", Status: domain.ImportPending, Total: 2, Processed: 1, Invalid: 1}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links/import", strings.NewReader("https://a.com\nhttps://b.com,,tomorrow\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.Create(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, "/api/v1/jobs/7", rr.Header().Get("Location"))
+		var body struct {
+			Job importJobResponse `json:"job"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "7", body.Job.ID)
+		assert.Equal(t, domain.ImportPending, body.Job.Status)
+		assert.Equal(t, 1, body.Job.Invalid)
+	})
+
+	t.Run("Create empty job", func(t *testing.T) {
+		imports := urlMocks.NewImportService(t)
+		handler := NewImportHandler(&slog.Logger{}, imports)
+
+		imports.On("Create", mock.Anything, "42", []domain.ImportRow{}).Return(nil, domain.ErrInvalidImport)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links/import", strings.NewReader("[]"))
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.Create(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Get job", func(t *testing.T) {
+		imports := urlMocks.NewImportService(t)
+		handler := NewImportHandler(&slog.Logger{}, imports)
+
+		imports.On("Get", mock.Anything, "42", "7", 10, 2).Return(&domain.ImportJob{ID: "7", Status: domain.ImportRunning, Total: 100, Processed: 40},
+			[]domain.ImportRowResult{
+				{Index: 11, Status: domain.BulkInvalid, LongURL: "not a url", Error: "invalid url"},
+				{Index: 15, Status: domain.BulkQuotaExceeded, LongURL: "https://a.com", Error: "monthly quota exhausted"},
+			}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/7?errors_after=10&errors_limit=2", nil)
+		req.SetPathValue("jobID", "7")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.Get(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Job             importJobResponse     `json:"job"`
+			Errors          []importErrorResponse `json:"errors"`
+			NextErrorsAfter int                   `json:"next_errors_after"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, 40, body.Job.Processed)
+		assert.Len(t, body.Errors, 2)
+		assert.Equal(t, importErrorResponse{Index: 11, Status: domain.BulkInvalid, OriginalURL: "not a url", Error: "invalid url"}, body.Errors[0])
+		assert.Equal(t, 15, body.NextErrorsAfter)
+	})
+
+	t.Run("Job not found", func(t *testing.T) {
+		imports := urlMocks.NewImportService(t)
+		handler := NewImportHandler(&slog.Logger{}, imports)
+
+		imports.On("Get", mock.Anything, "42", "7", -1, 0).Return(nil, nil, domain.ErrImportJobNotFound)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/7", nil)
+		req.SetPathValue("jobID", "7")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.Get(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Invalid errors limit", func(t *testing.T) {
+		handler := NewImportHandler(&slog.Logger{}, urlMocks.NewImportService(t))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/7?errors_limit=0", nil)
+		req.SetPathValue("jobID", "7")
+		rr := httptest.NewRecorder()
+
+		handler.Get(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestHandler_Usage(t *testing.T) {
+	m := metrics.NewMetrics(prometheus.NewRegistry())
+	urlshortener := urlMocks.NewURLShortenerService(t)
+	handler := NewHandler(&slog.Logger{}, urlshortener, urlMocks.NewRepresenrService(t), m, urlMocks.NewClickRecorder(t), urlMocks.NewClickPublisher(t))
+
+	month := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	urlshortener.On("Usage", mock.Anything, "42").Return(&domain.Usage{
+		Plan:     domain.Plan{Name: "free", MonthlyLinks: 100, MonthlyClicks: 10000},
+		Month:    month,
+		ResetsAt: month.AddDate(0, 1, 0),
+		Links:    3,
+		Clicks:   250,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/usage", nil)
+	req.Header.Set("user_id", "42")
+	rr := httptest.NewRecorder()
+
+	handler.Usage(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, map[string]any{"name": "free", "monthly_links": float64(100), "monthly_clicks": float64(10000), "custom_aliases": false}, body["plan"])
+	assert.Equal(t, "2024-05-01", body["month"])
+	assert.Equal(t, "2024-06-01T00:00:00Z", body["resets_at"])
+	assert.Equal(t, float64(3), body["links"])
+	assert.Equal(t, float64(250), body["clicks"])
+}
+
+func TestHandler_DeleteShortURL(t *testing.T) {
+	t.Run("Successful deletion", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		jsonInput, _ := json.Marshal(request.UrlRequest{URL: "shortURL"})
+		urlshortener.On("DeleteShortUrl", mock.Anything, "42", "shortURL").Return(nil)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/data/shorten/delete", bytes.NewReader(jsonInput))
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.DeleteShortURL(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		urlshortener.AssertExpectations(t)
+	})
+
+	t.Run("Link of another user", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		jsonInput, _ := json.Marshal(request.UrlRequest{URL: "shortURL"})
+		urlshortener.On("DeleteShortUrl", mock.Anything, "42", "shortURL").Return(domain.ErrNotLinkOwner)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/data/shorten/delete", bytes.NewReader(jsonInput))
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.DeleteShortURL(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		urlshortener.AssertExpectations(t)
+	})
+}
+
 func TestHandler_RedirectionToUrl(t *testing.T) {
 	t.Run("Successful redirection", func(t *testing.T) {
 		logger := &slog.Logger{}
 		urlshortener := urlMocks.NewURLShortenerService(t)
 		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
 		reg := prometheus.NewRegistry()
 		m := metrics.NewMetrics(reg)
-		handler := NewHandler(logger, urlshortener, render, m)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
 
 		shortURL := "shortURL"
 		originalURL := "https://example.com"
 
-		urlshortener.On("GetOriginalURL", mock.Anything, "").Return(originalURL, nil)
+		urlshortener.On("GetOriginalURL", mock.Anything, shortURL, mock.Anything).Return(originalURL, nil)
+		clicks.On("Record", mock.MatchedBy(func(event domain.ClickEvent) bool {
+			return event.ShortURL == shortURL && event.Referrer == "https://ref.example" && event.IP == "192.0.2.1"
+		})).Return()
+		live.On("Publish", mock.MatchedBy(func(event domain.ClickEvent) bool {
+			return event.ShortURL == shortURL
+		})).Return()
 
 		req := httptest.NewRequest(http.MethodGet, "/"+shortURL, nil)
+		req.SetPathValue("shortUrl", shortURL)
+		req.Header.Set("Referer", "https://ref.example")
 		rr := httptest.NewRecorder()
 
 		handler.RedirectionToUrl(rr, req)
 
-		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		assert.Equal(t, http.StatusFound, rr.Code)
 		assert.Equal(t, originalURL, rr.Header().Get("Location"))
+		assert.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
 		urlshortener.AssertExpectations(t)
+		clicks.AssertExpectations(t)
 	})
 
 	t.Run("Error getting original URL", func(t *testing.T) {
@@ -130,13 +561,15 @@ func TestHandler_RedirectionToUrl(t *testing.T) {
 		logger := slog.New(logHandler)
 		urlshortener := urlMocks.NewURLShortenerService(t)
 		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
 		reg := prometheus.NewRegistry()
 		m := metrics.NewMetrics(reg)
-		handler := NewHandler(logger, urlshortener, render, m)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
 
 		shortURL := "shortURL"
 
-		urlshortener.On("GetOriginalURL", mock.Anything, "").Return("", errors.New("database error"))
+		urlshortener.On("GetOriginalURL", mock.Anything, "", mock.Anything).Return("", errors.New("database error"))
 
 		req := httptest.NewRequest(http.MethodGet, "/"+shortURL, nil)
 		rr := httptest.NewRecorder()
@@ -153,3 +586,933 @@ func TestHandler_RedirectionToUrl(t *testing.T) {
 		//assert.Equal(t, float64(0), metrics.RedirectsTotal.Get())
 	})
 }
+
+func TestHandler_ListLinks(t *testing.T) {
+	t.Run("Successful listing", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		expectedFilter := domain.LinkFilter{
+			OwnerID:     "42",
+			Host:        "example.com",
+			CreatedFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			CreatedTo:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			SortBy:      domain.LinkSortClicks,
+			Limit:       5,
+		}
+		page := &domain.LinkPage{
+			Links:      []domain.URL{{ShortURL: "shortURL", LongURL: "https://example.com", Clicks: 3}},
+			NextCursor: "next",
+		}
+		urlshortener.On("ListLinks", mock.Anything, expectedFilter, "cur").Return(page, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links?limit=5&sort=clicks&host=example.com&created_from=2024-01-01&created_to=2024-01-31&cursor=cur", nil)
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.ListLinks(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var body map[string]any
+		json.Unmarshal(rr.Body.Bytes(), &body)
+
+		assert.Equal(t, "next", body["next_cursor"])
+		assert.Len(t, body["links"], 1)
+		urlshortener.AssertExpectations(t)
+	})
+
+	t.Run("Invalid sort", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links?sort=name", nil)
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.ListLinks(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		urlshortener.On("ListLinks", mock.Anything, mock.Anything, "bad").Return(nil, domain.ErrInvalidCursor)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links?cursor=bad", nil)
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.ListLinks(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		urlshortener.AssertExpectations(t)
+	})
+}
+
+func TestHandler_RedirectionToUrl_Expired(t *testing.T) {
+	t.Run("Expired link for API clients", func(t *testing.T) {
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		urlshortener.On("GetOriginalURL", mock.Anything, "", mock.Anything).Return("", domain.ErrLinkExpired)
+
+		req := httptest.NewRequest(http.MethodGet, "/shortURL", nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+
+		handler.RedirectionToUrl(rr, req)
+
+		assert.Equal(t, http.StatusGone, rr.Code)
+		urlshortener.AssertExpectations(t)
+	})
+
+	t.Run("Expired link for browsers", func(t *testing.T) {
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		urlshortener.On("GetOriginalURL", mock.Anything, "", mock.Anything).Return("", domain.ErrLinkExpired)
+		render.On("Expired", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(http.ResponseWriter).WriteHeader(http.StatusGone)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/shortURL", nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		rr := httptest.NewRecorder()
+
+		handler.RedirectionToUrl(rr, req)
+
+		assert.Equal(t, http.StatusGone, rr.Code)
+		assert.Empty(t, rr.Header().Get("Location"))
+		render.AssertExpectations(t)
+	})
+}
+
+func TestHandler_UpdateLink(t *testing.T) {
+	t.Run("Successful update", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		jsonInput, _ := json.Marshal(request.UpdateLinkRequest{URL: "https://new.example.com"})
+		updatedURL := &domain.URL{ShortURL: "shortURL", LongURL: "https://new.example.com", OwnerID: "42"}
+		urlshortener.On("UpdateLongURL", mock.Anything, "42", "shortURL", "https://new.example.com").Return(updatedURL, nil)
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/links/shortURL", bytes.NewReader(jsonInput))
+		req.SetPathValue("shortUrl", "shortURL")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.UpdateLink(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var body map[string]any
+		json.Unmarshal(rr.Body.Bytes(), &body)
+
+		assert.Equal(t, "https://new.example.com", body["link"].(map[string]any)["original_url"])
+		urlshortener.AssertExpectations(t)
+	})
+
+	t.Run("Missing url", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/links/shortURL", bytes.NewReader([]byte("{}")))
+		req.SetPathValue("shortUrl", "shortURL")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.UpdateLink(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Link of another user", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		logger := &slog.Logger{}
+		urlshortener := urlMocks.NewURLShortenerService(t)
+		render := urlMocks.NewRepresenrService(t)
+		clicks := urlMocks.NewClickRecorder(t)
+		live := urlMocks.NewClickPublisher(t)
+		handler := NewHandler(logger, urlshortener, render, m, clicks, live)
+
+		jsonInput, _ := json.Marshal(request.UpdateLinkRequest{URL: "https://new.example.com"})
+		urlshortener.On("UpdateLongURL", mock.Anything, "42", "shortURL", "https://new.example.com").Return(nil, domain.ErrNotLinkOwner)
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/links/shortURL", bytes.NewReader(jsonInput))
+		req.SetPathValue("shortUrl", "shortURL")
+		req.Header.Set("user_id", "42")
+		rr := httptest.NewRecorder()
+
+		handler.UpdateLink(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		urlshortener.AssertExpectations(t)
+	})
+}
+
+func TestAnalyticsHandler_Stats(t *testing.T) {
+	t.Run("Successful stats", func(t *testing.T) {
+		logger := &slog.Logger{}
+		analytics := urlMocks.NewAnalyticsService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, analytics, urlMocks.NewClickStreamService(t), m)
+
+		from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		query := domain.StatsQuery{From: from, To: from.AddDate(0, 0, 2), Bucket: domain.StatsBucketDay}
+		analytics.On("Stats", mock.Anything, "7", "abc", query).Return(&domain.LinkStats{
+			ShortURL:      "abc",
+			Query:         query,
+			AllTimeClicks: 10,
+			TotalClicks:   3,
+			HumanClicks:   2,
+			Series: []domain.StatsPoint{
+				{Start: from, Clicks: 1, HumanClicks: 1},
+				{Start: from.AddDate(0, 0, 1), Clicks: 2, HumanClicks: 1},
+			},
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/abc/stats?from=2024-05-01&to=2024-05-02&bucket=day", nil)
+		req.SetPathValue("shortUrl", "abc")
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.Stats(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Stats statsResponse `json:"stats"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, int64(3), body.Stats.TotalClicks)
+		assert.Equal(t, int64(10), body.Stats.AllTimeClicks)
+		assert.Equal(t, int64(2), body.Stats.HumanClicks)
+		assert.Len(t, body.Stats.Series, 2)
+		assert.Contains(t, rr.Body.String(), `"human_clicks":2`)
+	})
+
+	t.Run("Invalid bucket", func(t *testing.T) {
+		logger := &slog.Logger{}
+		analytics := urlMocks.NewAnalyticsService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, analytics, urlMocks.NewClickStreamService(t), m)
+
+		analytics.On("Stats", mock.Anything, "7", "abc", mock.Anything).Return(nil, domain.ErrInvalidStatsQuery)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/abc/stats?bucket=minute", nil)
+		req.SetPathValue("shortUrl", "abc")
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.Stats(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Foreign link", func(t *testing.T) {
+		logger := &slog.Logger{}
+		analytics := urlMocks.NewAnalyticsService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, analytics, urlMocks.NewClickStreamService(t), m)
+
+		analytics.On("Stats", mock.Anything, "7", "abc", mock.Anything).Return(nil, domain.ErrNotLinkOwner)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/abc/stats", nil)
+		req.SetPathValue("shortUrl", "abc")
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.Stats(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestRealIP(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "127.0.0.1"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "Direct client",
+			remoteAddr: "198.51.100.7:4321",
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "Headers from untrusted peer are ignored",
+			remoteAddr: "198.51.100.7:4321",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1", "X-Real-IP": "203.0.113.1"},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "Client behind trusted proxies",
+			remoteAddr: "127.0.0.1:4321",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.66, 203.0.113.1, 10.0.0.5"},
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "X-Real-IP without X-Forwarded-For",
+			remoteAddr: "10.1.2.3:4321",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.1"},
+			want:       "203.0.113.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := realIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	assert.Equal(t, "ip:192.0.2.1", identityKey(req))
+
+	req.Header.Set("user_id", "1")
+	assert.Equal(t, "user:1", identityKey(req))
+	// the identity headers of anonymous routes come from the client
+	assert.Equal(t, "ip:192.0.2.1", ipKey(req))
+
+	req.Header.Set("api_key_id", "7")
+	assert.Equal(t, "api_key:7", identityKey(req))
+}
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := parseRateLimits(config.RateLimitConfig{Redirect: "600/1m", Create: "30/1m", Auth: "off", API: "10/s", Client: "1200/1m"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 600, limits.redirect.Rate)
+	assert.Equal(t, "create", limits.create.Name)
+	assert.Equal(t, 0, limits.auth.Rate)
+	assert.Equal(t, time.Second, limits.api.Period)
+	assert.Equal(t, "client", limits.client.Name)
+	assert.Equal(t, time.Minute, limits.client.Period)
+
+	_, err = parseRateLimits(config.RateLimitConfig{API: "lots"})
+	assert.Error(t, err)
+}
+
+func TestTruncate(t *testing.T) {
+	// "é" is two bytes, the limit falls inside the last one
+	header := strings.Repeat("a", maxUserAgentLength-1) + "é"
+	truncated := truncate(header, maxUserAgentLength)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, strings.Repeat("a", maxUserAgentLength-1), truncated)
+
+	assert.Equal(t, "Mozilla/5.0", truncate("Mozilla/\xff5.0", maxUserAgentLength))
+	assert.Equal(t, "é", truncate("é", 2))
+}
+
+func TestAnalyticsHandler_Export(t *testing.T) {
+	t.Run("Clicks as CSV", func(t *testing.T) {
+		logger := &slog.Logger{}
+		analytics := urlMocks.NewAnalyticsService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, analytics, urlMocks.NewClickStreamService(t), m)
+
+		from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		analytics.On("ExportClicks", mock.Anything, "7", "abc", from, from.AddDate(0, 0, 1), mock.Anything).
+			Run(func(args mock.Arguments) {
+				fn := args.Get(5).(func(domain.ClickEvent) error)
+				fn(domain.ClickEvent{OccurredAt: from.Add(time.Hour), Referrer: "=HYPERLINK(1)", Browser: "Firefox"})
+				fn(domain.ClickEvent{OccurredAt: from.Add(2 * time.Hour), Device: "bot", Bot: true})
+			}).Return(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/abc/clicks/export?from=2024-05-01&to=2024-05-01", nil)
+		req.SetPathValue("shortUrl", "abc")
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.ExportClicks(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="abc-clicks.csv"`, rr.Header().Get("Content-Disposition"))
+		assert.Equal(t, "occurred_at,referrer,user_agent,ip_network,browser,os,device,bot,country,region\n"+
+			"2024-05-01T01:00:00Z,'=HYPERLINK(1),,,Firefox,,,false,,\n"+
+			"2024-05-01T02:00:00Z,,,,,,bot,true,,\n", rr.Body.String())
+	})
+
+	t.Run("Foreign link", func(t *testing.T) {
+		logger := &slog.Logger{}
+		analytics := urlMocks.NewAnalyticsService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, analytics, urlMocks.NewClickStreamService(t), m)
+
+		analytics.On("ExportClicks", mock.Anything, "7", "abc", mock.Anything, mock.Anything, mock.Anything).Return(domain.ErrNotLinkOwner)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/abc/clicks/export?format=jsonl", nil)
+		req.SetPathValue("shortUrl", "abc")
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.ExportClicks(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Links as JSON Lines", func(t *testing.T) {
+		logger := &slog.Logger{}
+		analytics := urlMocks.NewAnalyticsService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, analytics, urlMocks.NewClickStreamService(t), m)
+
+		createdAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		analytics.On("ExportLinks", mock.Anything, "7", mock.Anything).
+			Run(func(args mock.Arguments) {
+				fn := args.Get(2).(func(domain.URL) error)
+				fn(domain.URL{ShortURL: "abc", LongURL: "https://example.com", CreatedAt: createdAt, Clicks: 5})
+			}).Return(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/export?format=jsonl", nil)
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.ExportLinks(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/jsonl", rr.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"short_url":"abc","long_url":"https://example.com","created_at":"2024-05-01T00:00:00Z","expires_at":null,"max_clicks":0,"clicks":5}`, rr.Body.String())
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		logger := &slog.Logger{}
+		analytics := urlMocks.NewAnalyticsService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, analytics, urlMocks.NewClickStreamService(t), m)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/export?format=xlsx", nil)
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.ExportLinks(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestAnalyticsHandler_Live(t *testing.T) {
+	t.Run("Stream clicks", func(t *testing.T) {
+		logger := &slog.Logger{}
+		stream := urlMocks.NewClickStreamService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, urlMocks.NewAnalyticsService(t), stream, m)
+
+		events := make(chan domain.ClickEvent, 1)
+		events <- domain.ClickEvent{ShortURL: "abc", OccurredAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Country: "DE"}
+		close(events)
+		cancelled := false
+		stream.On("Subscribe", mock.Anything, "7", "abc").Return((<-chan domain.ClickEvent)(events), func() { cancelled = true }, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/abc/live", nil)
+		req.SetPathValue("shortUrl", "abc")
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.Live(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Body.String(), "event: click\ndata: {\"occurred_at\":\"2024-05-01T00:00:00Z\"")
+		assert.Contains(t, rr.Body.String(), "\"country\":\"DE\"")
+		assert.True(t, cancelled)
+	})
+
+	t.Run("Foreign link", func(t *testing.T) {
+		logger := &slog.Logger{}
+		stream := urlMocks.NewClickStreamService(t)
+		reg := prometheus.NewRegistry()
+		m := metrics.NewMetrics(reg)
+		handler := NewAnalyticsHandler(logger, urlMocks.NewAnalyticsService(t), stream, m)
+
+		stream.On("Subscribe", mock.Anything, "7", "abc").Return(nil, nil, domain.ErrNotLinkOwner)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/abc/live", nil)
+		req.SetPathValue("shortUrl", "abc")
+		req.Header.Set("user_id", "7")
+		rr := httptest.NewRecorder()
+
+		handler.Live(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestAuthHandler_RefreshTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "Rotated", wantStatus: http.StatusOK},
+		{name: "Unknown token", err: domain.ErrInvalidRefreshToken, wantStatus: http.StatusUnauthorized},
+		{name: "Reused token", err: fmt.Errorf("service.Auth.Refresh: %w", domain.ErrRefreshTokenReused), wantStatus: http.StatusUnauthorized},
+		{name: "Storage error", err: errors.New("conn refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+			auth := urlMocks.NewServiceAuth(t)
+			handler := NewAuthHandler(logger, auth)
+
+			var tokens *domain.Tokens
+			if tt.err == nil {
+				tokens = &domain.Tokens{AccessToken: "access", RefreshToken: "next"}
+			}
+			auth.On("Refresh", mock.Anything, "current", domain.Device{UserAgent: "curl/8.0", IP: "192.0.2.1"}).Return(tokens, tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/user/refresh", bytes.NewBufferString(`{"refresh_token":"current"}`))
+			req.Header.Set("User-Agent", "curl/8.0")
+			rr := httptest.NewRecorder()
+
+			handler.RefreshTokens(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.err == nil {
+				assert.Contains(t, rr.Body.String(), `"refresh_token":"next"`)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_ListSessions(t *testing.T) {
+	logger := &slog.Logger{}
+	auth := urlMocks.NewServiceAuth(t)
+	handler := NewAuthHandler(logger, auth)
+
+	lastUsed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	auth.On("ListSessions", mock.Anything, "1").Return([]domain.Session{
+		{ID: "5", UserID: "1", UserAgent: "curl/8.0", IP: "192.0.2.1", LastUsedAt: lastUsed},
+		{ID: "6", UserID: "1", UserAgent: "Firefox", IP: "192.0.2.2", LastUsedAt: lastUsed},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/user/sessions", nil)
+	req.Header.Set("user_id", "1")
+	req.Header.Set("session_id", "6")
+	rr := httptest.NewRecorder()
+
+	handler.ListSessions(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Sessions []sessionResponse `json:"sessions"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Len(t, body.Sessions, 2)
+	assert.False(t, body.Sessions[0].Current)
+	assert.True(t, body.Sessions[1].Current)
+	assert.Equal(t, "192.0.2.1", body.Sessions[0].IP)
+}
+
+func TestAuthHandler_RevokeSession(t *testing.T) {
+	t.Run("Revoked", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("RevokeSession", mock.Anything, "1", "5").Return(nil)
+
+		req := httptest.NewRequest(http.MethodDelete, "/user/sessions/5", nil)
+		req.SetPathValue("sessionID", "5")
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.RevokeSession(rr, req)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("Unknown session", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("RevokeSession", mock.Anything, "1", "9").Return(fmt.Errorf("service.Auth.RevokeSession: %w", domain.ErrSessionNotFound))
+
+		req := httptest.NewRequest(http.MethodDelete, "/user/sessions/9", nil)
+		req.SetPathValue("sessionID", "9")
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.RevokeSession(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestAuthHandler_Password(t *testing.T) {
+	t.Run("Change with a wrong password", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("ChangePassword", mock.Anything, "1", "5", "guess", "new-password").Return(domain.ErrInvalidCredentials)
+
+		req := httptest.NewRequest(http.MethodPost, "/user/password", bytes.NewBufferString(`{"old_password":"guess","new_password":"new-password"}`))
+		req.Header.Set("user_id", "1")
+		req.Header.Set("session_id", "5")
+		rr := httptest.NewRecorder()
+
+		handler.ChangePassword(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Change while logins are locked", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("ChangePassword", mock.Anything, "1", "5", "guess", "new-password").Return(&domain.LoginLockedError{RetryAfter: 90 * time.Second})
+
+		req := httptest.NewRequest(http.MethodPost, "/user/password", bytes.NewBufferString(`{"old_password":"guess","new_password":"new-password"}`))
+		req.Header.Set("user_id", "1")
+		req.Header.Set("session_id", "5")
+		rr := httptest.NewRecorder()
+
+		handler.ChangePassword(rr, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Short new password", func(t *testing.T) {
+		handler := NewAuthHandler(&slog.Logger{}, urlMocks.NewServiceAuth(t))
+
+		req := httptest.NewRequest(http.MethodPost, "/user/password", bytes.NewBufferString(`{"old_password":"password","new_password":"short"}`))
+		rr := httptest.NewRecorder()
+
+		handler.ChangePassword(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Forgot password", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("RequestPasswordReset", mock.Anything, "bob@example.com").Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/user/password/forgot", bytes.NewBufferString(`{"email":"bob@example.com"}`))
+		rr := httptest.NewRecorder()
+
+		handler.ForgotPassword(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+	})
+
+	t.Run("Reset with a used token", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("ResetPassword", mock.Anything, "token", "new-password").Return(fmt.Errorf("service.Auth.ResetPassword: %w", domain.ErrInvalidResetToken))
+
+		req := httptest.NewRequest(http.MethodPost, "/user/password/reset", bytes.NewBufferString(`{"token":"token","new_password":"new-password"}`))
+		rr := httptest.NewRecorder()
+
+		handler.ResetPassword(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestAuthHandler_MFA(t *testing.T) {
+	t.Run("Login asks for the second factor", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("Login", mock.Anything, "bob", "password", mock.Anything).Return(&domain.Tokens{MFAToken: "challenge"}, &domain.User{ID: "1", Nickname: "bob", TOTPEnabled: true}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/user/login", bytes.NewBufferString(`{"nickname":"bob","password":"password"}`))
+		rr := httptest.NewRecorder()
+
+		handler.Login(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"mfa_required":true,"mfa_token":"challenge"}`, rr.Body.String())
+	})
+
+	t.Run("Second step", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("LoginMFA", mock.Anything, "challenge", "123456", mock.Anything).Return(&domain.Tokens{AccessToken: "access", RefreshToken: "refresh"}, &domain.User{ID: "1", Nickname: "bob"}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/user/login/mfa", bytes.NewBufferString(`{"mfa_token":"challenge","code":"123456"}`))
+		rr := httptest.NewRecorder()
+
+		handler.LoginMFA(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"access_token":"access"`)
+	})
+
+	t.Run("Locked login", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("Login", mock.Anything, "bob", "password", mock.Anything).Return(nil, nil, &domain.LoginLockedError{RetryAfter: 1500 * time.Millisecond})
+
+		req := httptest.NewRequest(http.MethodPost, "/user/login", bytes.NewBufferString(`{"nickname":"bob","password":"password"}`))
+		rr := httptest.NewRecorder()
+
+		handler.Login(rr, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("LoginMFA", mock.Anything, "challenge", "000000", mock.Anything).Return(nil, nil, fmt.Errorf("service.Auth.checkSecondFactor: %w", domain.ErrInvalidMFACode))
+
+		req := httptest.NewRequest(http.MethodPost, "/user/login/mfa", bytes.NewBufferString(`{"mfa_token":"challenge","code":"000000"}`))
+		rr := httptest.NewRecorder()
+
+		handler.LoginMFA(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Enroll", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("EnrollTOTP", mock.Anything, "1", "password").Return(&totp.Key{Secret: "SECRET", URL: "otpauth://totp/x", QRCode: "data:image/png;base64,"}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/user/2fa/totp", bytes.NewBufferString(`{"password":"password"}`))
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.EnrollTOTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			TOTP totpKeyResponse `json:"totp"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, totpKeyResponse{Secret: "SECRET", URI: "otpauth://totp/x", QRCode: "data:image/png;base64,"}, body.TOTP)
+	})
+
+	t.Run("Confirm twice", func(t *testing.T) {
+		auth := urlMocks.NewServiceAuth(t)
+		handler := NewAuthHandler(&slog.Logger{}, auth)
+
+		auth.On("ConfirmTOTP", mock.Anything, "1", "123456").Return(nil, domain.ErrMFAAlreadyEnabled)
+
+		req := httptest.NewRequest(http.MethodPost, "/user/2fa/totp/confirm", bytes.NewBufferString(`{"code":"123456"}`))
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.ConfirmTOTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+}
+
+func TestAPIKeyHandler_Create(t *testing.T) {
+	t.Run("Created", func(t *testing.T) {
+		keys := urlMocks.NewAPIKeyService(t)
+		handler := NewAPIKeyHandler(&slog.Logger{}, keys)
+
+		expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		keys.On("Create", mock.Anything, "1", "ci", []domain.Scope{domain.ScopeLinksWrite}, expiresAt).
+			Return(&domain.APIKey{ID: "3", Name: "ci", Prefix: "usk_0123abcd", Scopes: []domain.Scope{domain.ScopeLinksWrite}, ExpiresAt: expiresAt}, "usk_0123abcdef", nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/user/api-keys", bytes.NewBufferString(`{"name":"ci","scopes":["links:write"],"expires_at":"2030-01-01T00:00:00Z"}`))
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.Create(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"key":"usk_0123abcdef"`)
+		assert.Contains(t, rr.Body.String(), `"scopes":["links:write"]`)
+	})
+
+	t.Run("Invalid scope", func(t *testing.T) {
+		keys := urlMocks.NewAPIKeyService(t)
+		handler := NewAPIKeyHandler(&slog.Logger{}, keys)
+
+		keys.On("Create", mock.Anything, "1", "ci", []domain.Scope{"links:delete"}, time.Time{}).
+			Return(nil, "", fmt.Errorf("%w: unknown scope", domain.ErrInvalidAPIKeyParams))
+
+		req := httptest.NewRequest(http.MethodPost, "/user/api-keys", bytes.NewBufferString(`{"name":"ci","scopes":["links:delete"]}`))
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.Create(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestAdminHandler_SetRole(t *testing.T) {
+	t.Run("Role changed", func(t *testing.T) {
+		admin := urlMocks.NewAdminService(t)
+		handler := NewAdminHandler(&slog.Logger{}, admin)
+
+		admin.On("SetRole", mock.Anything, "1", "2", domain.RoleAuditor).Return(nil)
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/admin/users/2", bytes.NewBufferString(`{"role":"auditor"}`))
+		req.SetPathValue("userID", "2")
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.SetRole(rr, req)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		admin := urlMocks.NewAdminService(t)
+		handler := NewAdminHandler(&slog.Logger{}, admin)
+
+		admin.On("SetRole", mock.Anything, "1", "2", domain.Role("root")).Return(fmt.Errorf("%w: \"root\"", domain.ErrInvalidRole))
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/admin/users/2", bytes.NewBufferString(`{"role":"root"}`))
+		req.SetPathValue("userID", "2")
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.SetRole(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		admin := urlMocks.NewAdminService(t)
+		handler := NewAdminHandler(&slog.Logger{}, admin)
+
+		admin.On("SetRole", mock.Anything, "1", "9", domain.RoleAdmin).Return(fmt.Errorf("service.Admin.SetRole: %w", domain.ErrUserNotFound))
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/admin/users/9", bytes.NewBufferString(`{"role":"admin"}`))
+		req.SetPathValue("userID", "9")
+		req.Header.Set("user_id", "1")
+		rr := httptest.NewRecorder()
+
+		handler.SetRole(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestAdminHandler_SetPlan(t *testing.T) {
+	t.Run("Set plan", func(t *testing.T) {
+		admin := urlMocks.NewAdminService(t)
+		handler := NewAdminHandler(&slog.Logger{}, admin)
+
+		admin.On("SetPlan", mock.Anything, "2", "pro").Return(nil)
+
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/users/2/plan", bytes.NewBufferString(`{"plan":"pro"}`))
+		req.SetPathValue("userID", "2")
+		rr := httptest.NewRecorder()
+
+		handler.SetPlan(rr, req)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("Unknown plan", func(t *testing.T) {
+		admin := urlMocks.NewAdminService(t)
+		handler := NewAdminHandler(&slog.Logger{}, admin)
+
+		admin.On("SetPlan", mock.Anything, "2", "gold").Return(fmt.Errorf("service.Admin.SetPlan: %w", domain.ErrPlanNotFound))
+
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/users/2/plan", bytes.NewBufferString(`{"plan":"gold"}`))
+		req.SetPathValue("userID", "2")
+		rr := httptest.NewRecorder()
+
+		handler.SetPlan(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestAdminHandler_ListLinks(t *testing.T) {
+	admin := urlMocks.NewAdminService(t)
+	handler := NewAdminHandler(&slog.Logger{}, admin)
+
+	filter := domain.LinkFilter{SortBy: domain.LinkSortCreatedAt, Limit: defaultLinksPageSize, OwnerID: "4"}
+	admin.On("ListLinks", mock.Anything, filter, "").Return(&domain.LinkPage{Links: []domain.URL{{ShortURL: "abc", LongURL: "https://example.com", OwnerID: "4"}}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/links?owner=4", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ListLinks(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"owner_id":"4"`)
+}
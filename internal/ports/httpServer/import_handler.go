@@ -0,0 +1,137 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/ports/httpServer/response"
+)
+
+const (
+	// maxImportBodySize bounds the upload of an import job.
+	maxImportBodySize = 200 << 20
+	// importUploadTimeout replaces the server timeouts for an upload, large
+	// files take longer than a regular request.
+	importUploadTimeout = 5 * time.Minute
+)
+
+type ImportService interface {
+	Create(ctx context.Context, ownerID string, rows []domain.ImportRow) (*domain.ImportJob, error)
+	Get(ctx context.Context, ownerID, id string, after, limit int) (*domain.ImportJob, []domain.ImportRowResult, error)
+}
+
+type ImportHandler struct {
+	logger  *slog.Logger
+	imports ImportService
+}
+
+func NewImportHandler(logger *slog.Logger, imports ImportService) *ImportHandler {
+	return &ImportHandler{
+		logger:  logger,
+		imports: imports,
+	}
+}
+
+// Create starts an import job of the authenticated user from a JSON array or
+// a CSV upload, the same as a bulk creation. The links are created in the
+// background, the job is polled at /api/v1/jobs/{jobID}.
+func (h *ImportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(importUploadTimeout)
+	err := rc.SetReadDeadline(deadline)
+	if err == nil {
+		err = rc.SetWriteDeadline(deadline)
+	}
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.logger.Error("failed to extend import upload deadline", slog.String("error", err.Error()))
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodySize)
+
+	items, err := parseBulkItems(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.ResultJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"message": err.Error()})
+			return
+		}
+
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	rows := make([]domain.ImportRow, len(items))
+	for i, item := range items {
+		rows[i] = domain.ImportRow{Index: i, Params: item.params, Err: item.err}
+	}
+
+	job, err := h.imports.Create(r.Context(), r.Header.Get("user_id"), rows)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidImport) {
+			response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+			return
+		}
+
+		h.logger.Error("failed to create import job", slog.String("error", err.Error()))
+		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	response.ResultJSON(w, http.StatusAccepted, map[string]any{"job": newImportJobResponse(*job)})
+}
+
+// Get returns the progress of an import job of the authenticated user with a
+// page of its row errors.
+func (h *ImportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	after, limit := -1, 0
+	var err error
+	if value := r.URL.Query().Get("errors_after"); value != "" {
+		after, err = strconv.Atoi(value)
+		if err != nil {
+			response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": "errors_after must be a row index"})
+			return
+		}
+	}
+	if value := r.URL.Query().Get("errors_limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": "errors_limit must be a positive integer"})
+			return
+		}
+	}
+
+	job, rowErrors, err := h.imports.Get(r.Context(), r.Header.Get("user_id"), r.PathValue("jobID"), after, limit)
+	if err != nil {
+		if errors.Is(err, domain.ErrImportJobNotFound) {
+			response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": err.Error()})
+			return
+		}
+
+		h.logger.Error("failed to get import job", slog.String("error", err.Error()))
+		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
+		return
+	}
+
+	errorsPage := make([]importErrorResponse, 0, len(rowErrors))
+	for _, rowErr := range rowErrors {
+		errorsPage = append(errorsPage, importErrorResponse{
+			Index:       rowErr.Index,
+			Status:      rowErr.Status,
+			OriginalURL: rowErr.LongURL,
+			Error:       rowErr.Error,
+		})
+	}
+
+	body := map[string]any{
+		"job":    newImportJobResponse(*job),
+		"errors": errorsPage,
+	}
+	if len(rowErrors) > 0 {
+		body["next_errors_after"] = rowErrors[len(rowErrors)-1].Index
+	}
+	response.ResultJSON(w, http.StatusOK, body)
+}
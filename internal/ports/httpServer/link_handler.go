@@ -0,0 +1,209 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/ports/httpServer/request"
+	"url-shortener/internal/ports/httpServer/response"
+)
+
+const defaultLinksPageSize = 20
+
+// ListLinks returns the links of the authenticated user.
+func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseLinkFilter(r)
+	if err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	filter.OwnerID = r.Header.Get("user_id")
+
+	page, err := h.urlshortener.ListLinks(r.Context(), filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+			return
+		}
+
+		h.logger.Error("failed to list links", slog.String("error", err.Error()))
+		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
+		return
+	}
+
+	links := make([]linkResponse, 0, len(page.Links))
+	for _, link := range page.Links {
+		links = append(links, newLinkResponse(link))
+	}
+
+	body := map[string]any{
+		"links":       links,
+		"next_cursor": page.NextCursor,
+	}
+	h.metrics.SuccessRequest.Inc()
+	response.ResultJSON(w, http.StatusOK, body)
+}
+
+// UpdateLink changes the destination of a link of the authenticated user.
+func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	var input request.UpdateLinkRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	if input.URL == "" {
+		response.ResultJSON(w, http.StatusBadRequest, map[string]any{"message": "field url is a required field"})
+		return
+	}
+
+	link, err := h.urlshortener.UpdateLongURL(r.Context(), r.Header.Get("user_id"), r.PathValue("shortUrl"), input.URL)
+	if err != nil {
+		h.linkError(w, "failed to update link", err)
+		return
+	}
+
+	body := map[string]any{
+		"link": newLinkResponse(*link),
+	}
+	h.metrics.SuccessRequest.Inc()
+	response.ResultJSON(w, http.StatusOK, body)
+}
+
+// LinkHistory returns the previous destinations of a link of the authenticated user.
+func (h *Handler) LinkHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := h.urlshortener.LinkHistory(r.Context(), r.Header.Get("user_id"), r.PathValue("shortUrl"))
+	if err != nil {
+		h.linkError(w, "failed to get link history", err)
+		return
+	}
+
+	changes := make([]linkChangeResponse, 0, len(history))
+	for _, change := range history {
+		changes = append(changes, linkChangeResponse{
+			OriginalURL: change.LongURL,
+			ChangedBy:   change.ChangedBy,
+			ChangedAt:   change.ChangedAt,
+		})
+	}
+
+	body := map[string]any{
+		"history": changes,
+	}
+	h.metrics.SuccessRequest.Inc()
+	response.ResultJSON(w, http.StatusOK, body)
+}
+
+// Usage returns the plan of the authenticated user and what they used of it this month.
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.urlshortener.Usage(r.Context(), r.Header.Get("user_id"))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": "user not found"})
+			return
+		}
+
+		h.logger.Error("failed to get usage", slog.String("error", err.Error()))
+		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": "failed to get usage"})
+		return
+	}
+
+	body := map[string]any{
+		"plan": planResponse{
+			Name:          usage.Plan.Name,
+			MonthlyLinks:  usage.Plan.MonthlyLinks,
+			MonthlyClicks: usage.Plan.MonthlyClicks,
+			CustomAliases: usage.Plan.CustomAliases,
+		},
+		"month":     usage.Month.Format(time.DateOnly),
+		"resets_at": usage.ResetsAt,
+		"links":     usage.Links,
+		"clicks":    usage.Clicks,
+	}
+	h.metrics.SuccessRequest.Inc()
+	response.ResultJSON(w, http.StatusOK, body)
+}
+
+// linkError answers with the status matching an error of an owner-only link operation.
+func (h *Handler) linkError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrOriginalURLNotFound):
+		response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": err.Error()})
+	case errors.Is(err, domain.ErrNotLinkOwner):
+		response.ResultJSON(w, http.StatusForbidden, map[string]any{"message": err.Error()})
+	default:
+		h.logger.Error(msg, slog.String("error", err.Error()))
+		response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
+	}
+}
+
+func parseLinkFilter(r *http.Request) (domain.LinkFilter, error) {
+	query := r.URL.Query()
+	filter := domain.LinkFilter{
+		SortBy: domain.LinkSortCreatedAt,
+		Host:   query.Get("host"),
+		Limit:  defaultLinksPageSize,
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > 100 {
+			return filter, fmt.Errorf("limit must be a number between 1 and 100")
+		}
+		filter.Limit = n
+	}
+
+	switch sortBy := domain.LinkSort(query.Get("sort")); sortBy {
+	case "":
+	case domain.LinkSortCreatedAt, domain.LinkSortClicks:
+		filter.SortBy = sortBy
+	default:
+		return filter, fmt.Errorf("sort must be one of: created_at, clicks")
+	}
+
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return filter, fmt.Errorf("order must be one of: asc, desc")
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(query.Get("created_from"), false); err != nil {
+		return filter, fmt.Errorf("created_from: %w", err)
+	}
+	if filter.CreatedTo, err = parseTimeParam(query.Get("created_to"), true); err != nil {
+		return filter, fmt.Errorf("created_to: %w", err)
+	}
+
+	return filter, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps and plain dates. A plain date used
+// as an upper bound covers the whole day.
+func parseTimeParam(value string, upper bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 time or YYYY-MM-DD date")
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
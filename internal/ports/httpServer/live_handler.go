@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// liveHeartbeatInterval keeps idle streams from being closed by proxies.
+	liveHeartbeatInterval = 15 * time.Second
+	// liveWriteTimeout replaces the server write timeout, it is extended on
+	// every write, so only a stuck client is disconnected.
+	liveWriteTimeout = 30 * time.Second
+	// liveRetry is how long browsers wait before reconnecting, in milliseconds.
+	liveRetry = 3000
+)
+
+// Live streams the clicks of a link of the authenticated user as Server-Sent Events.
+func (h *AnalyticsHandler) Live(w http.ResponseWriter, r *http.Request) {
+	events, cancel, err := h.stream.Subscribe(r.Context(), r.Header.Get("user_id"), r.PathValue("shortUrl"))
+	if err != nil {
+		h.analyticsError(w, "failed to subscribe to clicks", err)
+		return
+	}
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginx must pass the events through as they come
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	h.metrics.SuccessRequest.Inc()
+
+	err = h.sendEvent(w, rc, fmt.Sprintf("retry: %d\n\n", liveRetry))
+	if err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var msg string
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(newClickExportRow(event))
+			if err != nil {
+				h.logger.Error("failed to marshal click", slog.String("error", err.Error()))
+				continue
+			}
+			msg = "event: click\ndata: " + string(data) + "\n\n"
+		case <-heartbeat.C:
+			msg = ": ping\n\n"
+		}
+
+		err = h.sendEvent(w, rc, msg)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (h *AnalyticsHandler) sendEvent(w http.ResponseWriter, rc *http.ResponseController, msg string) error {
+	err := rc.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	_, err = w.Write([]byte(msg))
+	if err != nil {
+		return err
+	}
+
+	err = rc.Flush()
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/ports/httpServer/response"
+)
+
+// LoginMFA is the second step of a login with two-factor authentication, it
+// exchanges the MFA token of Login and a code for the token pair.
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var input loginMFARequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	tokens, user, err := h.auth.LoginMFA(r.Context(), input.MFAToken, input.Code, requestDevice(r))
+	if err != nil {
+		h.mfaError(w, "failed to login user", err)
+		return
+	}
+
+	payload, err := json.Marshal(tokenResponse{
+		UserID:       user.ID,
+		Nickname:     user.Nickname,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+	if err != nil {
+		h.logger.Error("can not marshal response body", slog.String("error", err.Error()))
+		ProcessError(w, "can not marshal response body", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+// EnrollTOTP creates a TOTP secret for the authenticated user, it is used
+// once ConfirmTOTP checks a code of it.
+func (h *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	var input enrollTOTPRequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	key, err := h.auth.EnrollTOTP(r.Context(), r.Header.Get("user_id"), input.Password)
+	if err != nil {
+		h.mfaError(w, "failed to enroll totp", err)
+		return
+	}
+
+	response.ResultJSON(w, http.StatusOK, map[string]any{"totp": totpKeyResponse{
+		Secret: key.Secret,
+		URI:    key.URL,
+		QRCode: key.QRCode,
+	}})
+}
+
+// ConfirmTOTP turns two-factor authentication on and answers with the
+// recovery codes.
+func (h *AuthHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var input totpCodeRequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	codes, err := h.auth.ConfirmTOTP(r.Context(), r.Header.Get("user_id"), input.Code)
+	if err != nil {
+		h.mfaError(w, "failed to confirm totp", err)
+		return
+	}
+
+	response.ResultJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// DisableTOTP turns two-factor authentication off.
+func (h *AuthHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var input disableTOTPRequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	err := h.auth.DisableTOTP(r.Context(), r.Header.Get("user_id"), input.Password, input.Code)
+	if err != nil {
+		h.mfaError(w, "failed to disable totp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the authenticated user.
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var input totpCodeRequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	codes, err := h.auth.RegenerateRecoveryCodes(r.Context(), r.Header.Get("user_id"), input.Code)
+	if err != nil {
+		h.mfaError(w, "failed to regenerate recovery codes", err)
+		return
+	}
+
+	response.ResultJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+func (h *AuthHandler) mfaError(w http.ResponseWriter, msg string, err error) {
+	if loginLocked(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		ProcessError(w, domain.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+	// the user of the token may be deleted in the meantime
+	case errors.Is(err, domain.ErrInvalidMFAToken), errors.Is(err, domain.ErrUserNotFound):
+		ProcessError(w, domain.ErrInvalidMFAToken.Error(), http.StatusUnauthorized)
+	case errors.Is(err, domain.ErrInvalidMFACode):
+		ProcessError(w, domain.ErrInvalidMFACode.Error(), http.StatusUnauthorized)
+	case errors.Is(err, domain.ErrMFAAlreadyEnabled):
+		ProcessError(w, domain.ErrMFAAlreadyEnabled.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrMFANotEnabled):
+		ProcessError(w, domain.ErrMFANotEnabled.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrMFANotEnrolled):
+		ProcessError(w, domain.ErrMFANotEnrolled.Error(), http.StatusConflict)
+	default:
+		h.logger.Error(msg, slog.String("error", err.Error()))
+		ProcessError(w, msg, http.StatusInternalServerError)
+	}
+}
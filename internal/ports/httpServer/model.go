@@ -1,7 +1,15 @@
 package httpserver
+
+import (
+	"time"
+	"url-shortener/internal/domain"
+)
+
 type registerRequest struct {
 	Nickname string `json:"nickname" validate:"required,min=3,max=50"`
 	Password string `json:"password" validate:"required,min=8,max=50"`
+	// Email is optional, without it the password can not be reset.
+	Email string `json:"email" validate:"omitempty,email,max=255"`
 }
 
 type loginRequest struct {
@@ -16,6 +24,247 @@ type tokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// mfaChallengeResponse answers a login of a user with two-factor
+// authentication, the MFA token is passed to /user/login/mfa with a code.
+type mfaChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	// Code is a code of the authenticator app or a recovery code.
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+type enrollTOTPRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type totpKeyResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+	// QRCode is a data URI of a PNG image.
+	QRCode string `json:"qr_code"`
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+type disableTOTPRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,max=20"`
+}
+
 type refreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
-}
\ No newline at end of file
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=50"`
+}
+
+type setEmailRequest struct {
+	Password string `json:"password" validate:"required"`
+	// Email is empty to remove the email.
+	Email string `json:"email" validate:"omitempty,email,max=255"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=50"`
+}
+
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session of the request.
+	Current bool `json:"current"`
+}
+
+func newSessionResponse(session domain.Session, currentID string) sessionResponse {
+	return sessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID == currentID,
+	}
+}
+
+type apiKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func newAPIKeyResponse(key domain.APIKey) apiKeyResponse {
+	res := apiKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    make([]string, 0, len(key.Scopes)),
+		CreatedAt: key.CreatedAt,
+	}
+	for _, scope := range key.Scopes {
+		res.Scopes = append(res.Scopes, string(scope))
+	}
+	if !key.ExpiresAt.IsZero() {
+		res.ExpiresAt = &key.ExpiresAt
+	}
+	if !key.LastUsedAt.IsZero() {
+		res.LastUsedAt = &key.LastUsedAt
+	}
+
+	return res
+}
+
+type linkResponse struct {
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	Clicks      int64      `json:"clicks"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxClicks   int64      `json:"max_clicks,omitempty"`
+}
+
+func newLinkResponse(link domain.URL) linkResponse {
+	res := linkResponse{
+		ShortURL:    link.ShortURL,
+		OriginalURL: link.LongURL,
+		Clicks:      link.Clicks,
+		CreatedAt:   link.CreatedAt,
+		MaxClicks:   link.MaxClicks,
+	}
+	if !link.ExpiresAt.IsZero() {
+		res.ExpiresAt = &link.ExpiresAt
+	}
+
+	return res
+}
+
+// adminLinkResponse is a link as admins see it, together with its owner.
+type adminLinkResponse struct {
+	linkResponse
+	OwnerID string `json:"owner_id,omitempty"`
+}
+
+func newAdminLinkResponse(link domain.URL) adminLinkResponse {
+	return adminLinkResponse{
+		linkResponse: newLinkResponse(link),
+		OwnerID:      link.OwnerID,
+	}
+}
+
+type userResponse struct {
+	ID       string `json:"id"`
+	Nickname string `json:"nickname"`
+	Role     string `json:"role"`
+	Plan     string `json:"plan"`
+}
+
+// planResponse is a plan, zero limits are unlimited.
+type planResponse struct {
+	Name          string `json:"name"`
+	MonthlyLinks  int64  `json:"monthly_links"`
+	MonthlyClicks int64  `json:"monthly_clicks"`
+	CustomAliases bool   `json:"custom_aliases"`
+}
+
+type linkChangeResponse struct {
+	OriginalURL string    `json:"original_url"`
+	ChangedBy   string    `json:"changed_by"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+type statsPointResponse struct {
+	Start       time.Time `json:"start"`
+	Clicks      int64     `json:"clicks"`
+	HumanClicks int64     `json:"human_clicks"`
+}
+
+type breakdownItemResponse struct {
+	Value       string `json:"value"`
+	Clicks      int64  `json:"clicks"`
+	HumanClicks int64  `json:"human_clicks"`
+}
+
+type statsResponse struct {
+	ShortURL      string               `json:"short_url"`
+	AllTimeClicks int64                `json:"all_time_clicks"`
+	TotalClicks   int64                `json:"total_clicks"`
+	HumanClicks   int64                `json:"human_clicks"`
+	Bucket        string               `json:"bucket"`
+	From          time.Time            `json:"from"`
+	To            time.Time            `json:"to"`
+	Series        []statsPointResponse `json:"series"`
+	// UniqueVisitors and DailyVisitors are approximate.
+	UniqueVisitors int64                   `json:"unique_visitors"`
+	DailyVisitors  []visitorsPointResponse `json:"daily_visitors"`
+	GroupBy        string                  `json:"group_by,omitempty"`
+	Breakdown      []breakdownItemResponse `json:"breakdown,omitempty"`
+}
+
+type visitorsPointResponse struct {
+	Day      string `json:"day"`
+	Visitors int64  `json:"visitors"`
+}
+
+type importJobResponse struct {
+	ID            string              `json:"id"`
+	Status        domain.ImportStatus `json:"status"`
+	Total         int                 `json:"total"`
+	Processed     int                 `json:"processed"`
+	Created       int                 `json:"created"`
+	Existed       int                 `json:"existed"`
+	Invalid       int                 `json:"invalid"`
+	QuotaExceeded int                 `json:"quota_exceeded"`
+	Error         string              `json:"error,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	FinishedAt    *time.Time          `json:"finished_at,omitempty"`
+}
+
+func newImportJobResponse(job domain.ImportJob) importJobResponse {
+	res := importJobResponse{
+		ID:            job.ID,
+		Status:        job.Status,
+		Total:         job.Total,
+		Processed:     job.Processed,
+		Created:       job.Created,
+		Existed:       job.Existed,
+		Invalid:       job.Invalid,
+		QuotaExceeded: job.QuotaExceeded,
+		Error:         job.Error,
+		CreatedAt:     job.CreatedAt,
+		UpdatedAt:     job.UpdatedAt,
+	}
+	if !job.FinishedAt.IsZero() {
+		res.FinishedAt = &job.FinishedAt
+	}
+
+	return res
+}
+
+type importErrorResponse struct {
+	Index       int               `json:"index"`
+	Status      domain.BulkStatus `json:"status"`
+	OriginalURL string            `json:"original_url,omitempty"`
+	Error       string            `json:"error"`
+}
@@ -0,0 +1,118 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ChangePassword replaces the password of the authenticated user, the other
+// sessions of the user are ended.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var input changePasswordRequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	err := h.auth.ChangePassword(r.Context(), r.Header.Get("user_id"), r.Header.Get("session_id"), input.OldPassword, input.NewPassword)
+	if err != nil {
+		h.passwordError(w, "failed to change password", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetEmail changes the address password reset links of the authenticated user are sent to.
+func (h *AuthHandler) SetEmail(w http.ResponseWriter, r *http.Request) {
+	var input setEmailRequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	err := h.auth.SetEmail(r.Context(), r.Header.Get("user_id"), input.Password, input.Email)
+	if err != nil {
+		h.passwordError(w, "failed to set email", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgotPassword sends a reset link to the email. The answer is the same for
+// unknown emails.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input forgotPasswordRequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	err := h.auth.RequestPasswordReset(r.Context(), input.Email)
+	if err != nil {
+		h.logger.Error("failed to request password reset", slog.String("error", err.Error()))
+		ProcessError(w, "failed to request password reset", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with the token of a reset link.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input resetPasswordRequest
+	if !readRequest(w, r, &input) {
+		return
+	}
+
+	err := h.auth.ResetPassword(r.Context(), input.Token, input.NewPassword)
+	if err != nil {
+		h.passwordError(w, "failed to reset password", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) passwordError(w http.ResponseWriter, msg string, err error) {
+	if loginLocked(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		ProcessError(w, domain.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+	case errors.Is(err, domain.ErrInvalidResetToken):
+		ProcessError(w, domain.ErrInvalidResetToken.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrEmailAlreadyExist):
+		ProcessError(w, domain.ErrEmailAlreadyExist.Error(), http.StatusConflict)
+	default:
+		h.logger.Error(msg, slog.String("error", err.Error()))
+		ProcessError(w, msg, http.StatusInternalServerError)
+	}
+}
+
+// readRequest decodes and validates the JSON body of r into dst, on failure
+// it answers with 400 and returns false.
+func readRequest(w http.ResponseWriter, r *http.Request, dst any) bool {
+	defer r.Body.Close()
+
+	err := json.NewDecoder(r.Body).Decode(dst)
+	if err != nil {
+		ProcessError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return false
+	}
+
+	if err = validator.New().Struct(dst); err != nil {
+		var validateErrs validator.ValidationErrors
+		errors.As(err, &validateErrs)
+
+		ProcessError(w, ValidationError(validateErrs), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,58 @@
+package httpserver
+
+import (
+	"net/http"
+	"url-shortener/internal/config"
+	ratelimiter "url-shortener/pkg/rate-limiter/leaking_bucket"
+)
+
+// rateLimits are the policies of the route groups.
+type rateLimits struct {
+	redirect ratelimiter.Policy
+	create   ratelimiter.Policy
+	auth     ratelimiter.Policy
+	api      ratelimiter.Policy
+	client   ratelimiter.Policy
+}
+
+func parseRateLimits(cfg config.RateLimitConfig) (rateLimits, error) {
+	var limits rateLimits
+	var err error
+	for _, group := range []struct {
+		policy *ratelimiter.Policy
+		name   string
+		value  string
+	}{
+		{&limits.redirect, "redirect", cfg.Redirect},
+		{&limits.create, "create", cfg.Create},
+		{&limits.auth, "auth", cfg.Auth},
+		{&limits.api, "api", cfg.API},
+		{&limits.client, "client", cfg.Client},
+	} {
+		*group.policy, err = ratelimiter.ParsePolicy(group.name, group.value)
+		if err != nil {
+			return rateLimits{}, err
+		}
+	}
+
+	return limits, nil
+}
+
+// ipKey counts the requests of a client IP. It is the key of the routes
+// without authentication, their identity headers come from the client.
+func ipKey(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+// identityKey counts the requests of an API key or a user wherever they come
+// from, anonymous requests per IP. It goes after Validate or Identify.
+func identityKey(r *http.Request) string {
+	if id := r.Header.Get("api_key_id"); id != "" {
+		return "api_key:" + id
+	}
+	if id := r.Header.Get("user_id"); id != "" {
+		return "user:" + id
+	}
+
+	return ipKey(r)
+}
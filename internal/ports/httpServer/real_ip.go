@@ -0,0 +1,97 @@
+package httpserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// parseTrustedProxies parses addresses and CIDRs, a bare address is a single host network.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// realIP replaces RemoteAddr with the client address reported by a trusted
+// proxy. X-Forwarded-For is read from the right, the first address that is
+// not a trusted proxy is the client, X-Real-IP is used without it. Requests
+// from other peers keep RemoteAddr, so clients can not spoof their address.
+func realIP(trusted []*net.IPNet) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isTrusted(trusted, net.ParseIP(clientIP(r))) {
+				if ip := forwardedIP(r, trusted); ip != "" {
+					r.RemoteAddr = net.JoinHostPort(ip, "0")
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forwardedIP(r *http.Request, trusted []*net.IPNet) string {
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		client = ip.String()
+		if !isTrusted(trusted, ip) {
+			return client
+		}
+	}
+	if client != "" {
+		// every hop is a proxy, the leftmost one is the closest to the client
+		return client
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+
+	return ""
+}
+
+func isTrusted(trusted []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
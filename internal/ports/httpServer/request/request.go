@@ -1,6 +1,31 @@
 package request
 
-type UrlRequest struct{
-	URL       string   `json:"url" binding:"required"`
-	//Expiry string   `json:"expiry"`
-}
\ No newline at end of file
+import "time"
+
+type UrlRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Alias is an optional custom short code.
+	Alias string `json:"alias"`
+	// ExpiresAt and MaxClicks optionally limit the lifetime of the link.
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks int64      `json:"max_clicks"`
+}
+
+type UpdateLinkRequest struct {
+	URL string `json:"url"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is optional, keys without it do not expire.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type SetPlanRequest struct {
+	Plan string `json:"plan"`
+}
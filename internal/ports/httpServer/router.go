@@ -2,28 +2,92 @@ package httpserver
 
 import (
 	"log/slog"
+	"net"
 	"net/http"
+	"url-shortener/internal/domain"
 	"url-shortener/pkg/jwt"
 	ratelimiter "url-shortener/pkg/rate-limiter/leaking_bucket"
-
-	"github.com/go-redis/redis_rate/v9"
 )
 
-func InitRouter(handler *Handler, auth *AuthHandler, logger *slog.Logger, rL *redis_rate.Limiter, manager jwt.TokenManager) http.Handler {
-	ratelimiter.Limiter = rL
-	rateLimiter := ratelimiter.RateLimit(logger)
+func InitRouter(handler *Handler, auth *AuthHandler, analytics *AnalyticsHandler, apiKeys *APIKeyHandler, admin *AdminHandler, imports *ImportHandler, logger *slog.Logger, limiter ratelimiter.Store, limits rateLimits, manager jwt.TokenManager, denylist jwt.SessionDenylist, keys jwt.APIKeyAuthenticator, trustedProxies []*net.IPNet) http.Handler {
+	// anonymous routes are limited per IP, the others per API key or user
+	// after authentication
+	limit := func(policy ratelimiter.Policy, h http.HandlerFunc) http.Handler {
+		return ratelimiter.RateLimit(logger, limiter, policy, ipKey)(h)
+	}
+	limitIdentity := func(policy ratelimiter.Policy, h http.Handler) http.Handler {
+		return ratelimiter.RateLimit(logger, limiter, policy, identityKey)(h)
+	}
+	// and per IP before it, so invalid tokens and API keys are limited too
+	limitClient := ratelimiter.RateLimit(logger, limiter, limits.client, ipKey)
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /user/register", auth.Register)
-	mux.HandleFunc("POST /user/login", auth.Login)
-	mux.HandleFunc("POST /user/refresh", auth.RefreshTokens)
+	mux.Handle("POST /user/register", limit(limits.auth, auth.Register))
+	mux.Handle("POST /user/login", limit(limits.auth, auth.Login))
+	mux.Handle("POST /user/login/mfa", limit(limits.auth, auth.LoginMFA))
+	mux.Handle("POST /user/refresh", limit(limits.auth, auth.RefreshTokens))
+	mux.Handle("POST /user/password/forgot", limit(limits.auth, auth.ForgotPassword))
+	mux.Handle("POST /user/password/reset", limit(limits.auth, auth.ResetPassword))
+	mux.Handle("GET /.well-known/jwks.json", limit(limits.api, jwt.ServeJWKS(manager)))
+
+	// the account is managed with sessions only, API keys can not be used here
+	sessionMiddleware := jwt.Validate(manager, denylist, nil)
+	session := func(h http.HandlerFunc) http.Handler {
+		return limitClient(sessionMiddleware(limitIdentity(limits.api, h)))
+	}
+	mux.Handle("POST /user/logout", session(auth.Logout))
+	mux.Handle("GET /user/sessions", session(auth.ListSessions))
+	mux.Handle("DELETE /user/sessions", session(auth.RevokeOtherSessions))
+	mux.Handle("DELETE /user/sessions/{sessionID}", session(auth.RevokeSession))
+	mux.Handle("POST /user/password", session(auth.ChangePassword))
+	mux.Handle("PUT /user/email", session(auth.SetEmail))
+	mux.Handle("POST /user/2fa/totp", session(auth.EnrollTOTP))
+	mux.Handle("POST /user/2fa/totp/confirm", session(auth.ConfirmTOTP))
+	mux.Handle("DELETE /user/2fa/totp", session(auth.DisableTOTP))
+	mux.Handle("POST /user/2fa/recovery-codes", session(auth.RegenerateRecoveryCodes))
+	mux.Handle("POST /user/api-keys", session(apiKeys.Create))
+	mux.Handle("GET /user/api-keys", session(apiKeys.List))
+	mux.Handle("DELETE /user/api-keys/{keyID}", session(apiKeys.Revoke))
+
+	// roles are carried by access tokens of sessions, API keys never have them
+	withRole := func(h http.HandlerFunc, roles ...domain.Role) http.Handler {
+		names := make([]string, 0, len(roles))
+		for _, role := range roles {
+			names = append(names, string(role))
+		}
+		return limitClient(sessionMiddleware(limitIdentity(limits.api, jwt.RequireRole(names...)(h))))
+	}
+	mux.Handle("GET /api/v1/admin/links", withRole(admin.ListLinks, domain.RoleAdmin, domain.RoleAuditor))
+	mux.Handle("PATCH /api/v1/admin/links/{shortUrl}", withRole(admin.UpdateLink, domain.RoleAdmin))
+	mux.Handle("DELETE /api/v1/admin/links/{shortUrl}", withRole(admin.DeleteLink, domain.RoleAdmin))
+	mux.Handle("GET /api/v1/admin/links/{shortUrl}/history", withRole(admin.LinkHistory, domain.RoleAdmin, domain.RoleAuditor))
+	mux.Handle("GET /api/v1/admin/users", withRole(admin.ListUsers, domain.RoleAdmin, domain.RoleAuditor))
+	mux.Handle("PATCH /api/v1/admin/users/{userID}", withRole(admin.SetRole, domain.RoleAdmin))
+	mux.Handle("PUT /api/v1/admin/users/{userID}/plan", withRole(admin.SetPlan, domain.RoleAdmin))
+	mux.Handle("DELETE /api/v1/admin/users/{userID}", withRole(admin.DeleteUser, domain.RoleAdmin))
+	mux.Handle("GET /api/v1/admin/stats", withRole(admin.Stats, domain.RoleAdmin, domain.RoleAuditor))
 
-	authMiddleware := jwt.Validate(manager)
-	mux.Handle("DELETE /api/v1/data/shorten/delete", authMiddleware(http.HandlerFunc(handler.DeleteShortURL)))
+	authMiddleware := jwt.Validate(manager, denylist, keys)
+	scoped := func(scope domain.Scope, h http.HandlerFunc) http.Handler {
+		return limitClient(authMiddleware(limitIdentity(limits.api, jwt.RequireScope(string(scope))(h))))
+	}
+	mux.Handle("DELETE /api/v1/data/shorten/delete", scoped(domain.ScopeLinksWrite, handler.DeleteShortURL))
+	mux.Handle("GET /api/v1/me/usage", scoped(domain.ScopeLinksRead, handler.Usage))
+	mux.Handle("GET /api/v1/links", scoped(domain.ScopeLinksRead, handler.ListLinks))
+	mux.Handle("POST /api/v1/links/bulk", scoped(domain.ScopeLinksWrite, handler.CreateLinksBulk))
+	mux.Handle("POST /api/v1/links/import", scoped(domain.ScopeLinksWrite, imports.Create))
+	mux.Handle("GET /api/v1/jobs/{jobID}", scoped(domain.ScopeLinksRead, imports.Get))
+	mux.Handle("PATCH /api/v1/links/{shortUrl}", scoped(domain.ScopeLinksWrite, handler.UpdateLink))
+	mux.Handle("GET /api/v1/links/{shortUrl}/history", scoped(domain.ScopeLinksRead, handler.LinkHistory))
+	mux.Handle("GET /api/v1/links/{shortUrl}/stats", scoped(domain.ScopeStatsRead, analytics.Stats))
+	mux.Handle("GET /api/v1/links/{shortUrl}/live", scoped(domain.ScopeStatsRead, analytics.Live))
+	mux.Handle("GET /api/v1/links/{shortUrl}/clicks/export", scoped(domain.ScopeStatsRead, analytics.ExportClicks))
+	mux.Handle("GET /api/v1/links/export", scoped(domain.ScopeLinksRead, analytics.ExportLinks))
 
-	mux.HandleFunc("POST /api/v1/data/shorten", handler.CreateShortURL)
-	mux.HandleFunc("GET /api/v1/{shortUrl}", handler.RedirectionToUrl)
-	mux.HandleFunc("GET /{shortUrl}", handler.RedirectionToUrl)
-	mux.HandleFunc("GET /", handler.Homepage)
-	muxWithLimiter := rateLimiter(mux)
-	return muxWithLimiter
+	identifyMiddleware := jwt.Identify(manager, denylist, keys)
+	mux.Handle("POST /api/v1/data/shorten", limitClient(identifyMiddleware(limitIdentity(limits.create, jwt.RequireScope(string(domain.ScopeLinksWrite))(http.HandlerFunc(handler.CreateShortURL))))))
+	mux.Handle("GET /api/v1/{shortUrl}", limit(limits.redirect, handler.RedirectionToUrl))
+	mux.Handle("GET /{shortUrl}", limit(limits.redirect, handler.RedirectionToUrl))
+	mux.Handle("GET /", limit(limits.redirect, handler.Homepage))
+	return realIP(trustedProxies)(mux)
 }
@@ -9,8 +9,7 @@ import (
 	"url-shortener/internal/config"
 	"url-shortener/pkg/jwt"
 	"url-shortener/pkg/metrics"
-
-	"github.com/go-redis/redis_rate/v9"
+	ratelimiter "url-shortener/pkg/rate-limiter/leaking_bucket"
 )
 
 type Server struct {
@@ -19,12 +18,25 @@ type Server struct {
 	shutDownTimeout time.Duration
 }
 
-func NewHTTPServer(config *config.ServerConfig, authService ServiceAuth, logger *slog.Logger, serviceURLShortener URLShortenerService, render RepresenrService, limiter *redis_rate.Limiter, metrics *metrics.PrometheusMetrics, manger jwt.TokenManager) (*Server, error) {
-	httpHandler := NewHandler(logger, serviceURLShortener, render, metrics)
+func NewHTTPServer(config *config.ServerConfig, authService ServiceAuth, logger *slog.Logger, serviceURLShortener URLShortenerService, render RepresenrService, clicks ClickRecorder, live ClickPublisher, analytics AnalyticsService, stream ClickStreamService, limiter ratelimiter.Store, metrics *metrics.PrometheusMetrics, manger jwt.TokenManager, denylist jwt.SessionDenylist, apiKeys APIKeyService, keys jwt.APIKeyAuthenticator, admin AdminService, imports ImportService) (*Server, error) {
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := parseRateLimits(config.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	httpHandler := NewHandler(logger, serviceURLShortener, render, metrics, clicks, live)
 	authHandler := NewAuthHandler(logger, authService)
+	analyticsHandler := NewAnalyticsHandler(logger, analytics, stream, metrics)
+	apiKeyHandler := NewAPIKeyHandler(logger, apiKeys)
+	adminHandler := NewAdminHandler(logger, admin)
+	importHandler := NewImportHandler(logger, imports)
 	server := &http.Server{
 		Addr:         ":" + config.Port,
-		Handler:      InitRouter(httpHandler, authHandler, logger, limiter, manger),
+		Handler:      InitRouter(httpHandler, authHandler, analyticsHandler, apiKeyHandler, adminHandler, importHandler, logger, limiter, limits, manger, denylist, keys, trustedProxies),
 		ReadTimeout:  config.ReadTimeout,
 		WriteTimeout: config.WriteTimeout,
 	}
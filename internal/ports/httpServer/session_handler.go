@@ -0,0 +1,69 @@
+package httpserver
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/ports/httpServer/response"
+)
+
+// Logout ends the session of the access token.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	err := h.auth.Logout(r.Context(), r.Header.Get("user_id"), r.Header.Get("session_id"))
+	if err != nil {
+		h.sessionError(w, "failed to logout", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSessions returns the active sessions of the authenticated user.
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.auth.ListSessions(r.Context(), r.Header.Get("user_id"))
+	if err != nil {
+		h.sessionError(w, "failed to list sessions", err)
+		return
+	}
+
+	current := r.Header.Get("session_id")
+	res := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, newSessionResponse(session, current))
+	}
+
+	response.ResultJSON(w, http.StatusOK, map[string]any{"sessions": res})
+}
+
+// RevokeSession ends a session of the authenticated user, e.g. on a lost device.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	err := h.auth.RevokeSession(r.Context(), r.Header.Get("user_id"), r.PathValue("sessionID"))
+	if err != nil {
+		h.sessionError(w, "failed to revoke session", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions ends every session of the authenticated user but the current one.
+func (h *AuthHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	revoked, err := h.auth.RevokeOtherSessions(r.Context(), r.Header.Get("user_id"), r.Header.Get("session_id"))
+	if err != nil {
+		h.sessionError(w, "failed to revoke sessions", err)
+		return
+	}
+
+	response.ResultJSON(w, http.StatusOK, map[string]any{"revoked": revoked})
+}
+
+func (h *AuthHandler) sessionError(w http.ResponseWriter, msg string, err error) {
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		response.ResultJSON(w, http.StatusNotFound, map[string]any{"message": domain.ErrSessionNotFound.Error()})
+		return
+	}
+
+	h.logger.Error(msg, slog.String("error", err.Error()))
+	response.ResultJSON(w, http.StatusInternalServerError, map[string]any{"message": msg})
+}
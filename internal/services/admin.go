@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+)
+
+const (
+	maxUsersPageSize = 100
+	topLinksCount    = 10
+)
+
+// AdminUserStorage is the user management of administrators.
+type AdminUserStorage interface {
+	// ListUsers returns up to limit users with an id greater than afterID, ordered by id.
+	ListUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error)
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	CountUsers(ctx context.Context) (int64, error)
+	SetUserRole(ctx context.Context, userID string, role domain.Role) error
+	// SetUserPlan gives domain.ErrPlanNotFound for unknown plans.
+	SetUserPlan(ctx context.Context, userID, plan string) error
+	DeleteUser(ctx context.Context, userID string) error
+	RevokeOtherSessions(ctx context.Context, userID, keepID string) ([]string, error)
+}
+
+// Admin manages every link and user regardless of the owner. Callers are
+// expected to have checked the role of the acting user.
+type Admin struct {
+	logger         *slog.Logger
+	links          *URLShortener
+	users          AdminUserStorage
+	denylist       SessionDenylist
+	accessTokenTTL time.Duration
+}
+
+func NewAdmin(cfg *config.AuthConfig, logger *slog.Logger, links *URLShortener, users AdminUserStorage, denylist SessionDenylist) *Admin {
+	return &Admin{
+		logger:         logger,
+		links:          links,
+		users:          users,
+		denylist:       denylist,
+		accessTokenTTL: cfg.AccessTokenTTL,
+	}
+}
+
+// ListLinks returns a page of the links of every user, or of filter.OwnerID when it is set.
+func (a *Admin) ListLinks(ctx context.Context, filter domain.LinkFilter, cursor string) (*domain.LinkPage, error) {
+	filter.AnyOwner = filter.OwnerID == ""
+
+	return a.links.ListLinks(ctx, filter, cursor)
+}
+
+// UpdateLink points any link to a new destination, the change is recorded as made by adminID.
+func (a *Admin) UpdateLink(ctx context.Context, adminID string, shortUrl string, longURL string) (*domain.URL, error) {
+	_, err := a.links.db.GetShortUrl(ctx, shortUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.links.updateLongURL(ctx, shortUrl, longURL, adminID)
+}
+
+func (a *Admin) LinkHistory(ctx context.Context, shortUrl string) ([]domain.LinkChange, error) {
+	_, err := a.links.db.GetShortUrl(ctx, shortUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.links.db.GetLinkHistory(ctx, shortUrl)
+}
+
+func (a *Admin) DeleteLink(ctx context.Context, shortUrl string) error {
+	_, err := a.links.db.GetShortUrl(ctx, shortUrl)
+	if err != nil {
+		return err
+	}
+
+	return a.links.deleteLink(ctx, shortUrl)
+}
+
+// ListUsers returns a page of users, cursor is the NextCursor of the previous page.
+func (a *Admin) ListUsers(ctx context.Context, cursor string, limit int) (*domain.UserPage, error) {
+	if limit <= 0 || limit > maxUsersPageSize {
+		limit = maxUsersPageSize
+	}
+
+	// one extra row tells whether there is a next page
+	users, err := a.users.ListUsers(ctx, cursor, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("service.Admin.ListUsers: %w", err)
+	}
+
+	page := &domain.UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = page.Users[limit-1].ID
+	}
+	for i := range page.Users {
+		page.Users[i].PasswordHash = ""
+	}
+
+	return page, nil
+}
+
+// SetRole changes the role of a user. The sessions of the user are ended, so
+// that no access token keeps the old role.
+func (a *Admin) SetRole(ctx context.Context, adminID, userID string, role domain.Role) error {
+	if !role.Valid() {
+		return fmt.Errorf("%w: %q, expected one of user, admin, auditor", domain.ErrInvalidRole, role)
+	}
+	// an admin demoting themselves could leave the service without admins
+	if adminID == userID {
+		return fmt.Errorf("%w: admins can not change their own role", domain.ErrInvalidRole)
+	}
+
+	err := a.users.SetUserRole(ctx, userID, role)
+	if err != nil {
+		return fmt.Errorf("service.Admin.SetRole: %w", err)
+	}
+
+	return a.endSessions(ctx, userID)
+}
+
+// SetPlan changes the plan of a user, the quotas of the new plan apply to
+// what the user already used this month.
+func (a *Admin) SetPlan(ctx context.Context, userID, plan string) error {
+	err := a.users.SetUserPlan(ctx, userID, plan)
+	if err != nil {
+		return fmt.Errorf("service.Admin.SetPlan: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUser removes a user, their links stay as anonymous links.
+func (a *Admin) DeleteUser(ctx context.Context, adminID, userID string) error {
+	if adminID == userID {
+		return fmt.Errorf("%w: admins can not delete themselves", domain.ErrInvalidRole)
+	}
+
+	_, err := a.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service.Admin.DeleteUser: %w", err)
+	}
+
+	// the sessions go with the user, their ids are needed for the denylist
+	err = a.endSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = a.users.DeleteUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service.Admin.DeleteUser: %w", err)
+	}
+
+	return nil
+}
+
+func (a *Admin) endSessions(ctx context.Context, userID string) error {
+	ids, err := a.users.RevokeOtherSessions(ctx, userID, "")
+	if err != nil {
+		return fmt.Errorf("service.Admin.endSessions: %w", err)
+	}
+	if a.denylist == nil || len(ids) == 0 {
+		return nil
+	}
+
+	err = a.denylist.DenySessions(ctx, ids, a.accessTokenTTL)
+	if err != nil {
+		a.logger.Error("failed to deny access tokens of revoked sessions", slog.Any("sessions", ids), slog.String("error", err.Error()))
+	}
+
+	return nil
+}
+
+// Stats sums up the users, links and clicks of the service.
+func (a *Admin) Stats(ctx context.Context) (*domain.GlobalStats, error) {
+	users, err := a.users.CountUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service.Admin.Stats: %w", err)
+	}
+
+	links, err := a.links.db.GetCountShortUrls(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service.Admin.Stats: %w", err)
+	}
+
+	clicks, err := a.links.db.CountClicks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service.Admin.Stats: %w", err)
+	}
+
+	top, err := a.links.db.ListLinks(ctx, domain.LinkFilter{AnyOwner: true, SortBy: domain.LinkSortClicks, Limit: topLinksCount})
+	if err != nil {
+		return nil, fmt.Errorf("service.Admin.Stats: %w", err)
+	}
+
+	return &domain.GlobalStats{
+		Users:    users,
+		Links:    int64(links),
+		Clicks:   clicks,
+		TopLinks: top,
+	}, nil
+}
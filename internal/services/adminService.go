@@ -2,50 +2,143 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 	"url-shortener/internal/config"
 	"url-shortener/internal/domain"
 	"url-shortener/pkg/hash"
 	"url-shortener/pkg/jwt"
+	"url-shortener/pkg/notify"
 )
 
 type UserStorage interface {
 	SaveUser(ctx context.Context, user *domain.User) (string, error)
 	GetUser(ctx context.Context, nickname string) (*domain.User, error)
-	SetSession(ctx context.Context, userID string, session *domain.Session) error
-	GetBySession(ctx context.Context, refreshToken string) (*domain.User, error)
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error
+	CreateSession(ctx context.Context, session *domain.Session, tokenHash string) error
+	GetSessionByToken(ctx context.Context, tokenHash string) (*domain.Session, error)
+	RotateSession(ctx context.Context, session *domain.Session, oldHash, newHash string) error
+	RevokeSession(ctx context.Context, sessionID string) error
+	ListSessions(ctx context.Context, userID string) ([]domain.Session, error)
+	RevokeUserSession(ctx context.Context, userID, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID, keepID string) ([]string, error)
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	SetEmail(ctx context.Context, userID, email string) error
+	// CreatePasswordReset stores a reset token, the earlier tokens of the user stop working.
+	CreatePasswordReset(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
+	// UsePasswordReset spends a reset token and returns its user, used and
+	// expired tokens give domain.ErrInvalidResetToken.
+	UsePasswordReset(ctx context.Context, tokenHash string) (string, error)
+	GetTOTP(ctx context.Context, userID string) (*domain.TOTP, error)
+	// SetTOTPSecret stores the secret of a started enrolment, it gives
+	// domain.ErrMFAAlreadyEnabled when two-factor authentication is on.
+	SetTOTPSecret(ctx context.Context, userID, secret string) error
+	EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error
+	// UseTOTPStep records the time step of an accepted code, steps that are
+	// not after the last recorded one give domain.ErrInvalidMFACode.
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	DisableTOTP(ctx context.Context, userID string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	// UseRecoveryCode spends a recovery code, used and unknown codes give
+	// domain.ErrInvalidMFACode.
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	RecordLoginLockout(ctx context.Context, lockout *domain.LoginLockout) error
+}
+
+// Notifier delivers messages to users.
+type Notifier interface {
+	Send(ctx context.Context, msg notify.Message) error
+}
+
+// SessionDenylist rejects the access tokens of revoked sessions until they expire.
+type SessionDenylist interface {
+	DenySessions(ctx context.Context, sessionIDs []string, ttl time.Duration) error
 }
 
 type Auth struct {
+	logger          *slog.Logger
 	storage         UserStorage
+	denylist        SessionDenylist
+	notifier        Notifier
+	attempts        LoginAttempts
+	limits          loginLimits
 	tokenManager    jwt.TokenManager
 	hasher          hash.PasswordHasher
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
+	resetTTL        time.Duration
+	resetURL        string
+	totpIssuer      string
+	mfaTokenTTL     time.Duration
+}
+
+// NewTokenManager creates the manager of the access tokens from the keys of config.
+func NewTokenManager(config *config.AuthConfig) (*jwt.Manager, error) {
+	return jwt.NewKeySetManager(jwt.KeyConfig{
+		Secret:          config.JWTSigningKey,
+		PreviousSecrets: config.JWTPreviousSigningKeys,
+		KeysDir:         config.JWTKeysDir,
+		SigningKeyID:    config.JWTSigningKeyID,
+	})
 }
 
-func NewAuth(config *config.AuthConfig, storage UserStorage) (*Auth, error) {
-	tokenManager, err := jwt.NewManager(config.JWTSigningKey)
+// NewAuth creates the auth service, without attempts failed logins are not limited.
+func NewAuth(config *config.AuthConfig, logger *slog.Logger, storage UserStorage, denylist SessionDenylist, notifier Notifier, attempts LoginAttempts) (*Auth, error) {
+	tokenManager, err := NewTokenManager(config)
 	if err != nil {
 		return nil, fmt.Errorf("service.Auth.New: %w", err)
 	}
 
-	hasher, err := hash.NewSHA1Hasher(config.PasswordSalt)
+	// the SHA1 hasher only verifies the hashes stored before Argon2id
+	var legacy hash.PasswordHasher
+	if config.PasswordSalt != "" {
+		legacy, err = hash.NewSHA1Hasher(config.PasswordSalt)
+		if err != nil {
+			return nil, fmt.Errorf("service.Auth.New: %w", err)
+		}
+	}
+
+	hasher, err := hash.NewArgon2idHasher(hash.Argon2idParams{
+		Memory:      config.Argon2Memory,
+		Iterations:  config.Argon2Iterations,
+		Parallelism: config.Argon2Parallelism,
+	}, legacy)
 	if err != nil {
 		return nil, fmt.Errorf("service.Auth.New: %w", err)
 	}
 
 	return &Auth{
-		storage:         storage,
+		logger:   logger,
+		storage:  storage,
+		denylist: denylist,
+		notifier: notifier,
+		attempts: attempts,
+		limits: loginLimits{
+			maxFailures:   config.LoginMaxFailures,
+			maxIPFailures: config.LoginMaxIPFailures,
+			window:        config.LoginFailureWindow,
+			lockout:       config.LoginLockout,
+			delayAfter:    config.LoginDelayAfter,
+			maxDelay:      config.LoginMaxDelay,
+		},
 		tokenManager:    tokenManager,
 		hasher:          hasher,
 		accessTokenTTL:  config.AccessTokenTTL,
 		refreshTokenTTL: config.RefreshTokenTTL,
+		resetTTL:        config.PasswordResetTTL,
+		resetURL:        config.PasswordResetURL,
+		totpIssuer:      config.TOTPIssuer,
+		mfaTokenTTL:     config.MFATokenTTL,
 	}, nil
 }
 
-func (a *Auth) Register(ctx context.Context, nickname, password string) error {
+// Register creates a user, email is optional and only needed to reset the password.
+func (a *Auth) Register(ctx context.Context, nickname, password, email string) error {
 	passHash, err := a.hasher.Hash(password)
 	if err != nil {
 		return fmt.Errorf("service.Auth.Register: %w", err)
@@ -54,6 +147,7 @@ func (a *Auth) Register(ctx context.Context, nickname, password string) error {
 	user := &domain.User{
 		Nickname:     nickname,
 		PasswordHash: passHash,
+		Email:        normalizeEmail(email),
 	}
 
 	_, err = a.storage.SaveUser(ctx, user)
@@ -64,59 +158,229 @@ func (a *Auth) Register(ctx context.Context, nickname, password string) error {
 	return nil
 }
 
-func (a *Auth) Login(ctx context.Context, nickname, password string) (*domain.Tokens, *domain.User, error) {
-	passwordHash, err := a.hasher.Hash(password)
+func (a *Auth) Login(ctx context.Context, nickname, password string, device domain.Device) (*domain.Tokens, *domain.User, error) {
+	err := a.checkLoginLock(ctx, nickname, device.IP)
 	if err != nil {
-		return nil, nil, fmt.Errorf("service.Auth.Login: %w", err)
+		return nil, nil, err
 	}
 
 	user, err := a.storage.GetUser(ctx, nickname)
 	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			// hashing anyway takes as long as a wrong password, so the
+			// response time does not tell whether the nickname exists
+			_, _ = a.hasher.Hash(password)
+			a.loginFailed(ctx, nickname, device.IP)
+			return nil, nil, domain.ErrInvalidCredentials
+		}
+
 		return nil, nil, fmt.Errorf("service.Auth.Login: %w", err)
 	}
 
-	if user.PasswordHash != passwordHash {
+	ok, rehash, err := a.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service.Auth.Login: %w", err)
+	}
+	if !ok {
+		a.loginFailed(ctx, nickname, device.IP)
 		return nil, nil, domain.ErrInvalidCredentials
 	}
 
-	session, err := a.CreateSession(ctx, user)
-	return session, user, err
+	// legacy hashes are upgraded while the password is known, a failure
+	// does not fail the login as the old hash stays valid
+	if rehash {
+		err = a.rehash(ctx, user, password)
+		if err != nil {
+			a.logger.Error("failed to upgrade password hash", slog.String("user_id", user.ID), slog.String("error", err.Error()))
+		}
+	}
+
+	// the session is only created once the second factor is checked by
+	// LoginMFA, the failures are kept until then
+	if user.TOTPEnabled {
+		mfaToken, err := a.tokenManager.NewMFAToken(user.ID, a.mfaTokenTTL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service.Auth.Login: %w", err)
+		}
+
+		return &domain.Tokens{MFAToken: mfaToken}, user, nil
+	}
+	a.loginSucceeded(ctx, nickname)
+
+	tokens, err := a.CreateSession(ctx, user, device)
+	return tokens, user, err
 }
 
-func (a *Auth) Refresh(ctx context.Context, token string) (*domain.Tokens, error) {
-	user, err := a.storage.GetBySession(ctx, token)
+func (a *Auth) rehash(ctx context.Context, user *domain.User, password string) error {
+	passHash, err := a.hasher.Hash(password)
 	if err != nil {
-		return nil, fmt.Errorf("service.Auth.Refresh: %w", err)
+		return err
+	}
+
+	err = a.storage.UpdatePasswordHash(ctx, user.ID, passHash)
+	if err != nil {
+		return err
 	}
 
-	return a.CreateSession(ctx, user)
+	user.PasswordHash = passHash
+	return nil
 }
 
-func (a *Auth) CreateSession(ctx context.Context, user *domain.User) (*domain.Tokens, error) {
-	accessToken, err := a.tokenManager.NewJWT(user.ID, user.Nickname, a.accessTokenTTL)
+// Refresh rotates the refresh token of a session. Presenting a token that was
+// already rotated means it leaked, so the whole session is revoked.
+func (a *Auth) Refresh(ctx context.Context, token string, device domain.Device) (*domain.Tokens, error) {
+	oldHash := hashToken(token)
+	session, err := a.storage.GetSessionByToken(ctx, oldHash)
 	if err != nil {
-		return nil, fmt.Errorf("service.Auth.CreateSession: %w", err)
+		return nil, fmt.Errorf("service.Auth.Refresh: %w", err)
+	}
+
+	now := time.Now()
+	if !session.Active(now) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	user, err := a.storage.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.Refresh: %w", err)
 	}
 
 	refreshToken, err := a.tokenManager.NewRefreshToken()
 	if err != nil {
-		return nil, fmt.Errorf("service.Auth.CreateSession: %w", err)
+		return nil, fmt.Errorf("service.Auth.Refresh: %w", err)
+	}
+
+	session.UserAgent = device.UserAgent
+	session.IP = device.IP
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(a.refreshTokenTTL)
+	err = a.storage.RotateSession(ctx, session, oldHash, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, domain.ErrRefreshTokenReused) {
+			revokeErr := a.storage.RevokeSession(ctx, session.ID)
+			if revokeErr != nil {
+				return nil, fmt.Errorf("service.Auth.Refresh: %w", revokeErr)
+			}
+			a.deny(ctx, session.ID)
+		}
+
+		return nil, fmt.Errorf("service.Auth.Refresh: %w", err)
 	}
 
-	tokens := &domain.Tokens{
+	accessToken, err := a.tokenManager.NewJWT(accessClaims(user, session.ID), a.accessTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.Refresh: %w", err)
+	}
+
+	return &domain.Tokens{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+	}, nil
+}
+
+// CreateSession starts a new session of the user on device.
+func (a *Auth) CreateSession(ctx context.Context, user *domain.User, device domain.Device) (*domain.Tokens, error) {
+	refreshToken, err := a.tokenManager.NewRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.CreateSession: %w", err)
 	}
 
+	now := time.Now()
 	session := &domain.Session{
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(a.refreshTokenTTL),
+		UserID:     user.ID,
+		UserAgent:  device.UserAgent,
+		IP:         device.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(a.refreshTokenTTL),
 	}
 
-	err = a.storage.SetSession(ctx, user.ID, session)
+	err = a.storage.CreateSession(ctx, session, hashToken(refreshToken))
 	if err != nil {
 		return nil, fmt.Errorf("service.Auth.CreateSession: %w", err)
 	}
 
-	return tokens, nil
+	accessToken, err := a.tokenManager.NewJWT(accessClaims(user, session.ID), a.accessTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.CreateSession: %w", err)
+	}
+
+	return &domain.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Logout ends the session the request was made with.
+func (a *Auth) Logout(ctx context.Context, userID, sessionID string) error {
+	return a.RevokeSession(ctx, userID, sessionID)
+}
+
+// ListSessions returns the active sessions of the user.
+func (a *Auth) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	sessions, err := a.storage.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.ListSessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends a session of the user, its refresh token stops working
+// at once and its access tokens are rejected until they expire.
+func (a *Auth) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	// tokens issued before sessions have no session to revoke
+	if sessionID == "" {
+		return domain.ErrSessionNotFound
+	}
+
+	err := a.storage.RevokeUserSession(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("service.Auth.RevokeSession: %w", err)
+	}
+	a.deny(ctx, sessionID)
+
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user except currentID and
+// returns how many were ended.
+func (a *Auth) RevokeOtherSessions(ctx context.Context, userID, currentID string) (int, error) {
+	ids, err := a.storage.RevokeOtherSessions(ctx, userID, currentID)
+	if err != nil {
+		return 0, fmt.Errorf("service.Auth.RevokeOtherSessions: %w", err)
+	}
+	a.deny(ctx, ids...)
+
+	return len(ids), nil
+}
+
+// deny rejects the access tokens of revoked sessions. The sessions are already
+// revoked in the storage, so a failure is only logged: their access tokens
+// keep working until they expire.
+func (a *Auth) deny(ctx context.Context, sessionIDs ...string) {
+	if a.denylist == nil || len(sessionIDs) == 0 {
+		return
+	}
+
+	err := a.denylist.DenySessions(ctx, sessionIDs, a.accessTokenTTL)
+	if err != nil {
+		a.logger.Error("failed to deny access tokens of revoked sessions", slog.Any("sessions", sessionIDs), slog.String("error", err.Error()))
+	}
+}
+
+func accessClaims(user *domain.User, sessionID string) jwt.UserInfo {
+	return jwt.UserInfo{
+		UserID:    user.ID,
+		Nickname:  user.Nickname,
+		SessionID: sessionID,
+		Role:      string(user.Role),
+	}
+}
+
+// hashToken is what is stored instead of a refresh or reset token, the tokens
+// are random enough for a plain SHA-256.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+	"url-shortener/pkg/hash"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuth_Login(t *testing.T) {
+	cfg := &config.AuthConfig{
+		AccessTokenTTL:    time.Minute,
+		RefreshTokenTTL:   time.Hour,
+		PasswordSalt:      "salt",
+		JWTSigningKey:     "key",
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+
+	t.Run("Argon2id hash", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		passHash, err := auth.hasher.Hash("password")
+		assert.NoError(t, err)
+		storage.On("GetUser", mock.Anything, "bob").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash}, nil)
+		storage.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		tokens, user, err := auth.Login(context.Background(), "bob", "password", domain.Device{})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.Equal(t, "1", user.ID)
+	})
+
+	t.Run("Legacy hash is upgraded", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		legacy, _ := hash.NewSHA1Hasher("salt")
+		passHash, _ := legacy.Hash("password")
+		storage.On("GetUser", mock.Anything, "bob").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash}, nil)
+		storage.On("UpdatePasswordHash", mock.Anything, "1", mock.MatchedBy(func(h string) bool {
+			return strings.HasPrefix(h, "$argon2id$v=19$m=1024,t=1,p=1$")
+		})).Return(nil)
+		storage.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		_, user, err := auth.Login(context.Background(), "bob", "password", domain.Device{})
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(user.PasswordHash, "$argon2id$"))
+	})
+
+	t.Run("Failed upgrade does not fail the login", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+		auth, err := NewAuth(cfg, logger, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		legacy, _ := hash.NewSHA1Hasher("salt")
+		passHash, _ := legacy.Hash("password")
+		storage.On("GetUser", mock.Anything, "bob").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash}, nil)
+		storage.On("UpdatePasswordHash", mock.Anything, "1", mock.Anything).Return(errors.New("conn refused"))
+		storage.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		_, _, err = auth.Login(context.Background(), "bob", "password", domain.Device{})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		legacy, _ := hash.NewSHA1Hasher("salt")
+		passHash, _ := legacy.Hash("password")
+		storage.On("GetUser", mock.Anything, "bob").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash}, nil)
+
+		_, _, err = auth.Login(context.Background(), "bob", "wrong password", domain.Device{})
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		storage.On("GetUser", mock.Anything, "bob").Return(nil, domain.ErrUserNotFound)
+
+		_, _, err = auth.Login(context.Background(), "bob", "password", domain.Device{})
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+}
+
+func TestAuth_Refresh(t *testing.T) {
+	cfg := &config.AuthConfig{
+		AccessTokenTTL:    time.Minute,
+		RefreshTokenTTL:   time.Hour,
+		JWTSigningKey:     "key",
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+	device := domain.Device{UserAgent: "curl/8.0", IP: "203.0.113.7"}
+
+	t.Run("Rotate the refresh token", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		session := &domain.Session{ID: "5", UserID: "1", ExpiresAt: time.Now().Add(time.Minute)}
+		storage.On("GetSessionByToken", mock.Anything, hashToken("old")).Return(session, nil)
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", Nickname: "bob"}, nil)
+		storage.On("RotateSession", mock.Anything, mock.MatchedBy(func(s *domain.Session) bool {
+			return s.ID == "5" && s.UserAgent == "curl/8.0" && s.IP == "203.0.113.7" && s.ExpiresAt.After(time.Now().Add(59*time.Minute))
+		}), hashToken("old"), mock.Anything).Return(nil)
+
+		tokens, err := auth.Refresh(context.Background(), "old", device)
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, "old", tokens.RefreshToken)
+		storage.AssertCalled(t, "RotateSession", mock.Anything, mock.Anything, hashToken("old"), hashToken(tokens.RefreshToken))
+	})
+
+	t.Run("Reused token revokes the session", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		session := &domain.Session{ID: "5", UserID: "1", ExpiresAt: time.Now().Add(time.Minute)}
+		storage.On("GetSessionByToken", mock.Anything, hashToken("old")).Return(session, nil)
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", Nickname: "bob"}, nil)
+		storage.On("RotateSession", mock.Anything, mock.Anything, hashToken("old"), mock.Anything).Return(domain.ErrRefreshTokenReused)
+		storage.On("RevokeSession", mock.Anything, "5").Return(nil)
+
+		_, err = auth.Refresh(context.Background(), "old", device)
+
+		assert.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+	})
+
+	t.Run("Expired session", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		session := &domain.Session{ID: "5", UserID: "1", ExpiresAt: time.Now().Add(-time.Minute)}
+		storage.On("GetSessionByToken", mock.Anything, hashToken("old")).Return(session, nil)
+
+		_, err = auth.Refresh(context.Background(), "old", device)
+
+		assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+	})
+
+	t.Run("Revoked session", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		session := &domain.Session{ID: "5", UserID: "1", ExpiresAt: time.Now().Add(time.Minute), RevokedAt: time.Now()}
+		storage.On("GetSessionByToken", mock.Anything, hashToken("old")).Return(session, nil)
+
+		_, err = auth.Refresh(context.Background(), "old", device)
+
+		assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+	})
+}
+
+func TestAuth_RevokeSessions(t *testing.T) {
+	cfg := &config.AuthConfig{
+		AccessTokenTTL:    15 * time.Minute,
+		RefreshTokenTTL:   time.Hour,
+		JWTSigningKey:     "key",
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+
+	t.Run("Logout denies the access tokens", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		denylist := urlMocks.NewSessionDenylist(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, denylist, nil, nil)
+		assert.NoError(t, err)
+
+		storage.On("RevokeUserSession", mock.Anything, "1", "5").Return(nil)
+		denylist.On("DenySessions", mock.Anything, []string{"5"}, 15*time.Minute).Return(nil)
+
+		err = auth.Logout(context.Background(), "1", "5")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Token without a session", func(t *testing.T) {
+		auth, err := NewAuth(cfg, &slog.Logger{}, urlMocks.NewUserStorage(t), urlMocks.NewSessionDenylist(t), nil, nil)
+		assert.NoError(t, err)
+
+		err = auth.Logout(context.Background(), "1", "")
+
+		assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+	})
+
+	t.Run("Session of another user", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, urlMocks.NewSessionDenylist(t), nil, nil)
+		assert.NoError(t, err)
+
+		storage.On("RevokeUserSession", mock.Anything, "1", "6").Return(domain.ErrSessionNotFound)
+
+		err = auth.RevokeSession(context.Background(), "1", "6")
+
+		assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+	})
+
+	t.Run("Revoke the other sessions", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		denylist := urlMocks.NewSessionDenylist(t)
+		logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+		auth, err := NewAuth(cfg, logger, storage, denylist, nil, nil)
+		assert.NoError(t, err)
+
+		storage.On("RevokeOtherSessions", mock.Anything, "1", "5").Return([]string{"6", "7"}, nil)
+		// the sessions are revoked even when their access tokens can not be denied
+		denylist.On("DenySessions", mock.Anything, []string{"6", "7"}, 15*time.Minute).Return(errors.New("conn refused"))
+
+		revoked, err := auth.RevokeOtherSessions(context.Background(), "1", "5")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, revoked)
+	})
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdmin_SetRole(t *testing.T) {
+	cfg := &config.AuthConfig{AccessTokenTTL: 15 * time.Minute}
+
+	t.Run("Role change ends the sessions", func(t *testing.T) {
+		users := urlMocks.NewAdminUserStorage(t)
+		denylist := urlMocks.NewSessionDenylist(t)
+		admin := NewAdmin(cfg, &slog.Logger{}, nil, users, denylist)
+
+		users.On("SetUserRole", mock.Anything, "2", domain.RoleAuditor).Return(nil)
+		users.On("RevokeOtherSessions", mock.Anything, "2", "").Return([]string{"8", "9"}, nil)
+		denylist.On("DenySessions", mock.Anything, []string{"8", "9"}, 15*time.Minute).Return(nil)
+
+		err := admin.SetRole(context.Background(), "1", "2", domain.RoleAuditor)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		admin := NewAdmin(cfg, &slog.Logger{}, nil, urlMocks.NewAdminUserStorage(t), urlMocks.NewSessionDenylist(t))
+
+		err := admin.SetRole(context.Background(), "1", "2", domain.Role("root"))
+
+		assert.ErrorIs(t, err, domain.ErrInvalidRole)
+	})
+
+	t.Run("Own role", func(t *testing.T) {
+		admin := NewAdmin(cfg, &slog.Logger{}, nil, urlMocks.NewAdminUserStorage(t), urlMocks.NewSessionDenylist(t))
+
+		err := admin.SetRole(context.Background(), "1", "1", domain.RoleUser)
+
+		assert.ErrorIs(t, err, domain.ErrInvalidRole)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		users := urlMocks.NewAdminUserStorage(t)
+		admin := NewAdmin(cfg, &slog.Logger{}, nil, users, urlMocks.NewSessionDenylist(t))
+
+		users.On("SetUserRole", mock.Anything, "7", domain.RoleAdmin).Return(domain.ErrUserNotFound)
+
+		err := admin.SetRole(context.Background(), "1", "7", domain.RoleAdmin)
+
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestAdmin_ListLinks(t *testing.T) {
+	t.Run("Links of every user", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		admin := NewAdmin(&config.AuthConfig{}, &slog.Logger{}, New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, nil, nil), urlMocks.NewAdminUserStorage(t), nil)
+
+		filter := domain.LinkFilter{SortBy: domain.LinkSortCreatedAt, Limit: 2}
+		anyOwner := filter
+		anyOwner.AnyOwner = true
+		anyOwner.Limit = 3
+		db.On("ListLinks", mock.Anything, anyOwner).Return([]domain.URL{{ShortURL: "a", OwnerID: "1"}, {ShortURL: "b"}}, nil)
+
+		page, err := admin.ListLinks(context.Background(), filter, "")
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Links, 2)
+		assert.Empty(t, page.NextCursor)
+	})
+}
+
+func TestAdmin_Stats(t *testing.T) {
+	db := urlMocks.NewDatabase(t)
+	users := urlMocks.NewAdminUserStorage(t)
+	admin := NewAdmin(&config.AuthConfig{}, &slog.Logger{}, New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, nil, nil), users, nil)
+
+	top := []domain.URL{{ShortURL: "a", Clicks: 40}, {ShortURL: "b", Clicks: 2}}
+	users.On("CountUsers", mock.Anything).Return(int64(3), nil)
+	db.On("GetCountShortUrls", mock.Anything).Return(12, nil)
+	db.On("CountClicks", mock.Anything).Return(int64(42), nil)
+	db.On("ListLinks", mock.Anything, domain.LinkFilter{AnyOwner: true, SortBy: domain.LinkSortClicks, Limit: 10}).Return(top, nil)
+
+	stats, err := admin.Stats(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.GlobalStats{Users: 3, Links: 12, Clicks: 42, TopLinks: top}, stats)
+}
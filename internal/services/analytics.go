@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+const (
+	defaultStatsRange = 30 * 24 * time.Hour
+	maxStatsBuckets   = 1000
+	exportPageSize    = 500
+)
+
+// Analytics answers questions about the clicks of links.
+type Analytics struct {
+	links    Database
+	clicks   ClickStorage
+	visitors VisitorCounter
+}
+
+func NewAnalytics(links Database, clicks ClickStorage, visitors VisitorCounter) *Analytics {
+	return &Analytics{
+		links:    links,
+		clicks:   clicks,
+		visitors: visitors,
+	}
+}
+
+// Stats returns the clicks and the unique visitors of the link of userID.
+// Zero bounds of the query default to the last 30 days, the series has a
+// point for every bucket. Visitors are counted per UTC day, for at most the
+// last maxStatsBuckets days of the range.
+func (a *Analytics) Stats(ctx context.Context, userID string, shortURL string, query domain.StatsQuery) (*domain.LinkStats, error) {
+	link, err := a.ownedLink(ctx, userID, shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err = normalizeStatsQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := a.clicks.ClickStats(ctx, shortURL, query)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &domain.LinkStats{
+		ShortURL:      link.ShortURL,
+		Query:         query,
+		AllTimeClicks: link.Clicks,
+		Series:        fillSeries(query, points),
+	}
+	for _, point := range points {
+		stats.TotalClicks += point.Clicks
+		stats.HumanClicks += point.HumanClicks
+	}
+
+	if query.GroupBy != "" {
+		stats.Breakdown, err = a.clicks.ClickBreakdown(ctx, shortURL, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	days := statsDays(query)
+	daily, err := a.visitors.DailyVisitors(ctx, shortURL, days)
+	if err != nil {
+		return nil, err
+	}
+	stats.DailyVisitors = make([]domain.VisitorsPoint, 0, len(days))
+	for i, day := range days {
+		stats.DailyVisitors = append(stats.DailyVisitors, domain.VisitorsPoint{Day: day, Visitors: daily[i]})
+	}
+
+	stats.UniqueVisitors, err = a.visitors.UniqueVisitors(ctx, shortURL, days)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// statsDays lists the UTC days the query range touches.
+func statsDays(query domain.StatsQuery) []time.Time {
+	from := domain.StatsBucketDay.Truncate(query.From)
+	if last := domain.StatsBucketDay.Truncate(query.To).AddDate(0, 0, -maxStatsBuckets+1); from.Before(last) {
+		from = last
+	}
+
+	days := make([]time.Time, 0)
+	for day := from; day.Before(query.To); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	return days
+}
+
+// ExportClicks passes the clicks of the link of userID in [from, to) to fn,
+// oldest first. A zero from exports since the first click, a zero to up to now.
+func (a *Analytics) ExportClicks(ctx context.Context, userID string, shortURL string, from, to time.Time, fn func(domain.ClickEvent) error) error {
+	_, err := a.ownedLink(ctx, userID, shortURL)
+	if err != nil {
+		return err
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("%w: from must be before to", domain.ErrInvalidStatsQuery)
+	}
+
+	return a.clicks.ExportClicks(ctx, shortURL, from.UTC(), to.UTC(), fn)
+}
+
+// ExportLinks passes every link of userID to fn, newest first. Links are read
+// page by page, so the export does not hold all of them in memory.
+func (a *Analytics) ExportLinks(ctx context.Context, userID string, fn func(domain.URL) error) error {
+	if userID == "" {
+		return domain.ErrNotLinkOwner
+	}
+
+	filter := domain.LinkFilter{
+		OwnerID: userID,
+		SortBy:  domain.LinkSortCreatedAt,
+		Limit:   exportPageSize,
+	}
+	for {
+		links, err := a.links.ListLinks(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			err = fn(link)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(links) < exportPageSize {
+			return nil
+		}
+		last := links[len(links)-1]
+		filter.After = &domain.LinkCursor{SortBy: filter.SortBy, CreatedAt: last.CreatedAt, ID: last.Id}
+	}
+}
+
+func (a *Analytics) ownedLink(ctx context.Context, userID string, shortURL string) (*domain.URL, error) {
+	link, err := a.links.GetShortUrl(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.OwnerID == "" || link.OwnerID != userID {
+		return nil, domain.ErrNotLinkOwner
+	}
+
+	return link, nil
+}
+
+func normalizeStatsQuery(query domain.StatsQuery) (domain.StatsQuery, error) {
+	switch query.Bucket {
+	case "":
+		query.Bucket = domain.StatsBucketDay
+	case domain.StatsBucketHour, domain.StatsBucketDay, domain.StatsBucketWeek:
+	default:
+		return query, fmt.Errorf("%w: bucket must be one of: hour, day, week", domain.ErrInvalidStatsQuery)
+	}
+
+	switch query.GroupBy {
+	case "", domain.StatsDimensionBrowser, domain.StatsDimensionOS, domain.StatsDimensionDevice, domain.StatsDimensionBot,
+		domain.StatsDimensionCountry, domain.StatsDimensionRegion:
+	default:
+		return query, fmt.Errorf("%w: group_by must be one of: browser, os, device, bot, country, region", domain.ErrInvalidStatsQuery)
+	}
+
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultStatsRange)
+	}
+	query.From, query.To = query.From.UTC(), query.To.UTC()
+
+	if !query.From.Before(query.To) {
+		return query, fmt.Errorf("%w: from must be before to", domain.ErrInvalidStatsQuery)
+	}
+
+	buckets := 0
+	for start := query.Bucket.Truncate(query.From); start.Before(query.To); start = query.Bucket.Next(start) {
+		if buckets++; buckets > maxStatsBuckets {
+			return query, fmt.Errorf("%w: the range is too long for %s buckets", domain.ErrInvalidStatsQuery, query.Bucket)
+		}
+	}
+
+	return query, nil
+}
+
+// fillSeries adds the buckets without clicks to points.
+func fillSeries(query domain.StatsQuery, points []domain.StatsPoint) []domain.StatsPoint {
+	clicks := make(map[time.Time]domain.StatsPoint, len(points))
+	for _, point := range points {
+		start := point.Start.UTC()
+		sum := clicks[start]
+		sum.Clicks += point.Clicks
+		sum.HumanClicks += point.HumanClicks
+		clicks[start] = sum
+	}
+
+	series := make([]domain.StatsPoint, 0)
+	for start := query.Bucket.Truncate(query.From); start.Before(query.To); start = query.Bucket.Next(start) {
+		point := clicks[start]
+		point.Start = start
+		series = append(series, point)
+	}
+
+	return series
+}
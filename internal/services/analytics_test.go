@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalytics_Stats(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	query := domain.StatsQuery{From: from, To: from.AddDate(0, 0, 3), Bucket: domain.StatsBucketDay}
+
+	t.Run("Zero-filled series", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		clicks := urlMocks.NewClickStorage(t)
+		visitors := urlMocks.NewVisitorCounter(t)
+		analytics := NewAnalytics(db, clicks, visitors)
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42", Clicks: 9}, nil)
+		clicks.On("ClickStats", mock.Anything, "abc", query).Return([]domain.StatsPoint{
+			{Start: from.AddDate(0, 0, 1), Clicks: 4, HumanClicks: 3},
+		}, nil)
+		days := []time.Time{from, from.AddDate(0, 0, 1), from.AddDate(0, 0, 2)}
+		visitors.On("DailyVisitors", mock.Anything, "abc", days).Return([]int64{0, 3, 0}, nil)
+		visitors.On("UniqueVisitors", mock.Anything, "abc", days).Return(int64(3), nil)
+
+		stats, err := analytics.Stats(context.Background(), "42", "abc", query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(9), stats.AllTimeClicks)
+		assert.Equal(t, int64(4), stats.TotalClicks)
+		assert.Equal(t, int64(3), stats.HumanClicks)
+		assert.Nil(t, stats.Breakdown)
+		assert.Equal(t, []domain.StatsPoint{
+			{Start: from, Clicks: 0},
+			{Start: from.AddDate(0, 0, 1), Clicks: 4, HumanClicks: 3},
+			{Start: from.AddDate(0, 0, 2), Clicks: 0},
+		}, stats.Series)
+		assert.Equal(t, int64(3), stats.UniqueVisitors)
+		assert.Equal(t, domain.VisitorsPoint{Day: from.AddDate(0, 0, 1), Visitors: 3}, stats.DailyVisitors[1])
+	})
+
+	t.Run("Breakdown by device", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		clicks := urlMocks.NewClickStorage(t)
+		visitors := urlMocks.NewVisitorCounter(t)
+		analytics := NewAnalytics(db, clicks, visitors)
+
+		grouped := query
+		grouped.GroupBy = domain.StatsDimensionDevice
+		breakdown := []domain.BreakdownItem{
+			{Value: "mobile", Clicks: 5, HumanClicks: 5},
+			{Value: "bot", Clicks: 2},
+		}
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42"}, nil)
+		clicks.On("ClickStats", mock.Anything, "abc", grouped).Return([]domain.StatsPoint{}, nil)
+		clicks.On("ClickBreakdown", mock.Anything, "abc", grouped).Return(breakdown, nil)
+		visitors.On("DailyVisitors", mock.Anything, "abc", mock.Anything).Return([]int64{0, 0, 0}, nil)
+		visitors.On("UniqueVisitors", mock.Anything, "abc", mock.Anything).Return(int64(0), nil)
+
+		stats, err := analytics.Stats(context.Background(), "42", "abc", grouped)
+
+		assert.NoError(t, err)
+		assert.Equal(t, breakdown, stats.Breakdown)
+	})
+
+	t.Run("Unknown dimension", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		analytics := NewAnalytics(db, urlMocks.NewClickStorage(t), urlMocks.NewVisitorCounter(t))
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42"}, nil)
+
+		_, err := analytics.Stats(context.Background(), "42", "abc", domain.StatsQuery{GroupBy: "referrer"})
+
+		assert.ErrorIs(t, err, domain.ErrInvalidStatsQuery)
+	})
+
+	t.Run("Foreign link", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		clicks := urlMocks.NewClickStorage(t)
+		visitors := urlMocks.NewVisitorCounter(t)
+		analytics := NewAnalytics(db, clicks, visitors)
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "1"}, nil)
+
+		_, err := analytics.Stats(context.Background(), "42", "abc", query)
+
+		assert.ErrorIs(t, err, domain.ErrNotLinkOwner)
+	})
+
+	t.Run("Invalid range", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		clicks := urlMocks.NewClickStorage(t)
+		visitors := urlMocks.NewVisitorCounter(t)
+		analytics := NewAnalytics(db, clicks, visitors)
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42"}, nil)
+
+		_, err := analytics.Stats(context.Background(), "42", "abc", domain.StatsQuery{From: query.To, To: query.From})
+
+		assert.ErrorIs(t, err, domain.ErrInvalidStatsQuery)
+	})
+}
+
+func TestAnalytics_ExportLinks(t *testing.T) {
+	db := urlMocks.NewDatabase(t)
+	analytics := NewAnalytics(db, urlMocks.NewClickStorage(t), urlMocks.NewVisitorCounter(t))
+
+	page := make([]domain.URL, exportPageSize)
+	for i := range page {
+		page[i] = domain.URL{Id: strconv.Itoa(exportPageSize - i), CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	last := page[len(page)-1]
+	first := domain.LinkFilter{OwnerID: "42", SortBy: domain.LinkSortCreatedAt, Limit: exportPageSize}
+	second := first
+	second.After = &domain.LinkCursor{SortBy: domain.LinkSortCreatedAt, CreatedAt: last.CreatedAt, ID: last.Id}
+	db.On("ListLinks", mock.Anything, first).Return(page, nil).Once()
+	db.On("ListLinks", mock.Anything, second).Return([]domain.URL{{Id: "0"}}, nil).Once()
+
+	exported := 0
+	err := analytics.ExportLinks(context.Background(), "42", func(domain.URL) error {
+		exported++
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, exportPageSize+1, exported)
+}
+
+func TestAnalytics_ExportClicks(t *testing.T) {
+	t.Run("Foreign link", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		analytics := NewAnalytics(db, urlMocks.NewClickStorage(t), urlMocks.NewVisitorCounter(t))
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "1"}, nil)
+
+		err := analytics.ExportClicks(context.Background(), "42", "abc", time.Time{}, time.Time{}, func(domain.ClickEvent) error { return nil })
+
+		assert.ErrorIs(t, err, domain.ErrNotLinkOwner)
+	})
+
+	t.Run("Open range ends now", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		clicks := urlMocks.NewClickStorage(t)
+		analytics := NewAnalytics(db, clicks, urlMocks.NewVisitorCounter(t))
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42"}, nil)
+		clicks.On("ExportClicks", mock.Anything, "abc", time.Time{}, mock.MatchedBy(func(to time.Time) bool {
+			return time.Since(to) < time.Minute
+		}), mock.Anything).Return(nil)
+
+		err := analytics.ExportClicks(context.Background(), "42", "abc", time.Time{}, time.Time{}, func(domain.ClickEvent) error { return nil })
+
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/jwt"
+)
+
+const (
+	// apiKeyPrefix makes the keys easy to spot, e.g. by secret scanners.
+	apiKeyPrefix = "usk_"
+	// apiKeyTouchInterval limits the writes of last_used_at for busy keys.
+	apiKeyTouchInterval = time.Minute
+	maxAPIKeyNameLength = 100
+)
+
+type APIKeyStorage interface {
+	// CreateAPIKey stores the key and sets key.ID.
+	CreateAPIKey(ctx context.Context, key *domain.APIKey, keyHash string) error
+	ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID string) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error
+}
+
+// APIKeys manages the personal API keys of users and authenticates requests made with them.
+type APIKeys struct {
+	logger  *slog.Logger
+	storage APIKeyStorage
+}
+
+func NewAPIKeys(logger *slog.Logger, storage APIKeyStorage) *APIKeys {
+	return &APIKeys{
+		logger:  logger,
+		storage: storage,
+	}
+}
+
+// Create issues a key with scopes, a zero expiresAt never expires. The
+// returned secret is not stored and can not be shown again.
+func (k *APIKeys) Create(ctx context.Context, userID, name string, scopes []domain.Scope, expiresAt time.Time) (*domain.APIKey, string, error) {
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return nil, "", fmt.Errorf("%w: name must be 1 to %d characters", domain.ErrInvalidAPIKeyParams, maxAPIKeyNameLength)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", domain.ErrInvalidAPIKeyParams)
+	}
+
+	unique := make([]domain.Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, "", fmt.Errorf("%w: unknown scope %q", domain.ErrInvalidAPIKeyParams, scope)
+		}
+		if !slices.Contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+
+	now := time.Now()
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return nil, "", fmt.Errorf("%w: expiration must be in the future", domain.ErrInvalidAPIKeyParams)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("service.APIKeys.Create: %w", err)
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(buf)
+
+	key := &domain.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:len(apiKeyPrefix)+8],
+		Scopes:    unique,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}
+	err := k.storage.CreateAPIKey(ctx, key, hashAPIKey(secret))
+	if err != nil {
+		return nil, "", fmt.Errorf("service.APIKeys.Create: %w", err)
+	}
+
+	return key, secret, nil
+}
+
+// List returns the keys of the user that are not revoked, expired ones included.
+func (k *APIKeys) List(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	keys, err := k.storage.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service.APIKeys.List: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (k *APIKeys) Revoke(ctx context.Context, userID, keyID string) error {
+	err := k.storage.RevokeAPIKey(ctx, userID, keyID)
+	if err != nil {
+		return fmt.Errorf("service.APIKeys.Revoke: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey implements jwt.APIKeyAuthenticator. Unknown, revoked and
+// expired keys give no user and no error.
+func (k *APIKeys) AuthenticateAPIKey(ctx context.Context, secret string) (*jwt.UserInfo, error) {
+	key, err := k.storage.GetAPIKeyByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("service.APIKeys.Authenticate: %w", err)
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, nil
+	}
+
+	if now.Sub(key.LastUsedAt) >= apiKeyTouchInterval {
+		err = k.storage.TouchAPIKey(ctx, key.ID, now)
+		if err != nil {
+			k.logger.Error("failed to update api key last use", slog.String("key_id", key.ID), slog.String("error", err.Error()))
+		}
+	}
+
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	return &jwt.UserInfo{
+		UserID:   key.UserID,
+		APIKeyID: key.ID,
+		Scopes:   scopes,
+	}, nil
+}
+
+// hashAPIKey is what is stored instead of the key, the keys are random enough
+// for a plain SHA-256.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAPIKeys_Create(t *testing.T) {
+	t.Run("Create key", func(t *testing.T) {
+		storage := urlMocks.NewAPIKeyStorage(t)
+		keys := NewAPIKeys(&slog.Logger{}, storage)
+
+		var storedHash string
+		storage.On("CreateAPIKey", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*domain.APIKey).ID = "3"
+			storedHash = args.String(2)
+		}).Return(nil)
+
+		key, secret, err := keys.Create(context.Background(), "1", "ci", []domain.Scope{domain.ScopeLinksWrite, domain.ScopeLinksWrite, domain.ScopeStatsRead}, time.Time{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "3", key.ID)
+		assert.True(t, strings.HasPrefix(secret, "usk_"))
+		assert.Equal(t, secret[:12], key.Prefix)
+		assert.Equal(t, []domain.Scope{domain.ScopeLinksWrite, domain.ScopeStatsRead}, key.Scopes)
+		assert.Equal(t, hashAPIKey(secret), storedHash)
+		assert.NotContains(t, storedHash, secret[4:])
+	})
+
+	tests := []struct {
+		name      string
+		keyName   string
+		scopes    []domain.Scope
+		expiresAt time.Time
+	}{
+		{name: "Empty name", keyName: "", scopes: []domain.Scope{domain.ScopeLinksRead}},
+		{name: "No scopes", keyName: "ci"},
+		{name: "Unknown scope", keyName: "ci", scopes: []domain.Scope{"links:delete"}},
+		{name: "Expiration in the past", keyName: "ci", scopes: []domain.Scope{domain.ScopeLinksRead}, expiresAt: time.Now().Add(-time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := NewAPIKeys(&slog.Logger{}, urlMocks.NewAPIKeyStorage(t))
+
+			_, _, err := keys.Create(context.Background(), "1", tt.keyName, tt.scopes, tt.expiresAt)
+
+			assert.ErrorIs(t, err, domain.ErrInvalidAPIKeyParams)
+		})
+	}
+}
+
+func TestAPIKeys_AuthenticateAPIKey(t *testing.T) {
+	t.Run("Active key", func(t *testing.T) {
+		storage := urlMocks.NewAPIKeyStorage(t)
+		keys := NewAPIKeys(&slog.Logger{}, storage)
+
+		storage.On("GetAPIKeyByHash", mock.Anything, hashAPIKey("usk_secret")).Return(&domain.APIKey{
+			ID: "3", UserID: "1", Scopes: []domain.Scope{domain.ScopeLinksRead, domain.ScopeStatsRead},
+		}, nil)
+		storage.On("TouchAPIKey", mock.Anything, "3", mock.Anything).Return(nil)
+
+		user, err := keys.AuthenticateAPIKey(context.Background(), "usk_secret")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1", user.UserID)
+		assert.Equal(t, "3", user.APIKeyID)
+		assert.Equal(t, []string{"links:read", "stats:read"}, user.Scopes)
+	})
+
+	t.Run("Recently used key is not touched", func(t *testing.T) {
+		storage := urlMocks.NewAPIKeyStorage(t)
+		keys := NewAPIKeys(&slog.Logger{}, storage)
+
+		storage.On("GetAPIKeyByHash", mock.Anything, hashAPIKey("usk_secret")).Return(&domain.APIKey{
+			ID: "3", UserID: "1", Scopes: []domain.Scope{domain.ScopeLinksRead}, LastUsedAt: time.Now().Add(-time.Second),
+		}, nil)
+
+		user, err := keys.AuthenticateAPIKey(context.Background(), "usk_secret")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+
+	inactive := []struct {
+		name string
+		key  *domain.APIKey
+		err  error
+	}{
+		{name: "Unknown key", err: domain.ErrAPIKeyNotFound},
+		{name: "Revoked key", key: &domain.APIKey{ID: "3", RevokedAt: time.Now()}},
+		{name: "Expired key", key: &domain.APIKey{ID: "3", ExpiresAt: time.Now().Add(-time.Minute)}},
+	}
+	for _, tt := range inactive {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := urlMocks.NewAPIKeyStorage(t)
+			keys := NewAPIKeys(&slog.Logger{}, storage)
+
+			storage.On("GetAPIKeyByHash", mock.Anything, hashAPIKey("usk_secret")).Return(tt.key, tt.err)
+
+			user, err := keys.AuthenticateAPIKey(context.Background(), "usk_secret")
+
+			assert.NoError(t, err)
+			assert.Nil(t, user)
+		})
+	}
+
+	t.Run("Storage error", func(t *testing.T) {
+		storage := urlMocks.NewAPIKeyStorage(t)
+		keys := NewAPIKeys(&slog.Logger{}, storage)
+
+		storage.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(nil, errors.New("conn refused"))
+
+		_, err := keys.AuthenticateAPIKey(context.Background(), "usk_secret")
+
+		assert.Error(t, err)
+	})
+}
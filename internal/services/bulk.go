@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/services/encoder/base62"
+	"url-shortener/internal/services/uniqueIdGenerator/go-snowflake-master"
+)
+
+// bulkInsertSize is how many links are saved per transaction.
+const bulkInsertSize = 200
+
+// CreateBulk creates the links of params on behalf of ownerID. Every item gets
+// a result in the order of params: an invalid item or an item over the links
+// quota does not fail the others. It also returns the number of all links.
+func (u *URLShortener) CreateBulk(ctx context.Context, ownerID string, params []domain.LinkParams) ([]domain.BulkResult, int, error) {
+	if ownerID == "" && !u.allowAnonymous {
+		return nil, 0, domain.ErrAnonymousLinksDisabled
+	}
+	if len(params) == 0 || len(params) > domain.MaxBulkLinks {
+		return nil, 0, fmt.Errorf("%w: expected 1 to %d links", domain.ErrInvalidBulk, domain.MaxBulkLinks)
+	}
+
+	month := usageMonth(time.Now())
+	usage, err := u.ownerUsage(ctx, ownerID, month)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := slices.Clone(params)
+	results := make([]domain.BulkResult, len(items))
+	// fresh are the items to create, shared the items whose link may be
+	// shared by destination
+	var fresh []int
+	shared := make(map[string][]int)
+	aliases := make(map[string]struct{})
+	for i := range items {
+		items[i].OwnerID = ownerID
+		err = u.validateBulkItem(items[i], usage, aliases)
+		if err != nil {
+			results[i] = domain.BulkResult{Status: domain.BulkInvalid, Err: err}
+			continue
+		}
+
+		if items[i].Alias == "" && items[i].ExpiresAt.IsZero() && items[i].MaxClicks == 0 {
+			shared[items[i].LongURL] = append(shared[items[i].LongURL], i)
+		} else {
+			fresh = append(fresh, i)
+		}
+	}
+
+	repeated, err := u.existingBulkLinks(ctx, ownerID, shared, results)
+	if err != nil {
+		return nil, 0, err
+	}
+	for first := range repeated {
+		fresh = append(fresh, first)
+	}
+	slices.Sort(fresh)
+
+	fresh = u.withinLinksQuota(ctx, ownerID, usage, month, fresh, results)
+	created, err := u.insertBulk(ctx, items, fresh, results)
+	u.uncountLinks(ctx, ownerID, usage, month, int64(len(fresh)-created))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// a repeated destination gets the link of its first item
+	for first, others := range repeated {
+		for _, i := range others {
+			results[i] = results[first]
+			if results[i].Status == domain.BulkCreated {
+				results[i].Status = domain.BulkExists
+			}
+		}
+	}
+
+	var count int
+	if created > 0 {
+		count, err = u.db.GetCountShortUrls(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return results, count, nil
+}
+
+func (u *URLShortener) validateBulkItem(params domain.LinkParams, usage *domain.Usage, aliases map[string]struct{}) error {
+	err := validateLongURL(params.LongURL)
+	if err != nil {
+		return err
+	}
+
+	err = validateExpiration(params)
+	if err != nil {
+		return err
+	}
+
+	if params.Alias == "" {
+		return nil
+	}
+	err = checkAliasAllowed(usage)
+	if err != nil {
+		return err
+	}
+	err = u.validateAlias(params.Alias)
+	if err != nil {
+		return err
+	}
+	if _, ok := aliases[params.Alias]; ok {
+		return domain.ErrAliasTaken
+	}
+	aliases[params.Alias] = struct{}{}
+
+	return nil
+}
+
+// validateLongURL accepts absolute http and https urls.
+func validateLongURL(longURL string) error {
+	parsed, err := url.ParseRequestURI(longURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: %q is not an absolute http or https url", domain.ErrInvalidURL, longURL)
+	}
+
+	return nil
+}
+
+// existingBulkLinks sets the results of the shared items whose destination
+// the owner already shortened. The other destinations are returned by their
+// first item with the items repeating it.
+func (u *URLShortener) existingBulkLinks(ctx context.Context, ownerID string, shared map[string][]int, results []domain.BulkResult) (map[int][]int, error) {
+	repeated := make(map[int][]int, len(shared))
+	if len(shared) == 0 {
+		return repeated, nil
+	}
+
+	longURLs := make([]string, 0, len(shared))
+	for longURL := range shared {
+		longURLs = append(longURLs, longURL)
+	}
+	existing, err := u.db.GetByLongUrls(ctx, ownerID, longURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	for longURL, items := range shared {
+		link, ok := existing[longURL]
+		if !ok {
+			repeated[items[0]] = items[1:]
+			continue
+		}
+		for _, i := range items {
+			results[i] = domain.BulkResult{Status: domain.BulkExists, Link: &link}
+		}
+	}
+
+	return repeated, nil
+}
+
+// withinLinksQuota counts the fresh items against the links quota and returns
+// those that fit, the results of the others are set.
+func (u *URLShortener) withinLinksQuota(ctx context.Context, ownerID string, usage *domain.Usage, month time.Time, fresh []int, results []domain.BulkResult) []int {
+	if usage == nil {
+		return fresh
+	}
+
+	if limit := usage.Plan.MonthlyLinks; limit > 0 {
+		quotaErr := &domain.QuotaExceededError{Quota: domain.QuotaLinks, Limit: limit, ResetsAt: month.AddDate(0, 1, 0)}
+		left := int(max(limit-usage.Links, 0))
+		if len(fresh) > left {
+			for _, i := range fresh[left:] {
+				results[i] = domain.BulkResult{Status: domain.BulkQuotaExceeded, Err: quotaErr}
+			}
+			fresh = fresh[:left]
+		}
+	}
+
+	// another request may have used the quota since it was read
+	err := u.countLinks(ctx, ownerID, usage, month, int64(len(fresh)))
+	if err != nil {
+		for _, i := range fresh {
+			results[i] = domain.BulkResult{Status: domain.BulkQuotaExceeded, Err: err}
+		}
+		return nil
+	}
+
+	return fresh
+}
+
+// insertBulk saves the fresh items in batches and sets their results. It
+// returns the number of links created.
+func (u *URLShortener) insertBulk(ctx context.Context, items []domain.LinkParams, fresh []int, results []domain.BulkResult) (int, error) {
+	var created int
+	for start := 0; start < len(fresh); start += bulkInsertSize {
+		chunk := fresh[start:min(start+bulkInsertSize, len(fresh))]
+		// a generated code may be taken by a custom alias, so just draw
+		// another id for the links that were skipped
+		for attempt := 0; len(chunk) > 0; attempt++ {
+			urls := make([]domain.URL, len(chunk))
+			for j, i := range chunk {
+				id := snowflake.ID()
+				shortURL := items[i].Alias
+				if shortURL == "" {
+					shortURL = base62.Base62Encode(id)
+				}
+				urls[j] = newURL(id, shortURL, items[i])
+			}
+
+			saved, err := u.db.InsertUrls(ctx, urls)
+			if err != nil {
+				return created, err
+			}
+			savedSet := make(map[string]struct{}, len(saved))
+			for _, shortURL := range saved {
+				savedSet[shortURL] = struct{}{}
+			}
+
+			var retry []int
+			for j, i := range chunk {
+				if _, ok := savedSet[urls[j].ShortURL]; ok {
+					results[i] = domain.BulkResult{Status: domain.BulkCreated, Link: &urls[j]}
+					created++
+					continue
+				}
+				if items[i].Alias != "" {
+					results[i] = u.takenAliasResult(ctx, items[i])
+					continue
+				}
+				if attempt == maxGenerateAttempts-1 {
+					results[i] = domain.BulkResult{Status: domain.BulkInvalid, Err: domain.ErrShortURLAlreadyExist}
+					continue
+				}
+				retry = append(retry, i)
+			}
+			chunk = retry
+		}
+	}
+
+	return created, nil
+}
+
+// takenAliasResult is the result of an item whose alias is taken, repeating
+// the same item is not a conflict.
+func (u *URLShortener) takenAliasResult(ctx context.Context, params domain.LinkParams) domain.BulkResult {
+	existUrl, err := u.db.GetShortUrl(ctx, params.Alias)
+	if err == nil && existUrl.OwnerID == params.OwnerID && existUrl.LongURL == params.LongURL {
+		return domain.BulkResult{Status: domain.BulkExists, Link: existUrl}
+	}
+
+	return domain.BulkResult{Status: domain.BulkInvalid, Err: domain.ErrAliasTaken}
+}
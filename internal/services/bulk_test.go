@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestURLShortener_CreateBulk(t *testing.T) {
+	saveAll := func(_ context.Context, urls []domain.URL) ([]string, error) {
+		saved := make([]string, len(urls))
+		for i, url := range urls {
+			saved[i] = url.ShortURL
+		}
+		return saved, nil
+	}
+
+	t.Run("Per item results", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, nil, nil)
+
+		existing := domain.URL{ShortURL: "abc", LongURL: "https://old.com", OwnerID: "42"}
+		db.On("GetByLongUrls", mock.Anything, "42", mock.Anything).Return(map[string]domain.URL{"https://old.com": existing}, nil)
+		db.On("InsertUrls", mock.Anything, mock.MatchedBy(func(urls []domain.URL) bool {
+			return len(urls) == 1 && isGeneratedURL(urls[0], "https://new.com", "42")
+		})).Return(saveAll)
+		db.On("GetCountShortUrls", mock.Anything).Return(5, nil)
+
+		results, count, err := shortener.CreateBulk(context.Background(), "42", []domain.LinkParams{
+			{LongURL: "https://new.com"},
+			{LongURL: "https://old.com"},
+			{LongURL: "not a url"},
+			{LongURL: "https://new.com"},
+			{LongURL: "ftp://files.com"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, count)
+		assert.Len(t, results, 5)
+		assert.Equal(t, domain.BulkCreated, results[0].Status)
+		assert.Equal(t, domain.BulkExists, results[1].Status)
+		assert.Equal(t, &existing, results[1].Link)
+		assert.Equal(t, domain.BulkInvalid, results[2].Status)
+		assert.ErrorIs(t, results[2].Err, domain.ErrInvalidURL)
+		assert.Equal(t, domain.BulkExists, results[3].Status)
+		assert.Equal(t, results[0].Link, results[3].Link)
+		assert.Equal(t, domain.BulkInvalid, results[4].Status)
+	})
+
+	t.Run("Aliases", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, nil, nil)
+
+		db.On("InsertUrls", mock.Anything, mock.Anything).Return([]string{"spring-sale"}, nil)
+		db.On("GetShortUrl", mock.Anything, "summer-sale").Return(&domain.URL{ShortURL: "summer-sale", LongURL: "https://example.com/summer", OwnerID: "42"}, nil)
+		db.On("GetShortUrl", mock.Anything, "winter-sale").Return(&domain.URL{ShortURL: "winter-sale", LongURL: "https://other.com", OwnerID: "7"}, nil)
+		db.On("GetCountShortUrls", mock.Anything).Return(5, nil)
+
+		results, _, err := shortener.CreateBulk(context.Background(), "42", []domain.LinkParams{
+			{LongURL: "https://example.com/spring", Alias: "spring-sale"},
+			{LongURL: "https://example.com/spring", Alias: "spring-sale"},
+			{LongURL: "https://example.com/summer", Alias: "summer-sale"},
+			{LongURL: "https://example.com/winter", Alias: "winter-sale"},
+			{LongURL: "https://example.com/x", Alias: "x"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkCreated, results[0].Status)
+		assert.Equal(t, "spring-sale", results[0].Link.ShortURL)
+		assert.ErrorIs(t, results[1].Err, domain.ErrAliasTaken)
+		assert.Equal(t, domain.BulkExists, results[2].Status)
+		assert.Equal(t, domain.BulkInvalid, results[3].Status)
+		assert.ErrorIs(t, results[3].Err, domain.ErrAliasTaken)
+		assert.ErrorIs(t, results[4].Err, domain.ErrInvalidAlias)
+	})
+
+	t.Run("Retry taken generated code", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, nil, nil)
+
+		db.On("GetByLongUrls", mock.Anything, "42", mock.Anything).Return(map[string]domain.URL{}, nil)
+		db.On("InsertUrls", mock.Anything, mock.Anything).Return([]string{}, nil).Once()
+		db.On("InsertUrls", mock.Anything, mock.Anything).Return(saveAll).Once()
+		db.On("GetCountShortUrls", mock.Anything).Return(1, nil)
+
+		results, _, err := shortener.CreateBulk(context.Background(), "42", []domain.LinkParams{{LongURL: "https://example.com"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkCreated, results[0].Status)
+	})
+
+	t.Run("Links over quota", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		usage := urlMocks.NewUsageStorage(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, usage, nil)
+
+		month := usageMonth(time.Now())
+		free := domain.Plan{Name: "free", MonthlyLinks: 2}
+		usage.On("GetUsage", mock.Anything, "42", month).Return(&domain.Usage{Plan: free, Links: 1}, nil)
+		db.On("GetByLongUrls", mock.Anything, "42", mock.Anything).Return(map[string]domain.URL{}, nil)
+		usage.On("AddLinkUsage", mock.Anything, "42", month, int64(1), int64(2)).Return(nil)
+		db.On("InsertUrls", mock.Anything, mock.Anything).Return(saveAll)
+		db.On("GetCountShortUrls", mock.Anything).Return(1, nil)
+
+		results, _, err := shortener.CreateBulk(context.Background(), "42", []domain.LinkParams{
+			{LongURL: "https://a.com"},
+			{LongURL: "https://b.com"},
+			{LongURL: "https://c.com"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkCreated, results[0].Status)
+		assert.Equal(t, domain.BulkQuotaExceeded, results[1].Status)
+		assert.Equal(t, domain.BulkQuotaExceeded, results[2].Status)
+		var quotaErr *domain.QuotaExceededError
+		assert.ErrorAs(t, results[1].Err, &quotaErr)
+	})
+
+	t.Run("Give back links not created", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		usage := urlMocks.NewUsageStorage(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, usage, nil)
+
+		month := usageMonth(time.Now())
+		pro := domain.Plan{Name: "pro", CustomAliases: true}
+		usage.On("GetUsage", mock.Anything, "42", month).Return(&domain.Usage{Plan: pro}, nil)
+		usage.On("AddLinkUsage", mock.Anything, "42", month, int64(2), int64(0)).Return(nil)
+		db.On("InsertUrls", mock.Anything, mock.Anything).Return([]string{"spring-sale"}, nil)
+		db.On("GetShortUrl", mock.Anything, "winter-sale").Return(&domain.URL{ShortURL: "winter-sale", LongURL: "https://other.com", OwnerID: "7"}, nil)
+		usage.On("RemoveLinkUsage", mock.Anything, "42", month, int64(1)).Return(nil)
+		db.On("GetCountShortUrls", mock.Anything).Return(1, nil)
+
+		_, _, err := shortener.CreateBulk(context.Background(), "42", []domain.LinkParams{
+			{LongURL: "https://example.com/spring", Alias: "spring-sale"},
+			{LongURL: "https://example.com/winter", Alias: "winter-sale"},
+		})
+
+		assert.NoError(t, err)
+		usage.AssertExpectations(t)
+	})
+
+	t.Run("Too many links", func(t *testing.T) {
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), urlMocks.NewDatabase(t), nil, nil)
+
+		_, _, err := shortener.CreateBulk(context.Background(), "42", make([]domain.LinkParams, domain.MaxBulkLinks+1))
+
+		assert.ErrorIs(t, err, domain.ErrInvalidBulk)
+	})
+}
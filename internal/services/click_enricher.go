@@ -0,0 +1,28 @@
+package services
+
+import "url-shortener/internal/domain"
+
+// ClickEnricher derives the user agent and location fields of click events.
+type ClickEnricher struct {
+	classifier AgentClassifier
+	geo        GeoLocator
+}
+
+// NewClickEnricher creates an enricher, geo is optional.
+func NewClickEnricher(classifier AgentClassifier, geo GeoLocator) *ClickEnricher {
+	return &ClickEnricher{
+		classifier: classifier,
+		geo:        geo,
+	}
+}
+
+// Enrich fills the user agent fields and, with a GeoIP database, the location of the event.
+func (e *ClickEnricher) Enrich(event *domain.ClickEvent) {
+	agent := e.classifier.Classify(event.UserAgent)
+	event.Browser, event.OS, event.Device, event.Bot = agent.Browser, agent.OS, agent.Device, agent.Bot
+
+	if e.geo != nil {
+		location := e.geo.Locate(event.IP)
+		event.Country, event.Region = location.Country, location.Region
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/metrics"
+)
+
+// flushTimeout bounds the last flush on shutdown.
+const flushTimeout = 5 * time.Second
+
+// ClickRecorder stores click events in batches in the background, so that
+// recording a click never slows down a redirect.
+type ClickRecorder struct {
+	logger           *slog.Logger
+	storage          ClickStorage
+	visitors         VisitorCounter
+	usage            UsageStorage
+	enricher         *ClickEnricher
+	metrics          *metrics.PrometheusMetrics
+	events           chan domain.ClickEvent
+	batchSize        int
+	flushInterval    time.Duration
+	visitorSalt      string
+	visitorRetention time.Duration
+}
+
+// NewClickRecorder creates the recorder, without usage the clicks are not
+// limited by plans.
+func NewClickRecorder(cfg *config.AnalyticsConfig, logger *slog.Logger, storage ClickStorage, visitors VisitorCounter, usage UsageStorage, enricher *ClickEnricher, metrics *metrics.PrometheusMetrics) *ClickRecorder {
+	salt := cfg.VisitorHashSalt
+	if salt == "" {
+		logger.Warn("VISITOR_HASH_SALT is not set, unique visitors are counted per process")
+		buf := make([]byte, 16)
+		rand.Read(buf)
+		salt = hex.EncodeToString(buf)
+	}
+
+	return &ClickRecorder{
+		logger:           logger,
+		storage:          storage,
+		visitors:         visitors,
+		usage:            usage,
+		enricher:         enricher,
+		metrics:          metrics,
+		events:           make(chan domain.ClickEvent, cfg.ClickBufferSize),
+		batchSize:        max(cfg.ClickBatchSize, 1),
+		flushInterval:    cfg.ClickFlushInterval,
+		visitorSalt:      salt,
+		visitorRetention: cfg.VisitorRetention,
+	}
+}
+
+// Record queues the event without blocking, the event is dropped when the buffer is full.
+func (c *ClickRecorder) Record(event domain.ClickEvent) {
+	event.IPNetwork = anonymizeIP(event.IP)
+	event.VisitorID = c.visitorID(event)
+
+	select {
+	case c.events <- event:
+	default:
+		c.metrics.ClickEventsDropped.Inc()
+	}
+}
+
+// Run stores the queued events until ctx is done, then flushes what is left.
+func (c *ClickRecorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.ClickEvent, 0, c.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			for i := len(c.events); i > 0; i-- {
+				batch = append(batch, <-c.events)
+			}
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			c.flush(flushCtx, batch)
+			cancel()
+			return ctx.Err()
+		case event := <-c.events:
+			batch = append(batch, event)
+			if len(batch) >= c.batchSize {
+				batch = c.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = c.flush(ctx, batch)
+		}
+	}
+}
+
+func (c *ClickRecorder) flush(ctx context.Context, batch []domain.ClickEvent) []domain.ClickEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	c.countClicks(ctx, batch)
+
+	// enriching here keeps the regular expressions and lookups off the redirect path
+	for i := range batch {
+		c.enricher.Enrich(&batch[i])
+	}
+	batch = c.withinQuota(ctx, batch)
+
+	humans := make([]domain.ClickEvent, 0, len(batch))
+	for _, event := range batch {
+		if !event.Bot {
+			humans = append(humans, event)
+		}
+	}
+
+	err := c.storage.InsertClicks(ctx, batch)
+	if err != nil {
+		c.logger.Error("failed to store click events", slog.Int("count", len(batch)), slog.String("error", err.Error()))
+		c.metrics.ClickEventsDropped.Add(float64(len(batch)))
+	}
+
+	// bots are not visitors
+	err = c.visitors.AddVisitors(ctx, humans, c.visitorRetention)
+	if err != nil {
+		c.logger.Error("failed to count unique visitors", slog.Int("count", len(humans)), slog.String("error", err.Error()))
+	}
+
+	// the storage may keep the batch, so it is not reused
+	return make([]domain.ClickEvent, 0, c.batchSize)
+}
+
+// countClicks adds the batch to the click counters of the links, every
+// redirect counts, even when its event is not stored.
+func (c *ClickRecorder) countClicks(ctx context.Context, batch []domain.ClickEvent) {
+	clicks := make(map[string]int64)
+	for _, event := range batch {
+		clicks[event.ShortURL]++
+	}
+
+	err := c.storage.AddClicks(ctx, clicks)
+	if err != nil {
+		c.logger.Error("failed to count clicks", slog.Int("count", len(batch)), slog.String("error", err.Error()))
+	}
+}
+
+// withinQuota drops the events over the clicks quota of the owners of their
+// links. The redirects are served anyway, only their analytics are lost. Bots
+// are not counted against the quota and their events are always kept.
+func (c *ClickRecorder) withinQuota(ctx context.Context, batch []domain.ClickEvent) []domain.ClickEvent {
+	if c.usage == nil {
+		return batch
+	}
+
+	clicks := make(map[string]int64)
+	for _, event := range batch {
+		if !event.Bot {
+			clicks[event.ShortURL]++
+		}
+	}
+	if len(clicks) == 0 {
+		return batch
+	}
+
+	// a failure must not lose the clicks, they are stored untracked
+	allowed, err := c.usage.TrackClicks(ctx, usageMonth(time.Now()), clicks)
+	if err != nil {
+		c.logger.Error("failed to track click usage", slog.Int("count", len(batch)), slog.String("error", err.Error()))
+		return batch
+	}
+
+	kept := batch[:0]
+	for _, event := range batch {
+		if event.Bot {
+			kept = append(kept, event)
+			continue
+		}
+		if allowed[event.ShortURL] > 0 {
+			allowed[event.ShortURL]--
+			kept = append(kept, event)
+		}
+	}
+	c.metrics.ClickEventsOverQuota.Add(float64(len(batch) - len(kept)))
+
+	return kept
+}
+
+// visitorID hashes the full address, so visitors of the same network are told apart.
+func (c *ClickRecorder) visitorID(event domain.ClickEvent) string {
+	sum := sha256.Sum256([]byte(c.visitorSalt + "\x00" + event.IP + "\x00" + event.UserAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+// anonymizeIP keeps the network part of the address only.
+func anonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+	"url-shortener/pkg/geoip"
+	"url-shortener/pkg/metrics"
+	"url-shortener/pkg/useragent"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClickRecorder(t *testing.T) {
+	t.Run("Flush full batch and the rest on shutdown", func(t *testing.T) {
+		logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+		storage := urlMocks.NewClickStorage(t)
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		visitors := urlMocks.NewVisitorCounter(t)
+		cfg := &config.AnalyticsConfig{ClickBufferSize: 10, ClickBatchSize: 2, ClickFlushInterval: time.Hour, VisitorHashSalt: "salt", VisitorRetention: time.Hour}
+		classifier := urlMocks.NewAgentClassifier(t)
+		geo := urlMocks.NewGeoLocator(t)
+		recorder := NewClickRecorder(cfg, logger, storage, visitors, nil, NewClickEnricher(classifier, geo), m)
+
+		var stored, counted []domain.ClickEvent
+		storage.On("InsertClicks", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = append(stored, args.Get(1).([]domain.ClickEvent)...)
+		}).Return(nil)
+		visitors.On("AddVisitors", mock.Anything, mock.Anything, time.Hour).Run(func(args mock.Arguments) {
+			counted = append(counted, args.Get(1).([]domain.ClickEvent)...)
+		}).Return(nil)
+		classifier.On("Classify", "Mozilla/5.0").Return(useragent.Agent{Browser: "Firefox", OS: "Linux", Device: "desktop"})
+		geo.On("Locate", "203.0.113.77").Return(geoip.Location{Country: "DE", Region: "DE-BE"})
+		geo.On("Locate", mock.Anything).Return(geoip.Location{})
+		classifier.On("Classify", "Slackbot 1.0").Return(useragent.Agent{Browser: "Slackbot", OS: "other", Device: "bot", Bot: true})
+		clicks := make(map[string]int64)
+		storage.On("AddClicks", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			for shortURL, n := range args.Get(1).(map[string]int64) {
+				clicks[shortURL] += n
+			}
+		}).Return(nil)
+
+		recorder.Record(domain.ClickEvent{ShortURL: "a", IP: "203.0.113.77", UserAgent: "Mozilla/5.0"})
+		recorder.Record(domain.ClickEvent{ShortURL: "b", IP: "2001:db8:1:2::1", UserAgent: "Mozilla/5.0"})
+		recorder.Record(domain.ClickEvent{ShortURL: "c", UserAgent: "Slackbot 1.0"})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := recorder.Run(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Len(t, stored, 3)
+		assert.Equal(t, "203.0.113.0/24", stored[0].IPNetwork)
+		assert.Equal(t, "2001:db8:1::/48", stored[1].IPNetwork)
+		assert.Equal(t, "", stored[2].IPNetwork)
+		assert.Equal(t, map[string]int64{"a": 1, "b": 1, "c": 1}, clicks)
+		assert.NotEmpty(t, stored[0].VisitorID)
+		assert.NotEqual(t, stored[0].VisitorID, stored[1].VisitorID)
+		assert.Equal(t, "Firefox", stored[0].Browser)
+		assert.Equal(t, "DE-BE", stored[0].Region)
+		assert.Equal(t, "", stored[1].Country)
+		assert.True(t, stored[2].Bot)
+		// bots are left out of unique visitors
+		assert.Len(t, counted, 2)
+	})
+
+	t.Run("Same visitor on different links", func(t *testing.T) {
+		logger := &slog.Logger{}
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		cfg := &config.AnalyticsConfig{ClickBufferSize: 2, ClickBatchSize: 2, VisitorHashSalt: "salt"}
+		recorder := NewClickRecorder(cfg, logger, urlMocks.NewClickStorage(t), urlMocks.NewVisitorCounter(t), nil, NewClickEnricher(urlMocks.NewAgentClassifier(t), nil), m)
+
+		a := recorder.visitorID(domain.ClickEvent{ShortURL: "a", IP: "203.0.113.7", UserAgent: "curl"})
+		b := recorder.visitorID(domain.ClickEvent{ShortURL: "b", IP: "203.0.113.7", UserAgent: "curl"})
+		other := recorder.visitorID(domain.ClickEvent{ShortURL: "a", IP: "203.0.113.8", UserAgent: "curl"})
+
+		assert.Equal(t, a, b)
+		assert.NotEqual(t, a, other)
+		assert.NotContains(t, a, "203.0.113")
+	})
+
+	t.Run("Drop events when the buffer is full", func(t *testing.T) {
+		logger := &slog.Logger{}
+		storage := urlMocks.NewClickStorage(t)
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		cfg := &config.AnalyticsConfig{ClickBufferSize: 1, ClickBatchSize: 1, ClickFlushInterval: time.Hour, VisitorHashSalt: "salt"}
+		recorder := NewClickRecorder(cfg, logger, storage, urlMocks.NewVisitorCounter(t), nil, NewClickEnricher(urlMocks.NewAgentClassifier(t), nil), m)
+
+		recorder.Record(domain.ClickEvent{ShortURL: "a"})
+		recorder.Record(domain.ClickEvent{ShortURL: "b"})
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.ClickEventsDropped))
+	})
+
+	t.Run("Drop events over the clicks quota", func(t *testing.T) {
+		logger := &slog.Logger{}
+		storage := urlMocks.NewClickStorage(t)
+		visitors := urlMocks.NewVisitorCounter(t)
+		usage := urlMocks.NewUsageStorage(t)
+		classifier := urlMocks.NewAgentClassifier(t)
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		cfg := &config.AnalyticsConfig{ClickBufferSize: 10, ClickBatchSize: 10, ClickFlushInterval: time.Hour, VisitorHashSalt: "salt"}
+		recorder := NewClickRecorder(cfg, logger, storage, visitors, usage, NewClickEnricher(classifier, nil), m)
+
+		usage.On("TrackClicks", mock.Anything, usageMonth(time.Now()), map[string]int64{"a": 2, "b": 1}).
+			Return(map[string]int64{"a": 1, "b": 1}, nil)
+		classifier.On("Classify", mock.Anything).Return(useragent.Agent{})
+		var stored []domain.ClickEvent
+		storage.On("InsertClicks", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).([]domain.ClickEvent)
+		}).Return(nil)
+		visitors.On("AddVisitors", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		storage.On("AddClicks", mock.Anything, map[string]int64{"a": 2, "b": 1}).Return(nil)
+
+		recorder.flush(context.Background(), []domain.ClickEvent{{ShortURL: "a"}, {ShortURL: "b"}, {ShortURL: "a"}})
+
+		assert.Len(t, stored, 2)
+		assert.Equal(t, "a", stored[0].ShortURL)
+		assert.Equal(t, "b", stored[1].ShortURL)
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.ClickEventsOverQuota))
+	})
+
+	t.Run("Bots do not count against the clicks quota", func(t *testing.T) {
+		logger := &slog.Logger{}
+		storage := urlMocks.NewClickStorage(t)
+		visitors := urlMocks.NewVisitorCounter(t)
+		usage := urlMocks.NewUsageStorage(t)
+		classifier := urlMocks.NewAgentClassifier(t)
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		cfg := &config.AnalyticsConfig{ClickBufferSize: 10, ClickBatchSize: 10, ClickFlushInterval: time.Hour, VisitorHashSalt: "salt"}
+		recorder := NewClickRecorder(cfg, logger, storage, visitors, usage, NewClickEnricher(classifier, nil), m)
+
+		// the quota has room for one click, which the bot must not take
+		usage.On("TrackClicks", mock.Anything, usageMonth(time.Now()), map[string]int64{"a": 1}).
+			Return(map[string]int64{"a": 1}, nil)
+		classifier.On("Classify", "Googlebot/2.1").Return(useragent.Agent{Bot: true})
+		classifier.On("Classify", "Mozilla/5.0").Return(useragent.Agent{})
+		var stored []domain.ClickEvent
+		storage.On("InsertClicks", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).([]domain.ClickEvent)
+		}).Return(nil)
+		visitors.On("AddVisitors", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		storage.On("AddClicks", mock.Anything, map[string]int64{"a": 2}).Return(nil)
+
+		recorder.flush(context.Background(), []domain.ClickEvent{{ShortURL: "a", UserAgent: "Googlebot/2.1"}, {ShortURL: "a", UserAgent: "Mozilla/5.0"}})
+
+		assert.Len(t, stored, 2)
+		assert.True(t, stored[0].Bot)
+		assert.False(t, stored[1].Bot)
+		assert.Equal(t, float64(0), testutil.ToFloat64(m.ClickEventsOverQuota))
+	})
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/metrics"
+)
+
+const (
+	// liveQueueSize is how many clicks may wait to be published, the excess is dropped.
+	liveQueueSize = 1000
+	// liveSubscriberSize is how many clicks a slow subscriber may lag behind
+	// before it misses clicks.
+	liveSubscriberSize = 64
+	// liveWatchersInterval is how often the links watched on any replica are
+	// refreshed, a new watcher may miss the clicks of other replicas that long.
+	liveWatchersInterval = time.Second
+)
+
+// ClickStream delivers clicks to live subscribers as they happen. Redirects
+// publish to an in-process bus, with a broker the clicks are fanned out
+// through it, so the subscribers of every replica get the clicks of all of them.
+type ClickStream struct {
+	logger   *slog.Logger
+	links    Database
+	broker   ClickBroker
+	enricher *ClickEnricher
+	metrics  *metrics.PrometheusMetrics
+	queue    chan domain.ClickEvent
+
+	// subscribing serializes the broker subscriptions, so they follow the
+	// first and the last subscriber of a link without holding mu over the network.
+	subscribing sync.Mutex
+	mu          sync.RWMutex
+	subscribers map[string]map[chan domain.ClickEvent]struct{}
+	// watchers counts the replicas subscribed to each link through the broker.
+	watchers map[string]int64
+	closed   bool
+}
+
+// NewClickStream creates a stream, without a broker clicks stay in the process.
+func NewClickStream(logger *slog.Logger, links Database, broker ClickBroker, enricher *ClickEnricher, metrics *metrics.PrometheusMetrics) *ClickStream {
+	return &ClickStream{
+		logger:      logger,
+		links:       links,
+		broker:      broker,
+		enricher:    enricher,
+		metrics:     metrics,
+		queue:       make(chan domain.ClickEvent, liveQueueSize),
+		subscribers: make(map[string]map[chan domain.ClickEvent]struct{}),
+	}
+}
+
+// Publish queues the click without blocking.
+func (s *ClickStream) Publish(event domain.ClickEvent) {
+	select {
+	case s.queue <- event:
+	default:
+		s.metrics.LiveClicksDropped.Inc()
+	}
+}
+
+// Subscribe returns the clicks of the link of userID. The channel is closed
+// when the stream stops, cancel must be called once the clicks are not needed.
+func (s *ClickStream) Subscribe(ctx context.Context, userID string, shortURL string) (<-chan domain.ClickEvent, func(), error) {
+	link, err := s.links.GetShortUrl(ctx, shortURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if link.OwnerID == "" || link.OwnerID != userID {
+		return nil, nil, domain.ErrNotLinkOwner
+	}
+
+	events := make(chan domain.ClickEvent, liveSubscriberSize)
+
+	s.subscribing.Lock()
+	defer s.subscribing.Unlock()
+
+	// the first subscriber of the link on this replica
+	if s.broker != nil && !s.hasSubscribers(shortURL) && !s.isClosed() {
+		err = s.broker.SubscribeClicks(ctx, shortURL)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		close(events)
+		return events, func() {}, nil
+	}
+
+	subscribers, ok := s.subscribers[shortURL]
+	if !ok {
+		subscribers = make(map[chan domain.ClickEvent]struct{})
+		s.subscribers[shortURL] = subscribers
+	}
+	subscribers[events] = struct{}{}
+
+	return events, func() { s.unsubscribe(shortURL, events) }, nil
+}
+
+func (s *ClickStream) unsubscribe(shortURL string, events chan domain.ClickEvent) {
+	s.subscribing.Lock()
+	defer s.subscribing.Unlock()
+
+	if !s.remove(shortURL, events) {
+		return
+	}
+	if s.broker != nil {
+		err := s.broker.UnsubscribeClicks(context.Background(), shortURL)
+		if err != nil {
+			s.logger.Error("failed to unsubscribe from clicks", slog.String("short_url", shortURL), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// Run publishes the queued clicks until ctx is done, then closes the subscriptions.
+func (s *ClickStream) Run(ctx context.Context) error {
+	defer s.close()
+
+	var refresh <-chan time.Time
+	if s.broker != nil {
+		received := make(chan struct{})
+		defer func() { <-received }()
+
+		go func() {
+			defer close(received)
+			err := s.broker.ReceiveClicks(ctx, s.deliver)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Error("failed to receive clicks", slog.String("error", err.Error()))
+			}
+		}()
+
+		s.refreshWatchers(ctx)
+		ticker := time.NewTicker(liveWatchersInterval)
+		defer ticker.Stop()
+		refresh = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-refresh:
+			s.refreshWatchers(ctx)
+		case event := <-s.queue:
+			s.publish(ctx, event)
+		}
+	}
+}
+
+// refreshWatchers fetches the links watched on any replica, on failure the
+// previous ones are kept.
+func (s *ClickStream) refreshWatchers(ctx context.Context) {
+	watchers, err := s.broker.ClickSubscribers(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("failed to count click subscribers", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.watchers = watchers
+}
+
+func (s *ClickStream) publish(ctx context.Context, event domain.ClickEvent) {
+	// the clicks of a link nobody watches are not sent anywhere
+	if !s.isWatched(event.ShortURL) {
+		return
+	}
+
+	event.IPNetwork = anonymizeIP(event.IP)
+	s.enricher.Enrich(&event)
+	// the address must not leave the process
+	event.IP, event.VisitorID = "", ""
+
+	if s.broker == nil {
+		s.deliver(event)
+		return
+	}
+
+	err := s.broker.PublishClick(ctx, event)
+	if err != nil {
+		s.logger.Error("failed to publish click", slog.String("short_url", event.ShortURL), slog.String("error", err.Error()))
+	}
+}
+
+// remove drops a subscriber and reports whether it was the last of its link.
+func (s *ClickStream) remove(shortURL string, events chan domain.ClickEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscribers := s.subscribers[shortURL]
+	if _, ok := subscribers[events]; !ok {
+		return false
+	}
+	delete(subscribers, events)
+	close(events)
+
+	if len(subscribers) > 0 {
+		return false
+	}
+	delete(s.subscribers, shortURL)
+
+	return true
+}
+
+func (s *ClickStream) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.closed
+}
+
+func (s *ClickStream) hasSubscribers(shortURL string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.subscribers[shortURL]) > 0
+}
+
+func (s *ClickStream) isWatched(shortURL string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.subscribers[shortURL]) > 0 || s.watchers[shortURL] > 0
+}
+
+// deliver passes the click to the subscribers of its link, a subscriber that
+// lags behind misses it.
+func (s *ClickStream) deliver(event domain.ClickEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for events := range s.subscribers[event.ShortURL] {
+		select {
+		case events <- event:
+		default:
+			s.metrics.LiveClicksDropped.Inc()
+		}
+	}
+}
+
+func (s *ClickStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for shortURL, subscribers := range s.subscribers {
+		for events := range subscribers {
+			close(events)
+		}
+		delete(s.subscribers, shortURL)
+	}
+}
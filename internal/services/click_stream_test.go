@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+	"url-shortener/pkg/metrics"
+	"url-shortener/pkg/useragent"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClickStream(t *testing.T) {
+	t.Run("In-process delivery", func(t *testing.T) {
+		logger := &slog.Logger{}
+		db := urlMocks.NewDatabase(t)
+		classifier := urlMocks.NewAgentClassifier(t)
+		stream := NewClickStream(logger, db, nil, NewClickEnricher(classifier, nil), metrics.NewMetrics(prometheus.NewRegistry()))
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42"}, nil)
+		classifier.On("Classify", "Mozilla/5.0").Return(useragent.Agent{Browser: "Firefox", OS: "Linux", Device: "desktop"})
+
+		events, cancel, err := stream.Subscribe(context.Background(), "42", "abc")
+		assert.NoError(t, err)
+		defer cancel()
+
+		ctx, stop := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- stream.Run(ctx) }()
+
+		stream.Publish(domain.ClickEvent{ShortURL: "other", UserAgent: "Mozilla/5.0"})
+		stream.Publish(domain.ClickEvent{ShortURL: "abc", IP: "203.0.113.7", UserAgent: "Mozilla/5.0"})
+
+		event := <-events
+		assert.Equal(t, "abc", event.ShortURL)
+		assert.Equal(t, "Firefox", event.Browser)
+		assert.Equal(t, "203.0.113.0/24", event.IPNetwork)
+		assert.Empty(t, event.IP)
+
+		stop()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		_, open := <-events
+		assert.False(t, open)
+	})
+
+	t.Run("Fan-out through the broker", func(t *testing.T) {
+		logger := &slog.Logger{}
+		db := urlMocks.NewDatabase(t)
+		broker := urlMocks.NewClickBroker(t)
+		classifier := urlMocks.NewAgentClassifier(t)
+		stream := NewClickStream(logger, db, broker, NewClickEnricher(classifier, nil), metrics.NewMetrics(prometheus.NewRegistry()))
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42"}, nil)
+		broker.On("SubscribeClicks", mock.Anything, "abc").Return(nil).Once()
+		broker.On("UnsubscribeClicks", mock.Anything, "abc").Return(nil).Once()
+
+		first, cancelFirst, err := stream.Subscribe(context.Background(), "42", "abc")
+		assert.NoError(t, err)
+		_, cancelSecond, err := stream.Subscribe(context.Background(), "42", "abc")
+		assert.NoError(t, err)
+
+		// a click of another replica
+		stream.deliver(domain.ClickEvent{ShortURL: "abc", Browser: "Chrome"})
+		assert.Equal(t, "Chrome", (<-first).Browser)
+
+		// the broker subscription is dropped with the last subscriber
+		cancelFirst()
+		cancelSecond()
+		broker.AssertExpectations(t)
+	})
+
+	t.Run("Only watched links are published to the broker", func(t *testing.T) {
+		logger := &slog.Logger{}
+		broker := urlMocks.NewClickBroker(t)
+		classifier := urlMocks.NewAgentClassifier(t)
+		stream := NewClickStream(logger, urlMocks.NewDatabase(t), broker, NewClickEnricher(classifier, nil), metrics.NewMetrics(prometheus.NewRegistry()))
+
+		published := make(chan domain.ClickEvent)
+		broker.On("ReceiveClicks", mock.Anything, mock.Anything).Return(nil)
+		// "abc" is watched on another replica
+		broker.On("ClickSubscribers", mock.Anything).Return(map[string]int64{"abc": 1}, nil)
+		classifier.On("Classify", mock.Anything).Return(useragent.Agent{})
+		broker.On("PublishClick", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			published <- args.Get(1).(domain.ClickEvent)
+		}).Return(nil)
+
+		ctx, stop := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- stream.Run(ctx) }()
+
+		stream.Publish(domain.ClickEvent{ShortURL: "other"})
+		stream.Publish(domain.ClickEvent{ShortURL: "abc"})
+
+		assert.Equal(t, "abc", (<-published).ShortURL)
+		stop()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		broker.AssertNumberOfCalls(t, "PublishClick", 1)
+	})
+
+	t.Run("Broker subscription does not block delivery", func(t *testing.T) {
+		logger := &slog.Logger{}
+		db := urlMocks.NewDatabase(t)
+		broker := urlMocks.NewClickBroker(t)
+		stream := NewClickStream(logger, db, broker, nil, metrics.NewMetrics(prometheus.NewRegistry()))
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42"}, nil)
+		db.On("GetShortUrl", mock.Anything, "xyz").Return(&domain.URL{ShortURL: "xyz", OwnerID: "42"}, nil)
+		broker.On("SubscribeClicks", mock.Anything, "abc").Return(nil)
+		broker.On("UnsubscribeClicks", mock.Anything, mock.Anything).Return(nil)
+
+		first, cancelFirst, err := stream.Subscribe(context.Background(), "42", "abc")
+		assert.NoError(t, err)
+		defer cancelFirst()
+
+		// clicks of other links are delivered while the broker answers
+		broker.On("SubscribeClicks", mock.Anything, "xyz").Run(func(mock.Arguments) {
+			stream.deliver(domain.ClickEvent{ShortURL: "abc", Browser: "Chrome"})
+		}).Return(nil)
+
+		_, cancelSecond, err := stream.Subscribe(context.Background(), "42", "xyz")
+		assert.NoError(t, err)
+		defer cancelSecond()
+		assert.Equal(t, "Chrome", (<-first).Browser)
+	})
+
+	t.Run("Lagging subscriber misses clicks", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		m := metrics.NewMetrics(prometheus.NewRegistry())
+		stream := NewClickStream(&slog.Logger{}, db, nil, nil, m)
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "42"}, nil)
+
+		events, cancel, err := stream.Subscribe(context.Background(), "42", "abc")
+		assert.NoError(t, err)
+		defer cancel()
+
+		for range liveSubscriberSize + 2 {
+			stream.deliver(domain.ClickEvent{ShortURL: "abc"})
+		}
+
+		assert.Len(t, events, liveSubscriberSize)
+		assert.Equal(t, float64(2), testutil.ToFloat64(m.LiveClicksDropped))
+	})
+
+	t.Run("Foreign link", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		stream := NewClickStream(&slog.Logger{}, db, nil, nil, metrics.NewMetrics(prometheus.NewRegistry()))
+
+		db.On("GetShortUrl", mock.Anything, "abc").Return(&domain.URL{ShortURL: "abc", OwnerID: "1"}, nil)
+
+		_, _, err := stream.Subscribe(context.Background(), "42", "abc")
+
+		assert.ErrorIs(t, err, domain.ErrNotLinkOwner)
+	})
+}
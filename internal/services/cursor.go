@@ -0,0 +1,33 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"url-shortener/internal/domain"
+)
+
+// Cursors are handed to clients as opaque strings, their content is an
+// implementation detail that may change between releases.
+
+func encodeCursor(cursor domain.LinkCursor) (string, error) {
+	buf, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func decodeCursor(cursor string) (*domain.LinkCursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	var res domain.LinkCursor
+	if err := json.Unmarshal(buf, &res); err != nil || res.ID == "" {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	return &res, nil
+}
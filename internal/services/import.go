@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/services/uniqueIdGenerator/go-snowflake-master"
+)
+
+// maxImportErrors bounds the row errors returned with a job at once.
+const maxImportErrors = 100
+
+type ImportStorage interface {
+	CreateImportJob(ctx context.Context, job domain.ImportJob, rows []domain.ImportRow) error
+	GetImportJob(ctx context.Context, id string) (*domain.ImportJob, error)
+	ListImportErrors(ctx context.Context, id string, after, limit int) ([]domain.ImportRowResult, error)
+	ClaimImportJob(ctx context.Context, lockedUntil time.Time) (*domain.ImportJob, error)
+	NextImportRows(ctx context.Context, id string, limit int) ([]domain.ImportRow, error)
+	SaveImportResults(ctx context.Context, id string, results []domain.ImportRowResult, lockedUntil time.Time) error
+	ReleaseImportJob(ctx context.Context, id, reason string, retryAt time.Time) error
+	FinishImportJob(ctx context.Context, id string, status domain.ImportStatus, reason string) error
+}
+
+// Imports creates the links of large uploads in the background. The jobs are
+// kept in the storage and leased to a worker, a job whose worker stopped is
+// resumed from its first row without a result.
+type Imports struct {
+	logger       *slog.Logger
+	storage      ImportStorage
+	links        *URLShortener
+	workers      int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	retryDelay   time.Duration
+	wake         chan struct{}
+}
+
+func NewImports(cfg *config.ImportConfig, logger *slog.Logger, storage ImportStorage, links *URLShortener) *Imports {
+	return &Imports{
+		logger:       logger,
+		storage:      storage,
+		links:        links,
+		workers:      max(cfg.Workers, 1),
+		pollInterval: cfg.PollInterval,
+		lease:        cfg.Lease,
+		maxAttempts:  max(cfg.MaxAttempts, 1),
+		retryDelay:   cfg.RetryDelay,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Create saves a job to import rows on behalf of ownerID, the rows that could
+// not be parsed are counted as invalid at once.
+func (i *Imports) Create(ctx context.Context, ownerID string, rows []domain.ImportRow) (*domain.ImportJob, error) {
+	if len(rows) == 0 || len(rows) > domain.MaxImportRows {
+		return nil, fmt.Errorf("%w: expected 1 to %d rows", domain.ErrInvalidImport, domain.MaxImportRows)
+	}
+
+	now := time.Now()
+	job := domain.ImportJob{
+		ID:        strconv.FormatUint(snowflake.ID(), 10),
+		OwnerID:   ownerID,
+		Status:    domain.ImportPending,
+		Total:     len(rows),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, row := range rows {
+		if row.Err != nil {
+			job.Invalid++
+		}
+	}
+	job.Processed = job.Invalid
+	if job.Processed == job.Total {
+		job.Status = domain.ImportDone
+		job.FinishedAt = now
+	}
+
+	err := i.storage.CreateImportJob(ctx, job, rows)
+	if err != nil {
+		return nil, fmt.Errorf("service.Imports.Create: %w", err)
+	}
+
+	if job.Status == domain.ImportPending {
+		select {
+		case i.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return &job, nil
+}
+
+// Get returns a job of ownerID with up to limit of its row errors after the
+// row index after, jobs of other users are not found.
+func (i *Imports) Get(ctx context.Context, ownerID, id string, after, limit int) (*domain.ImportJob, []domain.ImportRowResult, error) {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return nil, nil, domain.ErrImportJobNotFound
+	}
+
+	job, err := i.storage.GetImportJob(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.OwnerID != ownerID {
+		return nil, nil, domain.ErrImportJobNotFound
+	}
+
+	if limit <= 0 || limit > maxImportErrors {
+		limit = maxImportErrors
+	}
+	rowErrors, err := i.storage.ListImportErrors(ctx, id, after, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return job, rowErrors, nil
+}
+
+// Run processes the jobs with the workers until ctx is done.
+func (i *Imports) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for range i.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			i.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (i *Imports) work(ctx context.Context) {
+	ticker := time.NewTicker(i.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for i.processNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims a job and processes it, it reports whether there was one.
+func (i *Imports) processNext(ctx context.Context) bool {
+	job, err := i.storage.ClaimImportJob(ctx, time.Now().Add(i.lease))
+	if err != nil {
+		if !errors.Is(err, domain.ErrImportJobNotFound) && ctx.Err() == nil {
+			i.logger.Error("failed to claim import job", slog.String("error", err.Error()))
+		}
+		return false
+	}
+
+	err = i.Process(ctx, job)
+	if err == nil {
+		return true
+	}
+
+	// a job stopped by the shutdown is left to be resumed at once
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+	defer cancel()
+	if ctx.Err() != nil {
+		err = i.storage.ReleaseImportJob(releaseCtx, job.ID, "", time.Now())
+		if err != nil {
+			i.logger.Error("failed to release import job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+		}
+		return false
+	}
+
+	i.logger.Warn("import job failed", slog.String("job_id", job.ID), slog.Int("attempt", job.Attempts), slog.String("error", err.Error()))
+	if job.Attempts >= i.maxAttempts {
+		err = i.storage.FinishImportJob(releaseCtx, job.ID, domain.ImportFailed, err.Error())
+	} else {
+		err = i.storage.ReleaseImportJob(releaseCtx, job.ID, err.Error(), time.Now().Add(i.retryDelay))
+	}
+	if err != nil {
+		i.logger.Error("failed to release import job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+	}
+
+	return true
+}
+
+// Process creates the links of the rows of job that have no result yet,
+// saving the results chunk by chunk.
+func (i *Imports) Process(ctx context.Context, job *domain.ImportJob) error {
+	for {
+		rows, err := i.storage.NextImportRows(ctx, job.ID, domain.MaxBulkLinks)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return i.storage.FinishImportJob(ctx, job.ID, domain.ImportDone, "")
+		}
+
+		params := make([]domain.LinkParams, len(rows))
+		for j, row := range rows {
+			params[j] = row.Params
+		}
+		created, _, err := i.links.CreateBulk(ctx, job.OwnerID, params)
+		if err != nil {
+			return err
+		}
+
+		results := make([]domain.ImportRowResult, len(rows))
+		for j, row := range rows {
+			results[j] = domain.ImportRowResult{
+				Index:   row.Index,
+				Status:  created[j].Status,
+				LongURL: row.Params.LongURL,
+			}
+			if created[j].Link != nil {
+				results[j].ShortURL = created[j].Link.ShortURL
+			}
+			if created[j].Err != nil {
+				results[j].Error = created[j].Err.Error()
+			}
+		}
+
+		err = i.storage.SaveImportResults(ctx, job.ID, results, time.Now().Add(i.lease))
+		if err != nil {
+			return err
+		}
+	}
+}
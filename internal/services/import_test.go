@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImports(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	cfg := &config.ImportConfig{Workers: 1, PollInterval: time.Second, Lease: time.Minute, MaxAttempts: 3, RetryDelay: time.Second}
+
+	t.Run("Create counts unparsed rows", func(t *testing.T) {
+		storage := urlMocks.NewImportStorage(t)
+		imports := NewImports(cfg, logger, storage, nil)
+
+		rows := []domain.ImportRow{
+			{Index: 0, Params: domain.LinkParams{LongURL: "https://example.com"}},
+			{Index: 1, Err: domain.ErrInvalidExpiration},
+		}
+		storage.On("CreateImportJob", mock.Anything, mock.MatchedBy(func(job domain.ImportJob) bool {
+			return job.OwnerID == "42" && job.Status == domain.ImportPending && job.Total == 2 && job.Processed == 1 && job.Invalid == 1
+		}), rows).Return(nil)
+
+		job, err := imports.Create(context.Background(), "42", rows)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, job.ID)
+		assert.Equal(t, domain.ImportPending, job.Status)
+	})
+
+	t.Run("Create without valid rows is done", func(t *testing.T) {
+		storage := urlMocks.NewImportStorage(t)
+		imports := NewImports(cfg, logger, storage, nil)
+
+		storage.On("CreateImportJob", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		job, err := imports.Create(context.Background(), "42", []domain.ImportRow{{Err: domain.ErrInvalidURL}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ImportDone, job.Status)
+		assert.False(t, job.FinishedAt.IsZero())
+	})
+
+	t.Run("Create empty import", func(t *testing.T) {
+		imports := NewImports(cfg, logger, urlMocks.NewImportStorage(t), nil)
+
+		_, err := imports.Create(context.Background(), "42", nil)
+
+		assert.ErrorIs(t, err, domain.ErrInvalidImport)
+	})
+
+	t.Run("Get job of another user", func(t *testing.T) {
+		storage := urlMocks.NewImportStorage(t)
+		imports := NewImports(cfg, logger, storage, nil)
+
+		storage.On("GetImportJob", mock.Anything, "7").Return(&domain.ImportJob{ID: "7", OwnerID: "13"}, nil)
+
+		_, _, err := imports.Get(context.Background(), "42", "7", -1, 0)
+
+		assert.ErrorIs(t, err, domain.ErrImportJobNotFound)
+	})
+
+	t.Run("Get job with errors", func(t *testing.T) {
+		storage := urlMocks.NewImportStorage(t)
+		imports := NewImports(cfg, logger, storage, nil)
+
+		rowErrors := []domain.ImportRowResult{{Index: 3, Status: domain.BulkInvalid, Error: "invalid url"}}
+		storage.On("GetImportJob", mock.Anything, "7").Return(&domain.ImportJob{ID: "7", OwnerID: "42"}, nil)
+		storage.On("ListImportErrors", mock.Anything, "7", -1, maxImportErrors).Return(rowErrors, nil)
+
+		_, got, err := imports.Get(context.Background(), "42", "7", -1, 1000)
+
+		assert.NoError(t, err)
+		assert.Equal(t, rowErrors, got)
+	})
+
+	t.Run("Process rows in chunks", func(t *testing.T) {
+		storage := urlMocks.NewImportStorage(t)
+		db := urlMocks.NewDatabase(t)
+		imports := NewImports(cfg, logger, storage, New(&config.ShortenerConfig{}, logger, urlMocks.NewCache(t), db, nil, nil))
+
+		job := &domain.ImportJob{ID: "7", OwnerID: "42", Status: domain.ImportRunning}
+		storage.On("NextImportRows", mock.Anything, "7", domain.MaxBulkLinks).Return([]domain.ImportRow{
+			{Index: 4, Params: domain.LinkParams{LongURL: "https://example.com"}},
+			{Index: 5, Params: domain.LinkParams{LongURL: "not a url"}},
+		}, nil).Once()
+		storage.On("NextImportRows", mock.Anything, "7", domain.MaxBulkLinks).Return([]domain.ImportRow{}, nil).Once()
+		db.On("GetByLongUrls", mock.Anything, "42", []string{"https://example.com"}).
+			Return(map[string]domain.URL{"https://example.com": {ShortURL: "abc", LongURL: "https://example.com", OwnerID: "42"}}, nil)
+		storage.On("SaveImportResults", mock.Anything, "7", mock.MatchedBy(func(results []domain.ImportRowResult) bool {
+			return len(results) == 2 &&
+				results[0] == domain.ImportRowResult{Index: 4, Status: domain.BulkExists, ShortURL: "abc", LongURL: "https://example.com"} &&
+				results[1].Index == 5 && results[1].Status == domain.BulkInvalid && results[1].Error != ""
+		}), mock.AnythingOfType("time.Time")).Return(nil)
+		storage.On("FinishImportJob", mock.Anything, "7", domain.ImportDone, "").Return(nil)
+
+		err := imports.Process(context.Background(), job)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Retry failed job later", func(t *testing.T) {
+		storage := urlMocks.NewImportStorage(t)
+		imports := NewImports(cfg, logger, storage, nil)
+
+		storage.On("ClaimImportJob", mock.Anything, mock.AnythingOfType("time.Time")).Return(&domain.ImportJob{ID: "7", OwnerID: "42", Attempts: 1}, nil)
+		storage.On("NextImportRows", mock.Anything, "7", domain.MaxBulkLinks).Return(nil, errors.New("connection refused"))
+		storage.On("ReleaseImportJob", mock.Anything, "7", "connection refused", mock.AnythingOfType("time.Time")).Return(nil)
+
+		assert.True(t, imports.processNext(context.Background()))
+	})
+
+	t.Run("Fail job after max attempts", func(t *testing.T) {
+		storage := urlMocks.NewImportStorage(t)
+		imports := NewImports(cfg, logger, storage, nil)
+
+		storage.On("ClaimImportJob", mock.Anything, mock.AnythingOfType("time.Time")).Return(&domain.ImportJob{ID: "7", OwnerID: "42", Attempts: 3}, nil)
+		storage.On("NextImportRows", mock.Anything, "7", domain.MaxBulkLinks).Return(nil, errors.New("connection refused"))
+		storage.On("FinishImportJob", mock.Anything, "7", domain.ImportFailed, "connection refused").Return(nil)
+
+		assert.True(t, imports.processNext(context.Background()))
+	})
+
+	t.Run("No job to claim", func(t *testing.T) {
+		storage := urlMocks.NewImportStorage(t)
+		imports := NewImports(cfg, logger, storage, nil)
+
+		storage.On("ClaimImportJob", mock.Anything, mock.AnythingOfType("time.Time")).Return(nil, domain.ErrImportJobNotFound)
+
+		assert.False(t, imports.processNext(context.Background()))
+	})
+}
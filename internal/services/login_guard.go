@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+// LoginAttempts counts failed logins and locks the logins of nicknames and
+// IPs that fail too often.
+type LoginAttempts interface {
+	// AddLoginFailure counts a failure of key and returns the failures
+	// within window.
+	AddLoginFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	ResetLoginFailures(ctx context.Context, key string) error
+	// LockLogin refuses the logins of key for ttl, a longer lock is kept.
+	LockLogin(ctx context.Context, key string, ttl time.Duration) error
+	// LoginLockedFor returns how long the logins of key stay locked, zero
+	// when they are not.
+	LoginLockedFor(ctx context.Context, key string) (time.Duration, error)
+}
+
+type loginLimits struct {
+	maxFailures   int64
+	maxIPFailures int64
+	window        time.Duration
+	lockout       time.Duration
+	delayAfter    int64
+	maxDelay      time.Duration
+}
+
+// loginSubject is a nickname or an IP whose failed logins are counted.
+type loginSubject struct {
+	key         string
+	nickname    string
+	maxFailures int64
+}
+
+// loginSubjects returns the nickname and the IP of a login. The nickname is
+// counted whether the user exists or not, so a lock does not tell which
+// nicknames are registered.
+func (a *Auth) loginSubjects(nickname, ip string) []loginSubject {
+	subjects := []loginSubject{{
+		key:         "nickname:" + strings.ToLower(nickname),
+		nickname:    nickname,
+		maxFailures: a.limits.maxFailures,
+	}}
+	if ip != "" {
+		subjects = append(subjects, loginSubject{key: "ip:" + ip, maxFailures: a.limits.maxIPFailures})
+	}
+
+	return subjects
+}
+
+// checkLoginLock returns a *domain.LoginLockedError while the logins of the
+// nickname or the IP are locked. Redis failures do not block logins.
+func (a *Auth) checkLoginLock(ctx context.Context, nickname, ip string) error {
+	if a.attempts == nil {
+		return nil
+	}
+
+	var retryAfter time.Duration
+	for _, subject := range a.loginSubjects(nickname, ip) {
+		ttl, err := a.attempts.LoginLockedFor(ctx, subject.key)
+		if err != nil {
+			a.logger.Error("failed to check login lock", slog.String("error", err.Error()))
+			continue
+		}
+		retryAfter = max(retryAfter, ttl)
+	}
+	if retryAfter > 0 {
+		return &domain.LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// loginFailed counts a failed login. Failures of a nickname past delayAfter
+// lock it for a growing delay, too many failures lock the nickname or the IP
+// for the lockout and are recorded in the audit.
+func (a *Auth) loginFailed(ctx context.Context, nickname, ip string) {
+	if a.attempts == nil {
+		return
+	}
+
+	for _, subject := range a.loginSubjects(nickname, ip) {
+		failures, err := a.attempts.AddLoginFailure(ctx, subject.key, a.limits.window)
+		if err != nil {
+			a.logger.Error("failed to count failed login", slog.String("error", err.Error()))
+			continue
+		}
+
+		lockout := failures >= subject.maxFailures
+		var lock time.Duration
+		switch {
+		case lockout:
+			lock = a.limits.lockout
+		// an IP is shared by many users, it is only locked out
+		case subject.nickname != "" && failures >= a.limits.delayAfter:
+			lock = loginDelay(failures-a.limits.delayAfter, a.limits.maxDelay)
+		default:
+			continue
+		}
+
+		err = a.attempts.LockLogin(ctx, subject.key, lock)
+		if err != nil {
+			a.logger.Error("failed to lock login", slog.String("error", err.Error()))
+			continue
+		}
+		if lockout {
+			a.recordLockout(ctx, subject.nickname, ip, failures)
+		}
+	}
+}
+
+// loginSucceeded forgets the failures of the nickname. Those of the IP are
+// kept, otherwise logins to an own account would let an attacker go on.
+func (a *Auth) loginSucceeded(ctx context.Context, nickname string) {
+	if a.attempts == nil {
+		return
+	}
+
+	err := a.attempts.ResetLoginFailures(ctx, a.loginSubjects(nickname, "")[0].key)
+	if err != nil {
+		a.logger.Error("failed to reset failed logins", slog.String("error", err.Error()))
+	}
+}
+
+func (a *Auth) recordLockout(ctx context.Context, nickname, ip string, failures int64) {
+	now := time.Now()
+	a.logger.Warn("logins locked after failed attempts",
+		slog.String("nickname", nickname), slog.String("ip", ip), slog.Int64("failures", failures))
+
+	err := a.storage.RecordLoginLockout(ctx, &domain.LoginLockout{
+		Nickname:    nickname,
+		IP:          ip,
+		Failures:    failures,
+		LockedAt:    now,
+		LockedUntil: now.Add(a.limits.lockout),
+	})
+	if err != nil {
+		a.logger.Error("failed to record login lockout", slog.String("error", err.Error()))
+	}
+}
+
+// loginDelay is a second doubled n times, up to maxDelay.
+func loginDelay(n int64, maxDelay time.Duration) time.Duration {
+	if n >= 30 {
+		return maxDelay
+	}
+
+	return min(time.Second<<n, maxDelay)
+}
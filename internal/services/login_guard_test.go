@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuth_LoginLockout(t *testing.T) {
+	cfg := &config.AuthConfig{
+		AccessTokenTTL:     time.Minute,
+		RefreshTokenTTL:    time.Hour,
+		JWTSigningKey:      "key",
+		Argon2Memory:       1024,
+		Argon2Iterations:   1,
+		Argon2Parallelism:  1,
+		LoginMaxFailures:   10,
+		LoginMaxIPFailures: 100,
+		LoginFailureWindow: 15 * time.Minute,
+		LoginLockout:       15 * time.Minute,
+		LoginDelayAfter:    3,
+		LoginMaxDelay:      30 * time.Second,
+	}
+	device := domain.Device{IP: "192.0.2.1"}
+
+	t.Run("Locked nickname", func(t *testing.T) {
+		attempts := urlMocks.NewLoginAttempts(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, urlMocks.NewUserStorage(t), nil, nil, attempts)
+		assert.NoError(t, err)
+
+		attempts.On("LoginLockedFor", mock.Anything, "nickname:bob").Return(5*time.Minute, nil)
+		attempts.On("LoginLockedFor", mock.Anything, "ip:192.0.2.1").Return(time.Duration(0), nil)
+
+		_, _, err = auth.Login(context.Background(), "Bob", "password", device)
+
+		var locked *domain.LoginLockedError
+		assert.ErrorAs(t, err, &locked)
+		assert.Equal(t, 5*time.Minute, locked.RetryAfter)
+	})
+
+	t.Run("Unknown nickname is counted", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		attempts := urlMocks.NewLoginAttempts(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, attempts)
+		assert.NoError(t, err)
+
+		attempts.On("LoginLockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		storage.On("GetUser", mock.Anything, "eve").Return(nil, domain.ErrUserNotFound)
+		attempts.On("AddLoginFailure", mock.Anything, "nickname:eve", 15*time.Minute).Return(int64(1), nil)
+		attempts.On("AddLoginFailure", mock.Anything, "ip:192.0.2.1", 15*time.Minute).Return(int64(1), nil)
+
+		_, _, err = auth.Login(context.Background(), "eve", "password", device)
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("Growing delay", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		attempts := urlMocks.NewLoginAttempts(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, attempts)
+		assert.NoError(t, err)
+
+		passHash, _ := auth.hasher.Hash("password")
+		attempts.On("LoginLockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		storage.On("GetUser", mock.Anything, "bob").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash}, nil)
+		attempts.On("AddLoginFailure", mock.Anything, "nickname:bob", 15*time.Minute).Return(int64(5), nil)
+		attempts.On("AddLoginFailure", mock.Anything, "ip:192.0.2.1", 15*time.Minute).Return(int64(5), nil)
+		attempts.On("LockLogin", mock.Anything, "nickname:bob", 4*time.Second).Return(nil)
+
+		_, _, err = auth.Login(context.Background(), "bob", "guess", device)
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("Lockout is audited", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		attempts := urlMocks.NewLoginAttempts(t)
+		auth, err := NewAuth(cfg, slog.New(slog.NewTextHandler(os.Stderr, nil)), storage, nil, nil, attempts)
+		assert.NoError(t, err)
+
+		attempts.On("LoginLockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		storage.On("GetUser", mock.Anything, "bob").Return(nil, domain.ErrUserNotFound)
+		attempts.On("AddLoginFailure", mock.Anything, "nickname:bob", 15*time.Minute).Return(int64(10), nil)
+		attempts.On("AddLoginFailure", mock.Anything, "ip:192.0.2.1", 15*time.Minute).Return(int64(10), nil)
+		attempts.On("LockLogin", mock.Anything, "nickname:bob", 15*time.Minute).Return(nil)
+		storage.On("RecordLoginLockout", mock.Anything, mock.MatchedBy(func(l *domain.LoginLockout) bool {
+			return l.Nickname == "bob" && l.IP == "192.0.2.1" && l.Failures == 10
+		})).Return(nil)
+
+		_, _, err = auth.Login(context.Background(), "bob", "guess", device)
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("Success forgets the failures", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		attempts := urlMocks.NewLoginAttempts(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, attempts)
+		assert.NoError(t, err)
+
+		passHash, _ := auth.hasher.Hash("password")
+		attempts.On("LoginLockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		storage.On("GetUser", mock.Anything, "bob").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash}, nil)
+		attempts.On("ResetLoginFailures", mock.Anything, "nickname:bob").Return(nil)
+		storage.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		_, _, err = auth.Login(context.Background(), "bob", "password", device)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Wrong password of a signed in user is counted", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		attempts := urlMocks.NewLoginAttempts(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, attempts)
+		assert.NoError(t, err)
+
+		passHash, _ := auth.hasher.Hash("password")
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", Nickname: "Bob", PasswordHash: passHash}, nil)
+		attempts.On("LoginLockedFor", mock.Anything, "nickname:bob").Return(time.Duration(0), nil)
+		attempts.On("AddLoginFailure", mock.Anything, "nickname:bob", 15*time.Minute).Return(int64(1), nil)
+
+		err = auth.ChangePassword(context.Background(), "1", "5", "guess", "new-password")
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("Locked nickname can not check the password", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		attempts := urlMocks.NewLoginAttempts(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, attempts)
+		assert.NoError(t, err)
+
+		passHash, _ := auth.hasher.Hash("password")
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash}, nil)
+		attempts.On("LoginLockedFor", mock.Anything, "nickname:bob").Return(5*time.Minute, nil)
+
+		err = auth.SetEmail(context.Background(), "1", "password", "bob@example.com")
+
+		var locked *domain.LoginLockedError
+		assert.ErrorAs(t, err, &locked)
+		assert.Equal(t, 5*time.Minute, locked.RetryAfter)
+	})
+}
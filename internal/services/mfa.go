@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/totp"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at once.
+const recoveryCodeCount = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollTOTP starts the enrolment of two-factor authentication. The key is
+// added to an authenticator app and turned on by ConfirmTOTP, until then the
+// login stays the same.
+func (a *Auth) EnrollTOTP(ctx context.Context, userID, password string) (*totp.Key, error) {
+	user, err := a.checkPassword(ctx, userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(a.totpIssuer, user.Nickname)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.EnrollTOTP: %w", err)
+	}
+
+	err = a.storage.SetTOTPSecret(ctx, userID, key.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.EnrollTOTP: %w", err)
+	}
+
+	return key, nil
+}
+
+// ConfirmTOTP turns two-factor authentication on with a code of the enrolled
+// key and returns the recovery codes, they are not shown again.
+func (a *Auth) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	state, err := a.storage.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.ConfirmTOTP: %w", err)
+	}
+	if state.Enabled {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+	if state.Secret == "" {
+		return nil, domain.ErrMFANotEnrolled
+	}
+
+	step, ok := totp.Validate(state.Secret, code, time.Now(), state.LastStep)
+	if !ok {
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.ConfirmTOTP: %w", err)
+	}
+
+	err = a.storage.EnableTOTP(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.ConfirmTOTP: %w", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off, both factors are asked so
+// that a stolen access token or password alone can not do it.
+func (a *Auth) DisableTOTP(ctx context.Context, userID, password, code string) error {
+	_, err := a.checkPassword(ctx, userID, password)
+	if err != nil {
+		return err
+	}
+
+	err = a.checkSecondFactor(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+
+	err = a.storage.DisableTOTP(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service.Auth.DisableTOTP: %w", err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, the old
+// ones stop working.
+func (a *Auth) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	err := a.checkSecondFactor(ctx, userID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.RegenerateRecoveryCodes: %w", err)
+	}
+
+	err = a.storage.ReplaceRecoveryCodes(ctx, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.RegenerateRecoveryCodes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// LoginMFA finishes a login that Login answered with an MFA token, code is a
+// code of the authenticator app or a recovery code.
+func (a *Auth) LoginMFA(ctx context.Context, mfaToken, code string, device domain.Device) (*domain.Tokens, *domain.User, error) {
+	userID, err := a.tokenManager.ParseMFAToken(mfaToken)
+	if err != nil {
+		return nil, nil, domain.ErrInvalidMFAToken
+	}
+
+	user, err := a.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service.Auth.LoginMFA: %w", err)
+	}
+
+	// wrong codes count as failed logins, so they can not be guessed
+	err = a.checkLoginLock(ctx, user.Nickname, device.IP)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = a.checkSecondFactor(ctx, userID, code)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidMFACode) {
+			a.loginFailed(ctx, user.Nickname, device.IP)
+		}
+
+		return nil, nil, err
+	}
+	a.loginSucceeded(ctx, user.Nickname)
+
+	tokens, err := a.CreateSession(ctx, user, device)
+	return tokens, user, err
+}
+
+// checkSecondFactor accepts a TOTP code or spends a recovery code of the user.
+func (a *Auth) checkSecondFactor(ctx context.Context, userID, code string) error {
+	state, err := a.storage.GetTOTP(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service.Auth.checkSecondFactor: %w", err)
+	}
+	if !state.Enabled {
+		return domain.ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == 6 {
+		step, ok := totp.Validate(state.Secret, code, time.Now(), state.LastStep)
+		if !ok {
+			return domain.ErrInvalidMFACode
+		}
+
+		// the step is recorded by a conditional update, so a code that two
+		// requests race with is only accepted once
+		err = a.storage.UseTOTPStep(ctx, userID, step)
+	} else {
+		err = a.storage.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	}
+	if err != nil {
+		return fmt.Errorf("service.Auth.checkSecondFactor: %w", err)
+	}
+
+	return nil
+}
+
+// newRecoveryCodes returns recovery codes like "abcd-efgh" and the hashes
+// they are stored as.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts the codes as typed, with any case and with or
+// without the dash.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuth_TOTP(t *testing.T) {
+	cfg := &config.AuthConfig{
+		AccessTokenTTL:    time.Minute,
+		RefreshTokenTTL:   time.Hour,
+		JWTSigningKey:     "key",
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		TOTPIssuer:        "URL Shortener",
+		MFATokenTTL:       time.Minute,
+	}
+	const secret = "JBSWY3DPEHPK3PXP"
+	code, err := totp.GenerateCode(secret, time.Now())
+	assert.NoError(t, err)
+	step := time.Now().Unix() / 30
+
+	t.Run("Login asks for the second factor", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		passHash, _ := auth.hasher.Hash("password")
+		storage.On("GetUser", mock.Anything, "bob").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash, TOTPEnabled: true}, nil)
+
+		tokens, _, err := auth.Login(context.Background(), "bob", "password", domain.Device{})
+
+		assert.NoError(t, err)
+		assert.Empty(t, tokens.AccessToken)
+		assert.Empty(t, tokens.RefreshToken)
+		userID, err := auth.tokenManager.ParseMFAToken(tokens.MFAToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", userID)
+	})
+
+	t.Run("Second step with a code", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		mfaToken, _ := auth.tokenManager.NewMFAToken("1", time.Minute)
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", Nickname: "bob", TOTPEnabled: true}, nil)
+		storage.On("GetTOTP", mock.Anything, "1").Return(&domain.TOTP{Secret: secret, Enabled: true, LastStep: step - 5}, nil)
+		storage.On("UseTOTPStep", mock.Anything, "1", mock.MatchedBy(func(s int64) bool { return s >= step-1 && s <= step+1 })).Return(nil)
+		storage.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		tokens, user, err := auth.LoginMFA(context.Background(), mfaToken, code, domain.Device{})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.Equal(t, "bob", user.Nickname)
+	})
+
+	t.Run("Second step with a recovery code", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		mfaToken, _ := auth.tokenManager.NewMFAToken("1", time.Minute)
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", Nickname: "bob", TOTPEnabled: true}, nil)
+		storage.On("GetTOTP", mock.Anything, "1").Return(&domain.TOTP{Secret: secret, Enabled: true}, nil)
+		storage.On("UseRecoveryCode", mock.Anything, "1", hashToken("abcdefgh")).Return(nil)
+		storage.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		_, _, err = auth.LoginMFA(context.Background(), mfaToken, "ABCD-EFGH", domain.Device{})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Replayed code", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		mfaToken, _ := auth.tokenManager.NewMFAToken("1", time.Minute)
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", Nickname: "bob", TOTPEnabled: true}, nil)
+		storage.On("GetTOTP", mock.Anything, "1").Return(&domain.TOTP{Secret: secret, Enabled: true, LastStep: step + 1}, nil)
+
+		_, _, err = auth.LoginMFA(context.Background(), mfaToken, code, domain.Device{})
+
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("Access token is no MFA token", func(t *testing.T) {
+		auth, err := NewAuth(cfg, &slog.Logger{}, urlMocks.NewUserStorage(t), nil, nil, nil)
+		assert.NoError(t, err)
+
+		accessToken, _ := auth.tokenManager.NewJWT(accessClaims(&domain.User{ID: "1"}, "5"), time.Minute)
+
+		_, _, err = auth.LoginMFA(context.Background(), accessToken, code, domain.Device{})
+
+		assert.ErrorIs(t, err, domain.ErrInvalidMFAToken)
+	})
+
+	t.Run("Confirm returns the recovery codes", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		storage.On("GetTOTP", mock.Anything, "1").Return(&domain.TOTP{Secret: secret}, nil)
+		var storedHashes []string
+		storage.On("EnableTOTP", mock.Anything, "1", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			storedHashes = args.Get(3).([]string)
+		}).Return(nil)
+
+		codes, err := auth.ConfirmTOTP(context.Background(), "1", code)
+
+		assert.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+		// only the hashes of the codes are stored
+		assert.Equal(t, hashToken(normalizeRecoveryCode(codes[0])), storedHashes[0])
+	})
+
+	t.Run("Confirm without enrolment", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		storage.On("GetTOTP", mock.Anything, "1").Return(&domain.TOTP{}, nil)
+
+		_, err = auth.ConfirmTOTP(context.Background(), "1", code)
+
+		assert.ErrorIs(t, err, domain.ErrMFANotEnrolled)
+	})
+
+	t.Run("Enroll", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		passHash, _ := auth.hasher.Hash("password")
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", Nickname: "bob", PasswordHash: passHash}, nil)
+		storage.On("SetTOTPSecret", mock.Anything, "1", mock.Anything).Return(nil)
+
+		key, err := auth.EnrollTOTP(context.Background(), "1", "password")
+
+		assert.NoError(t, err)
+		assert.Contains(t, key.URL, "secret="+key.Secret)
+		storage.AssertCalled(t, "SetTOTPSecret", mock.Anything, "1", key.Secret)
+	})
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/notify"
+)
+
+// ChangePassword replaces the password of the user after checking the old one.
+// Every other session of the user is ended, sessionID is kept.
+func (a *Auth) ChangePassword(ctx context.Context, userID, sessionID, oldPassword, newPassword string) error {
+	_, err := a.checkPassword(ctx, userID, oldPassword)
+	if err != nil {
+		return err
+	}
+
+	err = a.setPassword(ctx, userID, newPassword)
+	if err != nil {
+		return fmt.Errorf("service.Auth.ChangePassword: %w", err)
+	}
+
+	_, err = a.RevokeOtherSessions(ctx, userID, sessionID)
+	return err
+}
+
+// SetEmail changes the address password reset links are sent to, the password
+// is asked so that a stolen access token can not take over the account.
+func (a *Auth) SetEmail(ctx context.Context, userID, password, email string) error {
+	_, err := a.checkPassword(ctx, userID, password)
+	if err != nil {
+		return err
+	}
+
+	err = a.storage.SetEmail(ctx, userID, normalizeEmail(email))
+	if err != nil {
+		return fmt.Errorf("service.Auth.SetEmail: %w", err)
+	}
+
+	return nil
+}
+
+// passwordResetTimeout bounds sending a reset link, which outlives the request.
+const passwordResetTimeout = 30 * time.Second
+
+// RequestPasswordReset sends a single-use reset link to email. The link is
+// sent in the background and unknown emails are not an error, so that neither
+// the response nor its timing tells which emails are registered.
+func (a *Auth) RequestPasswordReset(ctx context.Context, email string) error {
+	if a.notifier == nil {
+		return fmt.Errorf("service.Auth.RequestPasswordReset: no notifier")
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetTimeout)
+	go func() {
+		defer cancel()
+
+		err := a.sendPasswordReset(ctx, normalizeEmail(email))
+		if err != nil {
+			a.logger.Error("failed to send password reset", slog.String("error", err.Error()))
+		}
+	}()
+
+	return nil
+}
+
+func (a *Auth) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := a.storage.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("service.Auth.sendPasswordReset: %w", err)
+	}
+
+	token, err := a.tokenManager.NewRefreshToken()
+	if err != nil {
+		return fmt.Errorf("service.Auth.sendPasswordReset: %w", err)
+	}
+
+	err = a.storage.CreatePasswordReset(ctx, user.ID, hashToken(token), time.Now().Add(a.resetTTL))
+	if err != nil {
+		return fmt.Errorf("service.Auth.sendPasswordReset: %w", err)
+	}
+
+	err = a.notifier.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Hi %s,\n\nsomeone asked to reset the password of your account. "+
+			"Follow the link to choose a new password, it works once within %d minutes:\n\n%s%s\n\n"+
+			"If it was not you, ignore this message, your password stays the same.\n",
+			user.Nickname, int(a.resetTTL.Minutes()), a.resetURL, token),
+	})
+	if err != nil {
+		return fmt.Errorf("service.Auth.sendPasswordReset: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and ends every session
+// of the user.
+func (a *Auth) ResetPassword(ctx context.Context, token, newPassword string) error {
+	userID, err := a.storage.UsePasswordReset(ctx, hashToken(token))
+	if err != nil {
+		return fmt.Errorf("service.Auth.ResetPassword: %w", err)
+	}
+
+	err = a.setPassword(ctx, userID, newPassword)
+	if err != nil {
+		return fmt.Errorf("service.Auth.ResetPassword: %w", err)
+	}
+
+	_, err = a.RevokeOtherSessions(ctx, userID, "")
+	return err
+}
+
+// checkPassword returns the user if password is theirs. Wrong passwords are
+// failed logins of the user, so a stolen access token does not let the
+// password be guessed past the lockout.
+func (a *Auth) checkPassword(ctx context.Context, userID, password string) (*domain.User, error) {
+	user, err := a.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.checkPassword: %w", err)
+	}
+
+	err = a.checkLoginLock(ctx, user.Nickname, "")
+	if err != nil {
+		return nil, err
+	}
+
+	ok, _, err := a.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("service.Auth.checkPassword: %w", err)
+	}
+	if !ok {
+		a.loginFailed(ctx, user.Nickname, "")
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+func (a *Auth) setPassword(ctx context.Context, userID, password string) error {
+	passHash, err := a.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return a.storage.UpdatePasswordHash(ctx, userID, passHash)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+	"url-shortener/pkg/notify"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuth_ChangePassword(t *testing.T) {
+	cfg := &config.AuthConfig{
+		AccessTokenTTL:    15 * time.Minute,
+		JWTSigningKey:     "key",
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+
+	t.Run("Other sessions are ended", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		denylist := urlMocks.NewSessionDenylist(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, denylist, nil, nil)
+		assert.NoError(t, err)
+
+		passHash, _ := auth.hasher.Hash("old-password")
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", PasswordHash: passHash}, nil)
+		storage.On("UpdatePasswordHash", mock.Anything, "1", mock.MatchedBy(func(h string) bool {
+			ok, _, _ := auth.hasher.Verify("new-password", h)
+			return ok
+		})).Return(nil)
+		storage.On("RevokeOtherSessions", mock.Anything, "1", "5").Return([]string{"6"}, nil)
+		denylist.On("DenySessions", mock.Anything, []string{"6"}, 15*time.Minute).Return(nil)
+
+		err = auth.ChangePassword(context.Background(), "1", "5", "old-password", "new-password")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Wrong old password", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		passHash, _ := auth.hasher.Hash("old-password")
+		storage.On("GetUserByID", mock.Anything, "1").Return(&domain.User{ID: "1", PasswordHash: passHash}, nil)
+
+		err = auth.ChangePassword(context.Background(), "1", "5", "guess", "new-password")
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+}
+
+func TestAuth_PasswordReset(t *testing.T) {
+	cfg := &config.AuthConfig{
+		AccessTokenTTL:    15 * time.Minute,
+		JWTSigningKey:     "key",
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		PasswordResetTTL:  time.Hour,
+		PasswordResetURL:  "https://sho.rt/reset?token=",
+	}
+
+	t.Run("Reset link is sent", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		notifier := urlMocks.NewNotifier(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, notifier, nil)
+		assert.NoError(t, err)
+
+		storage.On("GetUserByEmail", mock.Anything, "bob@example.com").Return(&domain.User{ID: "1", Nickname: "bob", Email: "bob@example.com"}, nil)
+		var storedHash string
+		storage.On("CreatePasswordReset", mock.Anything, "1", mock.Anything, mock.MatchedBy(func(expiresAt time.Time) bool {
+			return time.Until(expiresAt) > 59*time.Minute
+		})).Run(func(args mock.Arguments) {
+			storedHash = args.String(2)
+		}).Return(nil)
+		var sent notify.Message
+		done := make(chan struct{})
+		notifier.On("Send", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(1).(notify.Message)
+			close(done)
+		}).Return(nil)
+
+		err = auth.RequestPasswordReset(context.Background(), " Bob@Example.com")
+
+		assert.NoError(t, err)
+		<-done
+		assert.Equal(t, "bob@example.com", sent.To)
+		_, link, found := strings.Cut(sent.Body, cfg.PasswordResetURL)
+		assert.True(t, found)
+		token, _, _ := strings.Cut(link, "\n")
+		// only the hash of the token is stored
+		assert.Equal(t, hashToken(token), storedHash)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, urlMocks.NewNotifier(t), nil)
+		assert.NoError(t, err)
+
+		done := make(chan struct{})
+		storage.On("GetUserByEmail", mock.Anything, "eve@example.com").Run(func(args mock.Arguments) {
+			close(done)
+		}).Return(nil, domain.ErrUserNotFound)
+
+		err = auth.RequestPasswordReset(context.Background(), "eve@example.com")
+
+		assert.NoError(t, err)
+		<-done
+	})
+
+	t.Run("Failed delivery is not reported", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		notifier := urlMocks.NewNotifier(t)
+		auth, err := NewAuth(cfg, slog.New(slog.NewTextHandler(os.Stderr, nil)), storage, nil, notifier, nil)
+		assert.NoError(t, err)
+
+		storage.On("GetUserByEmail", mock.Anything, "bob@example.com").Return(&domain.User{ID: "1", Nickname: "bob", Email: "bob@example.com"}, nil)
+		storage.On("CreatePasswordReset", mock.Anything, "1", mock.Anything, mock.Anything).Return(nil)
+		done := make(chan struct{})
+		notifier.On("Send", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			close(done)
+		}).Return(errors.New("smtp error"))
+
+		err = auth.RequestPasswordReset(context.Background(), "bob@example.com")
+
+		// a registered email is answered like an unknown one
+		assert.NoError(t, err)
+		<-done
+	})
+
+	t.Run("Reset ends every session", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		denylist := urlMocks.NewSessionDenylist(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, denylist, nil, nil)
+		assert.NoError(t, err)
+
+		storage.On("UsePasswordReset", mock.Anything, hashToken("token")).Return("1", nil)
+		storage.On("UpdatePasswordHash", mock.Anything, "1", mock.Anything).Return(nil)
+		storage.On("RevokeOtherSessions", mock.Anything, "1", "").Return([]string{"5", "6"}, nil)
+		denylist.On("DenySessions", mock.Anything, []string{"5", "6"}, 15*time.Minute).Return(nil)
+
+		err = auth.ResetPassword(context.Background(), "token", "new-password")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Used token", func(t *testing.T) {
+		storage := urlMocks.NewUserStorage(t)
+		auth, err := NewAuth(cfg, &slog.Logger{}, storage, nil, nil, nil)
+		assert.NoError(t, err)
+
+		storage.On("UsePasswordReset", mock.Anything, hashToken("token")).Return("", domain.ErrInvalidResetToken)
+
+		err = auth.ResetPassword(context.Background(), "token", "new-password")
+
+		assert.ErrorIs(t, err, domain.ErrInvalidResetToken)
+	})
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+// UsageStorage keeps the plans of users and what they used of them per month.
+type UsageStorage interface {
+	GetUsage(ctx context.Context, userID string, month time.Time) (*domain.Usage, error)
+	// AddLinkUsage counts n new links of the user in month, it gives
+	// domain.ErrQuotaExceeded when they do not fit into limit. Zero limit is
+	// unlimited.
+	AddLinkUsage(ctx context.Context, userID string, month time.Time, n, limit int64) error
+	RemoveLinkUsage(ctx context.Context, userID string, month time.Time, n int64) error
+	// TrackClicks counts the clicks of every link in month against the plan
+	// of its owner and returns how many of them may be stored. Anonymous
+	// links have no quota.
+	TrackClicks(ctx context.Context, month time.Time, clicks map[string]int64) (map[string]int64, error)
+}
+
+// usageMonth returns the start of the month of t, quotas reset on the first
+// of a month in UTC.
+func usageMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Usage returns what the user used of their plan this month.
+func (u *URLShortener) Usage(ctx context.Context, userID string) (*domain.Usage, error) {
+	if u.usage == nil {
+		return nil, fmt.Errorf("service.URLShortener.Usage: quotas are not enabled")
+	}
+
+	month := usageMonth(time.Now())
+	usage, err := u.usage.GetUsage(ctx, userID, month)
+	if err != nil {
+		return nil, fmt.Errorf("service.URLShortener.Usage: %w", err)
+	}
+	usage.Month = month
+	usage.ResetsAt = month.AddDate(0, 1, 0)
+
+	return usage, nil
+}
+
+// ownerUsage returns the plan and usage of the owner of new links, nil for
+// anonymous links and without quotas.
+func (u *URLShortener) ownerUsage(ctx context.Context, ownerID string, month time.Time) (*domain.Usage, error) {
+	if u.usage == nil || ownerID == "" {
+		return nil, nil
+	}
+
+	usage, err := u.usage.GetUsage(ctx, ownerID, month)
+	if err != nil {
+		return nil, fmt.Errorf("service.URLShortener.ownerUsage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// countLinks counts n new links of ownerID in month against the plan of
+// usage, it gives a *domain.QuotaExceededError when they do not fit into the
+// links of the month.
+func (u *URLShortener) countLinks(ctx context.Context, ownerID string, usage *domain.Usage, month time.Time, n int64) error {
+	if usage == nil || n == 0 {
+		return nil
+	}
+
+	err := u.usage.AddLinkUsage(ctx, ownerID, month, n, usage.Plan.MonthlyLinks)
+	if err != nil {
+		if errors.Is(err, domain.ErrQuotaExceeded) {
+			return &domain.QuotaExceededError{Quota: domain.QuotaLinks, Limit: usage.Plan.MonthlyLinks, ResetsAt: month.AddDate(0, 1, 0)}
+		}
+
+		return fmt.Errorf("service.URLShortener.countLinks: %w", err)
+	}
+
+	return nil
+}
+
+// uncountLinks gives back n links counted by countLinks that were not created.
+func (u *URLShortener) uncountLinks(ctx context.Context, ownerID string, usage *domain.Usage, month time.Time, n int64) {
+	if usage == nil || n == 0 {
+		return
+	}
+
+	err := u.usage.RemoveLinkUsage(ctx, ownerID, month, n)
+	if err != nil {
+		u.logger.Error("failed to give back link usage", slog.String("user_id", ownerID), slog.String("error", err.Error()))
+	}
+}
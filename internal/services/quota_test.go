@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestURLShortener_Quotas(t *testing.T) {
+	free := domain.Plan{Name: "free", MonthlyLinks: 2, MonthlyClicks: 100}
+	month := usageMonth(time.Now())
+
+	t.Run("Count new link", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		usage := urlMocks.NewUsageStorage(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, usage, nil)
+
+		destURL := "https://example.com"
+		usage.On("GetUsage", mock.Anything, "42", month).Return(&domain.Usage{Plan: free}, nil)
+		db.On("GetByLongUrl", mock.Anything, "42", destURL).Return(nil, domain.ErrOriginalURLNotFound)
+		usage.On("AddLinkUsage", mock.Anything, "42", month, int64(1), int64(2)).Return(nil)
+		db.On("InsertUrl", mock.Anything, generatedURL(destURL, "42")).Return(nil)
+		db.On("GetCountShortUrls", mock.Anything).Return(1, nil)
+
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: destURL})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Links quota exhausted", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		usage := urlMocks.NewUsageStorage(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, usage, nil)
+
+		destURL := "https://example.com"
+		usage.On("GetUsage", mock.Anything, "42", month).Return(&domain.Usage{Plan: free, Links: 2}, nil)
+		db.On("GetByLongUrl", mock.Anything, "42", destURL).Return(nil, domain.ErrOriginalURLNotFound)
+		usage.On("AddLinkUsage", mock.Anything, "42", month, int64(1), int64(2)).Return(domain.ErrQuotaExceeded)
+
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: destURL})
+
+		var quotaErr *domain.QuotaExceededError
+		assert.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, domain.QuotaLinks, quotaErr.Quota)
+		assert.Equal(t, int64(2), quotaErr.Limit)
+		assert.Equal(t, month.AddDate(0, 1, 0), quotaErr.ResetsAt)
+		db.AssertNotCalled(t, "InsertUrl", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Existing link is not counted", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		usage := urlMocks.NewUsageStorage(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, usage, nil)
+
+		destURL := "https://example.com"
+		usage.On("GetUsage", mock.Anything, "42", month).Return(&domain.Usage{Plan: free, Links: 2}, nil)
+		db.On("GetByLongUrl", mock.Anything, "42", destURL).Return(&domain.URL{ShortURL: "abc", LongURL: destURL, OwnerID: "42"}, nil)
+
+		link, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: destURL})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", link.ShortURL)
+	})
+
+	t.Run("Custom alias not in plan", func(t *testing.T) {
+		usage := urlMocks.NewUsageStorage(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), urlMocks.NewDatabase(t), usage, nil)
+
+		usage.On("GetUsage", mock.Anything, "42", month).Return(&domain.Usage{Plan: free}, nil)
+
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: "https://example.com", Alias: "spring-sale"})
+
+		assert.ErrorIs(t, err, domain.ErrNotInPlan)
+	})
+
+	t.Run("Give back link on a taken alias", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		usage := urlMocks.NewUsageStorage(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), db, usage, nil)
+
+		pro := domain.Plan{Name: "pro", CustomAliases: true}
+		usage.On("GetUsage", mock.Anything, "42", month).Return(&domain.Usage{Plan: pro}, nil)
+		usage.On("AddLinkUsage", mock.Anything, "42", month, int64(1), int64(0)).Return(nil)
+		db.On("InsertUrl", mock.Anything, mock.Anything).Return(domain.ErrShortURLAlreadyExist)
+		db.On("GetShortUrl", mock.Anything, "spring-sale").Return(&domain.URL{ShortURL: "spring-sale", LongURL: "https://other.com", OwnerID: "7"}, nil)
+		usage.On("RemoveLinkUsage", mock.Anything, "42", month, int64(1)).Return(nil)
+
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: "https://example.com", Alias: "spring-sale"})
+
+		assert.ErrorIs(t, err, domain.ErrAliasTaken)
+		usage.AssertExpectations(t)
+	})
+
+	t.Run("Anonymous links have no quota", func(t *testing.T) {
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, &slog.Logger{}, urlMocks.NewCache(t), db, urlMocks.NewUsageStorage(t), nil)
+
+		destURL := "https://example.com"
+		db.On("GetByLongUrl", mock.Anything, "", destURL).Return(nil, domain.ErrOriginalURLNotFound)
+		db.On("InsertUrl", mock.Anything, generatedURL(destURL, "")).Return(nil)
+		db.On("GetCountShortUrls", mock.Anything).Return(1, nil)
+
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: destURL})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Usage of the month", func(t *testing.T) {
+		usage := urlMocks.NewUsageStorage(t)
+		shortener := New(&config.ShortenerConfig{}, &slog.Logger{}, urlMocks.NewCache(t), urlMocks.NewDatabase(t), usage, nil)
+
+		usage.On("GetUsage", mock.Anything, "42", month).Return(&domain.Usage{Plan: free, Links: 1, Clicks: 30}, nil)
+
+		got, err := shortener.Usage(context.Background(), "42")
+
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.Usage{Plan: free, Month: month, ResetsAt: month.AddDate(0, 1, 0), Links: 1, Clicks: 30}, got)
+	})
+}
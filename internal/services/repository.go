@@ -2,17 +2,88 @@ package services
 
 import (
 	"context"
+	"time"
 	"url-shortener/internal/domain"
+	"url-shortener/pkg/geoip"
+	"url-shortener/pkg/useragent"
 )
 
 type Database interface {
 	InsertUrl(ctx context.Context, url domain.URL) error
-	GetShortUrl(ctx context.Context, url string) (*domain.URL, error) 
-	GetByLongUrl(ctx context.Context, url string) (*domain.URL, error)
+	GetShortUrl(ctx context.Context, url string) (*domain.URL, error)
+	// InsertUrls saves the links in one transaction, the links whose short url
+	// is taken are skipped. It returns the short urls of the saved links.
+	InsertUrls(ctx context.Context, urls []domain.URL) ([]string, error)
+	// GetByLongUrl finds a link of the owner to url that has no expiration rules.
+	GetByLongUrl(ctx context.Context, ownerID string, url string) (*domain.URL, error)
+	// GetByLongUrls is GetByLongUrl for many urls, the links are keyed by
+	// destination and the urls without a link are left out.
+	GetByLongUrls(ctx context.Context, ownerID string, urls []string) (map[string]domain.URL, error)
 	GetCountShortUrls(ctx context.Context) (int, error)
+	// CountClicks counts the redirects of every link.
+	CountClicks(ctx context.Context) (int64, error)
 	DeleteShortUrl(ctx context.Context, shortURL string) error
+	// IncrementClicks counts a redirect, it returns domain.ErrLinkExpired when
+	// the link has no clicks left.
+	IncrementClicks(ctx context.Context, shortURL string) error
+	ListLinks(ctx context.Context, filter domain.LinkFilter) ([]domain.URL, error)
+	// UpdateLongUrl changes the destination of the link and keeps the previous one in its history.
+	UpdateLongUrl(ctx context.Context, shortURL string, longURL string, changedBy string) (*domain.URL, error)
+	GetLinkHistory(ctx context.Context, shortURL string) ([]domain.LinkChange, error)
+	// ArchiveExpiredUrls removes the links expired at now and returns how many were removed.
+	ArchiveExpiredUrls(ctx context.Context, now time.Time) (int64, error)
 }
 
 type EncoderService interface {
 	Base62Encode(number uint64) string
 }
+
+type ClickStorage interface {
+	InsertClicks(ctx context.Context, events []domain.ClickEvent) error
+	// AddClicks adds clicks to the click counters of the links that have no
+	// max_clicks, the limited links are counted on redirect.
+	AddClicks(ctx context.Context, clicks map[string]int64) error
+	// ClickStats counts the clicks of the link in the query range per bucket,
+	// buckets without clicks are omitted.
+	ClickStats(ctx context.Context, shortURL string, query domain.StatsQuery) ([]domain.StatsPoint, error)
+	// ClickBreakdown counts the clicks of the link in the query range per
+	// value of query.GroupBy.
+	ClickBreakdown(ctx context.Context, shortURL string, query domain.StatsQuery) ([]domain.BreakdownItem, error)
+	// ExportClicks passes the clicks of the link in [from, to) to fn one by
+	// one, oldest first. An error of fn stops the export and is returned.
+	ExportClicks(ctx context.Context, shortURL string, from, to time.Time, fn func(domain.ClickEvent) error) error
+}
+
+// ClickBroker fans clicks out to every replica of the service.
+type ClickBroker interface {
+	PublishClick(ctx context.Context, event domain.ClickEvent) error
+	// SubscribeClicks and UnsubscribeClicks choose the links whose clicks
+	// ReceiveClicks gets.
+	SubscribeClicks(ctx context.Context, shortURL string) error
+	UnsubscribeClicks(ctx context.Context, shortURL string) error
+	// ReceiveClicks passes the clicks of the subscribed links to fn until ctx is done.
+	ReceiveClicks(ctx context.Context, fn func(domain.ClickEvent)) error
+	// ClickSubscribers counts the replicas subscribed to the clicks of each
+	// link, the links nobody watches are left out.
+	ClickSubscribers(ctx context.Context) (map[string]int64, error)
+}
+
+type AgentClassifier interface {
+	Classify(userAgent string) useragent.Agent
+}
+
+type GeoLocator interface {
+	Locate(ip string) geoip.Location
+}
+
+// VisitorCounter keeps approximate per-day sets of the visitors of a link.
+// Days are UTC dates of the events.
+type VisitorCounter interface {
+	// AddVisitors adds VisitorID of every event to the set of its link and day,
+	// a set is kept for ttl after the last addition.
+	AddVisitors(ctx context.Context, events []domain.ClickEvent, ttl time.Duration) error
+	// DailyVisitors counts the visitors of every day.
+	DailyVisitors(ctx context.Context, shortURL string, days []time.Time) ([]int64, error)
+	// UniqueVisitors counts the visitors of all the days together.
+	UniqueVisitors(ctx context.Context, shortURL string, days []time.Time) (int64, error)
+}
@@ -8,14 +8,16 @@ import (
 )
 
 type Render struct {
-	homeTemplate *template.Template
-	logger       *slog.Logger
+	homeTemplate    *template.Template
+	expiredTemplate *template.Template
+	logger          *slog.Logger
 }
 
 func New(templatePath string, logger *slog.Logger) *Render {
 	return &Render{
-		homeTemplate: template.Must(template.ParseFiles(fmt.Sprintf("%s/%s", templatePath, "home.html"))),
-		logger:       logger,
+		homeTemplate:    template.Must(template.ParseFiles(fmt.Sprintf("%s/%s", templatePath, "home.html"))),
+		expiredTemplate: template.Must(template.ParseFiles(fmt.Sprintf("%s/%s", templatePath, "expired.html"))),
+		logger:          logger,
 	}
 }
 
@@ -25,3 +27,14 @@ func (r *Render) Home(w http.ResponseWriter) {
 		r.logger.Error("can not execute home page", slog.String("error", err.Error()))
 	}
 }
+
+// Expired renders the "link expired" page with the 410 Gone status.
+func (r *Render) Expired(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusGone)
+
+	err := r.expiredTemplate.Execute(w, nil)
+	if err != nil {
+		r.logger.Error("can not execute expired page", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Sweeper periodically moves expired links out of the links storage.
+type Sweeper struct {
+	logger   *slog.Logger
+	db       Database
+	interval time.Duration
+}
+
+func NewSweeper(logger *slog.Logger, db Database, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		logger:   logger,
+		db:       db,
+		interval: interval,
+	}
+}
+
+func (s *Sweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.Sweep(ctx)
+		}
+	}
+}
+
+func (s *Sweeper) Sweep(ctx context.Context) {
+	count, err := s.db.ArchiveExpiredUrls(ctx, time.Now())
+	if err != nil {
+		s.logger.Error("failed to archive expired links", slog.String("error", err.Error()))
+		return
+	}
+
+	if count > 0 {
+		s.logger.Info("archived expired links", slog.Int64("count", count))
+	}
+}
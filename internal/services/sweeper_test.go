@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+	urlMocks "url-shortener/internal/mocks"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSweeper_Sweep(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	db := urlMocks.NewDatabase(t)
+	sweeper := NewSweeper(logger, db, time.Minute)
+
+	db.On("ArchiveExpiredUrls", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(2), nil)
+
+	sweeper.Sweep(context.Background())
+
+	db.AssertExpectations(t)
+}
@@ -2,97 +2,462 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
+	"url-shortener/internal/config"
 	"url-shortener/internal/domain"
 	"url-shortener/internal/services/encoder/base62"
 	"url-shortener/internal/services/uniqueIdGenerator/go-snowflake-master"
 	"url-shortener/pkg/cache"
 )
 
+const (
+	maxLinksPageSize    = 100
+	maxGenerateAttempts = 3
+	minAliasLength      = 3
+	maxAliasLength      = 32
+)
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
 type URLShortener struct {
-	logger *slog.Logger
-	cache  cache.Cache
-	db     Database
+	logger          *slog.Logger
+	cache           cache.Cache
+	db              Database
+	usage           UsageStorage
+	agents          AgentClassifier
+	allowAnonymous  bool
+	reservedAliases map[string]struct{}
 }
 
-func New(logger *slog.Logger, cache cache.Cache, db Database) *URLShortener {
+// New creates the shortener, without usage the links are not limited by plans.
+// Without agents every redirect uses up a click of a link limited by max_clicks.
+func New(cfg *config.ShortenerConfig, logger *slog.Logger, cache cache.Cache, db Database, usage UsageStorage, agents AgentClassifier) *URLShortener {
+	reserved := make(map[string]struct{}, len(cfg.ReservedAliases))
+	for _, alias := range cfg.ReservedAliases {
+		reserved[strings.ToLower(strings.TrimSpace(alias))] = struct{}{}
+	}
+
 	return &URLShortener{
-		logger: logger,
-		cache:  cache,
-		db:     db,
+		logger:          logger,
+		cache:           cache,
+		db:              db,
+		usage:           usage,
+		agents:          agents,
+		allowAnonymous:  cfg.AllowAnonymous,
+		reservedAliases: reserved,
 	}
 }
 
-func (u *URLShortener) Create(ctx context.Context, destUrl string) (*domain.URL, int,  error) {
+// Create shortens params.LongURL on behalf of params.OwnerID. An empty owner
+// creates an anonymous link, which is only allowed when anonymous links are
+// enabled. A new link of a user counts against the links quota of their plan.
+func (u *URLShortener) Create(ctx context.Context, params domain.LinkParams) (*domain.URL, int, error) {
+	if params.OwnerID == "" && !u.allowAnonymous {
+		return nil, 0, domain.ErrAnonymousLinksDisabled
+	}
 
-	// check if link already exists on database
-	existUrl, err := u.db.GetByLongUrl(ctx, destUrl)
-	if err == nil {
-		return existUrl, 0, nil
+	err := validateExpiration(params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	month := usageMonth(time.Now())
+	usage, err := u.ownerUsage(ctx, params.OwnerID, month)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if params.Alias != "" {
+		err = checkAliasAllowed(usage)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return u.createWithAlias(ctx, params, usage, month)
+	}
+
+	// check if the owner already shortened this link, links with expiration
+	// rules are never shared
+	if !params.ExpiresAt.IsZero() || params.MaxClicks > 0 {
+		err = domain.ErrOriginalURLNotFound
+	} else {
+		var existUrl *domain.URL
+		existUrl, err = u.db.GetByLongUrl(ctx, params.OwnerID, params.LongURL)
+		if err == nil {
+			return existUrl, 0, nil
+		}
 	}
 
 	if !errors.Is(err, domain.ErrOriginalURLNotFound) {
 		return nil, 0, err
 	}
 
-	id := snowflake.ID()
+	err = u.countLinks(ctx, params.OwnerID, usage, month, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	url, err := u.insertGenerated(ctx, params)
+	if err != nil {
+		u.uncountLinks(ctx, params.OwnerID, usage, month, 1)
+		return nil, 0, err
+	}
+
+	count, err := u.db.GetCountShortUrls(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	encodedUrl := base62.Base62Encode(id)
+	return &url, count, nil
+}
+
+// insertGenerated saves a link with a generated code.
+func (u *URLShortener) insertGenerated(ctx context.Context, params domain.LinkParams) (domain.URL, error) {
+	// a generated code may be taken by a custom alias, so just draw another id
+	for attempt := 0; ; attempt++ {
+		id := snowflake.ID()
+		url := newURL(id, base62.Base62Encode(id), params)
+
+		// It's a new link, so let's save it
+		err := u.db.InsertUrl(ctx, url)
+		if err == nil {
+			return url, nil
+		}
+		if !errors.Is(err, domain.ErrShortURLAlreadyExist) || attempt == maxGenerateAttempts-1 {
+			return domain.URL{}, err
+		}
+	}
+}
+
+func (u *URLShortener) createWithAlias(ctx context.Context, params domain.LinkParams, usage *domain.Usage, month time.Time) (*domain.URL, int, error) {
+	err := u.validateAlias(params.Alias)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	url := domain.URL{
-		Id:       strconv.Itoa(int(id)),
-		ShortURL: encodedUrl,
-		LongURL:  destUrl,
+	err = u.countLinks(ctx, params.OwnerID, usage, month, 1)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// It's a new link, so let's save it
+	url := newURL(snowflake.ID(), params.Alias, params)
 	err = u.db.InsertUrl(ctx, url)
 	if err != nil {
-		return nil, 0,  err
+		u.uncountLinks(ctx, params.OwnerID, usage, month, 1)
+	}
+	if errors.Is(err, domain.ErrShortURLAlreadyExist) {
+		// repeating the same request is not a conflict
+		existUrl, getErr := u.db.GetShortUrl(ctx, params.Alias)
+		if getErr == nil && existUrl.OwnerID == params.OwnerID && existUrl.LongURL == params.LongURL {
+			return existUrl, 0, nil
+		}
+
+		return nil, 0, domain.ErrAliasTaken
+	}
+	if err != nil {
+		return nil, 0, err
 	}
 
 	count, err := u.db.GetCountShortUrls(ctx)
 	if err != nil {
-		return nil, 0,  err
+		return nil, 0, err
 	}
 
 	return &url, count, nil
 }
 
-func (u *URLShortener) GetOriginalURL(ctx context.Context, shortUrl string) (string, error) {
+func (u *URLShortener) validateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d characters", domain.ErrInvalidAlias, minAliasLength, maxAliasLength)
+	}
+
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: only latin letters, digits, '-' and '_' are allowed, starting with a letter or a digit", domain.ErrInvalidAlias)
+	}
+
+	if _, ok := u.reservedAliases[strings.ToLower(alias)]; ok {
+		return fmt.Errorf("%w: %q is reserved", domain.ErrInvalidAlias, alias)
+	}
+
+	return nil
+}
+
+// checkAliasAllowed tells whether the plan of usage includes custom aliases,
+// links without a plan may have them.
+func checkAliasAllowed(usage *domain.Usage) error {
+	if usage != nil && !usage.Plan.CustomAliases {
+		return fmt.Errorf("custom aliases are %w %q", domain.ErrNotInPlan, usage.Plan.Name)
+	}
+
+	return nil
+}
+
+func validateExpiration(params domain.LinkParams) error {
+	if params.MaxClicks < 0 {
+		return fmt.Errorf("%w: max_clicks must not be negative", domain.ErrInvalidExpiration)
+	}
+
+	if !params.ExpiresAt.IsZero() && !params.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: expires_at must be in the future", domain.ErrInvalidExpiration)
+	}
+
+	return nil
+}
+
+func newURL(id uint64, shortURL string, params domain.LinkParams) domain.URL {
+	return domain.URL{
+		Id:        strconv.Itoa(int(id)),
+		ShortURL:  shortURL,
+		LongURL:   params.LongURL,
+		OwnerID:   params.OwnerID,
+		ExpiresAt: params.ExpiresAt,
+		MaxClicks: params.MaxClicks,
+	}
+}
+
+// cachedLink is the cache entry of a link. The expiration rules travel with
+// the destination, so a cache hit enforces them as well.
+type cachedLink struct {
+	LongURL   string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxClicks int64     `json:"max_clicks,omitempty"`
+}
+
+// GetOriginalURL returns the destination of a link for a redirect of a client
+// with userAgent. Known bots, such as link previews, do not use up the clicks
+// of a link limited by max_clicks.
+func (u *URLShortener) GetOriginalURL(ctx context.Context, shortUrl string, userAgent string) (string, error) {
 
 	//use trategy cashe aside
 	//first check in redis
-	redisUrl, err := u.cache.Get(ctx, shortUrl)
-	if err == nil {
-		return fmt.Sprintf("%v", redisUrl), nil
+	link, err := u.getCachedLink(ctx, shortUrl)
+	if err != nil {
+		//if cache miss, query the database
+		url, err := u.db.GetShortUrl(ctx, shortUrl)
+		if err != nil {
+			return "", err
+		}
+
+		if url.Expired(time.Now()) {
+			return "", domain.ErrLinkExpired
+		}
+
+		link = &cachedLink{LongURL: url.LongURL, ExpiresAt: url.ExpiresAt, MaxClicks: url.MaxClicks}
+		u.setCachedLink(ctx, shortUrl, link)
 	}
-	//if cache miss, query the database
-	url, err := u.db.GetShortUrl(ctx, shortUrl)
+
+	if !link.ExpiresAt.IsZero() && !time.Now().Before(link.ExpiresAt) {
+		return "", domain.ErrLinkExpired
+	}
+
+	// the other links are counted by the click recorder, off the redirect path
+	if link.MaxClicks > 0 {
+		if u.agents != nil && u.agents.Classify(userAgent).Bot {
+			err = u.checkClicksLeft(ctx, shortUrl)
+		} else {
+			err = u.countClick(ctx, shortUrl)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return link.LongURL, nil
+}
+
+func (u *URLShortener) getCachedLink(ctx context.Context, shortUrl string) (*cachedLink, error) {
+	value, err := u.cache.Get(ctx, shortUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var link cachedLink
+	err = json.Unmarshal([]byte(fmt.Sprintf("%v", value)), &link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func (u *URLShortener) setCachedLink(ctx context.Context, shortUrl string, link *cachedLink) {
+	buf, err := json.Marshal(link)
 	if err != nil {
-		return "", err
+		u.logger.Error("can not marshal cached link", slog.String("message", err.Error()))
+		return
+	}
+
+	// the entry must not outlive the link
+	ttl := time.Hour
+	if !link.ExpiresAt.IsZero() {
+		ttl = min(ttl, time.Until(link.ExpiresAt))
 	}
 
 	//store in the redis
-	err = u.cache.Set(ctx, shortUrl, url, time.Hour)
+	err = u.cache.Set(ctx, shortUrl, string(buf), ttl)
 	if err != nil {
 		u.logger.Error("redis insertion error", slog.String("message", err.Error()))
 	}
+}
 
-	return url.LongURL, nil
+// countClick bumps the click counter of a link limited by max_clicks, the
+// counter decides whether the redirect is allowed. Other failures must not
+// break the redirect.
+func (u *URLShortener) countClick(ctx context.Context, shortUrl string) error {
+	err := u.db.IncrementClicks(ctx, shortUrl)
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, domain.ErrLinkExpired) {
+		return err
+	}
+
+	u.logger.Error("failed to count click", slog.String("short_url", shortUrl), slog.String("error", err.Error()))
+	return nil
 }
 
+// checkClicksLeft tells whether a link limited by max_clicks has clicks left
+// without using one up. Like counting, a failure must not break the redirect.
+func (u *URLShortener) checkClicksLeft(ctx context.Context, shortUrl string) error {
+	url, err := u.db.GetShortUrl(ctx, shortUrl)
+	if errors.Is(err, domain.ErrOriginalURLNotFound) {
+		return err
+	}
+	if err != nil {
+		u.logger.Error("failed to check clicks left", slog.String("short_url", shortUrl), slog.String("error", err.Error()))
+		return nil
+	}
 
-func (u *URLShortener) DeleteShortUrl(ctx context.Context, shortUrl string) (error) {
+	if url.Expired(time.Now()) {
+		return domain.ErrLinkExpired
+	}
 
-	 err := u.db.DeleteShortUrl(ctx, shortUrl)
+	return nil
+}
+
+// ListLinks returns a page of the links matching filter. cursor is the
+// NextCursor of the previous page, empty for the first page.
+func (u *URLShortener) ListLinks(ctx context.Context, filter domain.LinkFilter, cursor string) (*domain.LinkPage, error) {
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if after.SortBy != filter.SortBy || after.Ascending != filter.Ascending {
+			return nil, domain.ErrInvalidCursor
+		}
+		filter.After = after
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > maxLinksPageSize {
+		limit = maxLinksPageSize
+	}
+	filter.Limit = limit
+	// one extra row tells whether there is a next page
+	filter.Limit++
+
+	links, err := u.db.ListLinks(ctx, filter)
 	if err != nil {
-		return  err
+		return nil, err
 	}
 
+	page := &domain.LinkPage{Links: links}
+	if len(links) > limit {
+		page.Links = links[:limit]
+		last := page.Links[limit-1]
+		page.NextCursor, err = encodeCursor(domain.LinkCursor{
+			SortBy:    filter.SortBy,
+			Ascending: filter.Ascending,
+			CreatedAt: last.CreatedAt,
+			Clicks:    last.Clicks,
+			ID:        last.Id,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// DeleteShortUrl removes the link if it belongs to userID.
+func (u *URLShortener) DeleteShortUrl(ctx context.Context, userID string, shortUrl string) error {
+	_, err := u.ownedLink(ctx, userID, shortUrl)
+	if err != nil {
+		return err
+	}
+
+	return u.deleteLink(ctx, shortUrl)
+}
+
+func (u *URLShortener) deleteLink(ctx context.Context, shortUrl string) error {
+	err := u.db.DeleteShortUrl(ctx, shortUrl)
+	if err != nil {
+		return err
+	}
+
+	u.invalidate(ctx, shortUrl)
 	return nil
 }
+
+// UpdateLongURL points the link of userID to a new destination.
+func (u *URLShortener) UpdateLongURL(ctx context.Context, userID string, shortUrl string, longURL string) (*domain.URL, error) {
+	_, err := u.ownedLink(ctx, userID, shortUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.updateLongURL(ctx, shortUrl, longURL, userID)
+}
+
+// updateLongURL points the link to a new destination on behalf of changedBy.
+func (u *URLShortener) updateLongURL(ctx context.Context, shortUrl string, longURL string, changedBy string) (*domain.URL, error) {
+	url, err := u.db.UpdateLongUrl(ctx, shortUrl, longURL, changedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// the next redirect must go to the new destination
+	u.invalidate(ctx, shortUrl)
+	return url, nil
+}
+
+// LinkHistory returns the previous destinations of the link of userID, oldest first.
+func (u *URLShortener) LinkHistory(ctx context.Context, userID string, shortUrl string) ([]domain.LinkChange, error) {
+	_, err := u.ownedLink(ctx, userID, shortUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.db.GetLinkHistory(ctx, shortUrl)
+}
+
+func (u *URLShortener) ownedLink(ctx context.Context, userID string, shortUrl string) (*domain.URL, error) {
+	url, err := u.db.GetShortUrl(ctx, shortUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if url.OwnerID == "" || url.OwnerID != userID {
+		return nil, domain.ErrNotLinkOwner
+	}
+
+	return url, nil
+}
+
+func (u *URLShortener) invalidate(ctx context.Context, shortUrl string) {
+	err := u.cache.Delete(ctx, shortUrl)
+	if err != nil {
+		u.logger.Error("redis deletion error", slog.String("short_url", shortUrl), slog.String("message", err.Error()))
+	}
+}
@@ -2,16 +2,19 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
+	"url-shortener/internal/config"
 	"url-shortener/internal/domain"
 	urlMocks "url-shortener/internal/mocks"
 	"url-shortener/internal/services/encoder/base62"
-	"url-shortener/internal/services/uniqueIdGenerator/go-snowflake-master"
+	"url-shortener/pkg/useragent"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -22,35 +25,57 @@ func TestURLShortener_Create(t *testing.T) {
 		logger := &slog.Logger{}
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		destURL := "https://example.com"
-		id := snowflake.ID() + 1
-
-		encodedURL := base62.Base62Encode(id)
-		expectedURL := &domain.URL{
-			Id:       strconv.Itoa(int(id)),
-			ShortURL: encodedURL,
-			LongURL:  destURL,
-		}
-
-		db.On("GetByLongUrl", mock.Anything, destURL).Return(nil, domain.ErrOriginalURLNotFound)
-		db.On("InsertUrl", mock.Anything, *expectedURL).Return(nil)
+		db.On("GetByLongUrl", mock.Anything, "", destURL).Return(nil, domain.ErrOriginalURLNotFound)
+		db.On("InsertUrl", mock.Anything, generatedURL(destURL, "")).Return(nil)
 		db.On("GetCountShortUrls", mock.Anything).Return(10, nil)
 
-		actualURL, count, err := shortener.Create(context.Background(), destURL)
+		actualURL, count, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: destURL})
 
 		assert.NoError(t, err)
-		assert.Equal(t, expectedURL, actualURL)
+		assert.True(t, isGeneratedURL(*actualURL, destURL, ""))
 		assert.Equal(t, 10, count)
 		db.AssertExpectations(t)
 	})
 
+	t.Run("Create URL for owner", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		destURL := "https://example.com"
+		db.On("GetByLongUrl", mock.Anything, "42", destURL).Return(nil, domain.ErrOriginalURLNotFound)
+		db.On("InsertUrl", mock.Anything, generatedURL(destURL, "42")).Return(nil)
+		db.On("GetCountShortUrls", mock.Anything).Return(1, nil)
+
+		actualURL, count, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: destURL})
+
+		assert.NoError(t, err)
+		assert.True(t, isGeneratedURL(*actualURL, destURL, "42"))
+		assert.Equal(t, 1, count)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Anonymous links disabled", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: false}, logger, cache, db, nil, nil)
+
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: "https://example.com"})
+
+		assert.ErrorIs(t, err, domain.ErrAnonymousLinksDisabled)
+		db.AssertExpectations(t)
+	})
+
 	t.Run("Existing URL", func(t *testing.T) {
 		logger := &slog.Logger{}
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		destURL := "https://example.com"
 		existingURL := &domain.URL{
@@ -59,9 +84,9 @@ func TestURLShortener_Create(t *testing.T) {
 			LongURL:  destURL,
 		}
 
-		db.On("GetByLongUrl", mock.Anything, destURL).Return(existingURL, nil)
+		db.On("GetByLongUrl", mock.Anything, "", destURL).Return(existingURL, nil)
 
-		actualURL, count, err := shortener.Create(context.Background(), destURL)
+		actualURL, count, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: destURL})
 
 		assert.NoError(t, err)
 		assert.Equal(t, existingURL, actualURL)
@@ -73,13 +98,13 @@ func TestURLShortener_Create(t *testing.T) {
 		logger := &slog.Logger{}
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		destURL := "https://example.com"
 
-		db.On("GetByLongUrl", mock.Anything, destURL).Return(nil, errors.New("database error"))
+		db.On("GetByLongUrl", mock.Anything, "", destURL).Return(nil, errors.New("database error"))
 
-		_, _, err := shortener.Create(context.Background(), destURL)
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: destURL})
 
 		assert.Error(t, err)
 		db.AssertExpectations(t)
@@ -89,22 +114,13 @@ func TestURLShortener_Create(t *testing.T) {
 		logger := &slog.Logger{}
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		destURL := "https://example.com"
-		id := snowflake.ID() + 1
-
-		encodedURL := base62.Base62Encode(id)
-		expectedURL := &domain.URL{
-			Id:       strconv.Itoa(int(id)),
-			ShortURL: encodedURL,
-			LongURL:  destURL,
-		}
-
-		db.On("GetByLongUrl", mock.Anything, destURL).Return(nil, domain.ErrOriginalURLNotFound)
-		db.On("InsertUrl", mock.Anything, *expectedURL).Return(errors.New("database error"))
+		db.On("GetByLongUrl", mock.Anything, "", destURL).Return(nil, domain.ErrOriginalURLNotFound)
+		db.On("InsertUrl", mock.Anything, generatedURL(destURL, "")).Return(errors.New("database error"))
 
-		_, _, err := shortener.Create(context.Background(), destURL)
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: destURL})
 
 		assert.Error(t, err)
 		db.AssertExpectations(t)
@@ -114,25 +130,119 @@ func TestURLShortener_Create(t *testing.T) {
 		logger := &slog.Logger{}
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		destURL := "https://example.com"
-		id := snowflake.ID() + 1
+		db.On("GetByLongUrl", mock.Anything, "", destURL).Return(nil, domain.ErrOriginalURLNotFound)
+		db.On("InsertUrl", mock.Anything, generatedURL(destURL, "")).Return(nil)
+		db.On("GetCountShortUrls", mock.Anything).Return(0, errors.New("database error"))
 
-		encodedURL := base62.Base62Encode(id)
-		expectedURL := &domain.URL{
-			Id:       strconv.Itoa(int(id)),
-			ShortURL: encodedURL,
-			LongURL:  destURL,
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: destURL})
+
+		assert.Error(t, err)
+		db.AssertExpectations(t)
+	})
+}
+
+// generatedURL matches a freshly generated link: the short code must be the
+// base62 form of its snowflake id, which is only known at call time.
+func generatedURL(destURL, ownerID string) any {
+	return mock.MatchedBy(func(url domain.URL) bool {
+		return isGeneratedURL(url, destURL, ownerID)
+	})
+}
+
+func isGeneratedURL(url domain.URL, destURL, ownerID string) bool {
+	id, err := strconv.ParseUint(url.Id, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return url.ShortURL == base62.Base62Encode(id) && url.LongURL == destURL && url.OwnerID == ownerID
+}
+
+func TestURLShortener_CreateWithAlias(t *testing.T) {
+	cfg := &config.ShortenerConfig{AllowAnonymous: true, ReservedAliases: []string{"api", "metrics"}}
+
+	t.Run("Create alias", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(cfg, logger, cache, db, nil, nil)
+
+		db.On("InsertUrl", mock.Anything, mock.MatchedBy(func(url domain.URL) bool {
+			return url.ShortURL == "spring-sale" && url.LongURL == "https://example.com" && url.OwnerID == "42" && url.Id != ""
+		})).Return(nil)
+		db.On("GetCountShortUrls", mock.Anything).Return(1, nil)
+
+		actualURL, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: "https://example.com", Alias: "spring-sale"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "spring-sale", actualURL.ShortURL)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Invalid aliases", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(cfg, logger, cache, db, nil, nil)
+
+		for _, alias := range []string{"ab", "-sale", "spring sale", "весна", "API", "metrics", strings.Repeat("a", 33)} {
+			_, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: "https://example.com", Alias: alias})
+
+			assert.ErrorIs(t, err, domain.ErrInvalidAlias, alias)
 		}
+	})
 
-		db.On("GetByLongUrl", mock.Anything, destURL).Return(nil, domain.ErrOriginalURLNotFound)
-		db.On("InsertUrl", mock.Anything, *expectedURL).Return(nil)
-		db.On("GetCountShortUrls", mock.Anything).Return(0, errors.New("database error"))
+	t.Run("Alias taken", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(cfg, logger, cache, db, nil, nil)
 
-		_, _, err := shortener.Create(context.Background(), destURL)
+		db.On("InsertUrl", mock.Anything, mock.Anything).Return(domain.ErrShortURLAlreadyExist)
+		db.On("GetShortUrl", mock.Anything, "spring-sale").Return(&domain.URL{ShortURL: "spring-sale", LongURL: "https://other.com", OwnerID: "7"}, nil)
 
-		assert.Error(t, err)
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: "https://example.com", Alias: "spring-sale"})
+
+		assert.ErrorIs(t, err, domain.ErrAliasTaken)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Same alias requested again", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(cfg, logger, cache, db, nil, nil)
+
+		existingURL := &domain.URL{ShortURL: "spring-sale", LongURL: "https://example.com", OwnerID: "42"}
+		db.On("InsertUrl", mock.Anything, mock.Anything).Return(domain.ErrShortURLAlreadyExist)
+		db.On("GetShortUrl", mock.Anything, "spring-sale").Return(existingURL, nil)
+
+		actualURL, _, err := shortener.Create(context.Background(), domain.LinkParams{OwnerID: "42", LongURL: "https://example.com", Alias: "spring-sale"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, existingURL, actualURL)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Generated code taken by alias", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(cfg, logger, cache, db, nil, nil)
+
+		destURL := "https://example.com"
+		db.On("GetByLongUrl", mock.Anything, "", destURL).Return(nil, domain.ErrOriginalURLNotFound)
+		db.On("InsertUrl", mock.Anything, generatedURL(destURL, "")).Return(domain.ErrShortURLAlreadyExist).Once()
+		db.On("InsertUrl", mock.Anything, generatedURL(destURL, "")).Return(nil).Once()
+		db.On("GetCountShortUrls", mock.Anything).Return(2, nil)
+
+		actualURL, _, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: destURL})
+
+		assert.NoError(t, err)
+		assert.True(t, isGeneratedURL(*actualURL, destURL, ""))
 		db.AssertExpectations(t)
 	})
 }
@@ -142,14 +252,14 @@ func TestURLShortener_GetOriginalURL(t *testing.T) {
 		logger := &slog.Logger{}
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		shortURL := "shortURL"
 		longURL := "https://example.com"
 
-		cache.On("Get", mock.Anything, shortURL).Return(longURL, nil)
+		cache.On("Get", mock.Anything, shortURL).Return(cacheEntry(cachedLink{LongURL: longURL}), nil)
 
-		actualURL, err := shortener.GetOriginalURL(context.Background(), shortURL)
+		actualURL, err := shortener.GetOriginalURL(context.Background(), shortURL, "")
 
 		assert.NoError(t, err)
 		assert.Equal(t, longURL, actualURL)
@@ -161,7 +271,7 @@ func TestURLShortener_GetOriginalURL(t *testing.T) {
 		logger := &slog.Logger{}
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		shortURL := "shortURL"
 		longURL := "https://example.com"
@@ -171,9 +281,9 @@ func TestURLShortener_GetOriginalURL(t *testing.T) {
 
 		cache.On("Get", mock.Anything, shortURL).Return(nil, errors.New("cache miss"))
 		db.On("GetShortUrl", mock.Anything, shortURL).Return(expectedURL, nil)
-		cache.On("Set", mock.Anything, shortURL, expectedURL, time.Hour).Return(nil)
+		cache.On("Set", mock.Anything, shortURL, cacheEntry(cachedLink{LongURL: longURL}), time.Hour).Return(nil)
 
-		actualURL, err := shortener.GetOriginalURL(context.Background(), shortURL)
+		actualURL, err := shortener.GetOriginalURL(context.Background(), shortURL, "")
 
 		assert.NoError(t, err)
 		assert.Equal(t, longURL, actualURL)
@@ -185,14 +295,14 @@ func TestURLShortener_GetOriginalURL(t *testing.T) {
 		logger := &slog.Logger{}
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		shortURL := "shortURL"
 
 		cache.On("Get", mock.Anything, shortURL).Return(nil, errors.New("cache miss"))
 		db.On("GetShortUrl", mock.Anything, shortURL).Return(nil, errors.New("database error"))
 
-		_, err := shortener.GetOriginalURL(context.Background(), shortURL)
+		_, err := shortener.GetOriginalURL(context.Background(), shortURL, "")
 
 		assert.Error(t, err)
 		cache.AssertExpectations(t)
@@ -207,7 +317,7 @@ func TestURLShortener_GetOriginalURL(t *testing.T) {
 		logger := slog.New(handler)
 		cache := urlMocks.NewCache(t)
 		db := urlMocks.NewDatabase(t)
-		shortener := New(logger, cache, db)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
 
 		shortURL := "shortURL"
 		longURL := "https://example.com"
@@ -217,9 +327,9 @@ func TestURLShortener_GetOriginalURL(t *testing.T) {
 
 		cache.On("Get", mock.Anything, shortURL).Return(nil, errors.New("cache miss"))
 		db.On("GetShortUrl", mock.Anything, shortURL).Return(expectedURL, nil)
-		cache.On("Set", mock.Anything, shortURL, expectedURL, time.Hour).Return(errors.New("cache set error"))
+		cache.On("Set", mock.Anything, shortURL, cacheEntry(cachedLink{LongURL: longURL}), time.Hour).Return(errors.New("cache set error"))
 
-		actualURL, err := shortener.GetOriginalURL(context.Background(), shortURL)
+		actualURL, err := shortener.GetOriginalURL(context.Background(), shortURL, "")
 
 		assert.NoError(t, err)
 		assert.Equal(t, longURL, actualURL)
@@ -227,3 +337,355 @@ func TestURLShortener_GetOriginalURL(t *testing.T) {
 		db.AssertExpectations(t)
 	})
 }
+
+func TestURLShortener_GetOriginalURL_Expiration(t *testing.T) {
+	t.Run("Expired by time in database", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		expiredURL := &domain.URL{LongURL: "https://example.com", ExpiresAt: time.Now().Add(-time.Minute)}
+		cache.On("Get", mock.Anything, "shortURL").Return(nil, errors.New("cache miss"))
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(expiredURL, nil)
+
+		_, err := shortener.GetOriginalURL(context.Background(), "shortURL", "")
+
+		assert.ErrorIs(t, err, domain.ErrLinkExpired)
+		cache.AssertExpectations(t)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Expired by time in cache", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		entry := cacheEntry(cachedLink{LongURL: "https://example.com", ExpiresAt: time.Now().Add(-time.Minute)})
+		cache.On("Get", mock.Anything, "shortURL").Return(entry, nil)
+
+		_, err := shortener.GetOriginalURL(context.Background(), "shortURL", "")
+
+		assert.ErrorIs(t, err, domain.ErrLinkExpired)
+		cache.AssertExpectations(t)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Cache entry lives until expiration", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		expiringURL := &domain.URL{LongURL: "https://example.com", ExpiresAt: time.Now().Add(10 * time.Minute)}
+		cache.On("Get", mock.Anything, "shortURL").Return(nil, errors.New("cache miss"))
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(expiringURL, nil)
+		cache.On("Set", mock.Anything, "shortURL", mock.Anything, mock.MatchedBy(func(ttl time.Duration) bool {
+			return ttl > 9*time.Minute && ttl <= 10*time.Minute
+		})).Return(nil)
+
+		actualURL, err := shortener.GetOriginalURL(context.Background(), "shortURL", "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", actualURL)
+		cache.AssertExpectations(t)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Clicks exhausted on cache hit", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		entry := cacheEntry(cachedLink{LongURL: "https://example.com", MaxClicks: 5})
+		cache.On("Get", mock.Anything, "shortURL").Return(entry, nil)
+		db.On("IncrementClicks", mock.Anything, "shortURL").Return(domain.ErrLinkExpired)
+
+		_, err := shortener.GetOriginalURL(context.Background(), "shortURL", "")
+
+		assert.ErrorIs(t, err, domain.ErrLinkExpired)
+		cache.AssertExpectations(t)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Failed click count does not break the redirect", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		entry := cacheEntry(cachedLink{LongURL: "https://example.com", MaxClicks: 5})
+		cache.On("Get", mock.Anything, "shortURL").Return(entry, nil)
+		db.On("IncrementClicks", mock.Anything, "shortURL").Return(errors.New("database error"))
+
+		actualURL, err := shortener.GetOriginalURL(context.Background(), "shortURL", "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", actualURL)
+	})
+
+	t.Run("Bot does not use up clicks", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		agents := urlMocks.NewAgentClassifier(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, agents)
+
+		entry := cacheEntry(cachedLink{LongURL: "https://example.com", MaxClicks: 5})
+		cache.On("Get", mock.Anything, "shortURL").Return(entry, nil)
+		agents.On("Classify", "Slackbot-LinkExpanding 1.0").Return(useragent.Agent{Bot: true})
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(&domain.URL{LongURL: "https://example.com", MaxClicks: 5, Clicks: 4}, nil)
+
+		actualURL, err := shortener.GetOriginalURL(context.Background(), "shortURL", "Slackbot-LinkExpanding 1.0")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", actualURL)
+		db.AssertNotCalled(t, "IncrementClicks", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Bot does not follow exhausted link", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		agents := urlMocks.NewAgentClassifier(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, agents)
+
+		entry := cacheEntry(cachedLink{LongURL: "https://example.com", MaxClicks: 5})
+		cache.On("Get", mock.Anything, "shortURL").Return(entry, nil)
+		agents.On("Classify", "Googlebot/2.1").Return(useragent.Agent{Bot: true})
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(&domain.URL{LongURL: "https://example.com", MaxClicks: 5, Clicks: 5}, nil)
+
+		_, err := shortener.GetOriginalURL(context.Background(), "shortURL", "Googlebot/2.1")
+
+		assert.ErrorIs(t, err, domain.ErrLinkExpired)
+		db.AssertNotCalled(t, "IncrementClicks", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Human uses up a click", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		agents := urlMocks.NewAgentClassifier(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, agents)
+
+		entry := cacheEntry(cachedLink{LongURL: "https://example.com", MaxClicks: 5})
+		cache.On("Get", mock.Anything, "shortURL").Return(entry, nil)
+		agents.On("Classify", "Mozilla/5.0").Return(useragent.Agent{})
+		db.On("IncrementClicks", mock.Anything, "shortURL").Return(nil)
+
+		actualURL, err := shortener.GetOriginalURL(context.Background(), "shortURL", "Mozilla/5.0")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", actualURL)
+		db.AssertExpectations(t)
+	})
+}
+
+func TestURLShortener_CreateWithExpiration(t *testing.T) {
+	t.Run("Expiring link is never shared", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
+
+		expiresAt := time.Now().Add(time.Hour)
+		db.On("InsertUrl", mock.Anything, mock.MatchedBy(func(url domain.URL) bool {
+			return url.ExpiresAt.Equal(expiresAt) && url.MaxClicks == 10
+		})).Return(nil)
+		db.On("GetCountShortUrls", mock.Anything).Return(1, nil)
+
+		actualURL, _, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: "https://example.com", ExpiresAt: expiresAt, MaxClicks: 10})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), actualURL.MaxClicks)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Expiration in the past", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{AllowAnonymous: true}, logger, cache, db, nil, nil)
+
+		_, _, err := shortener.Create(context.Background(), domain.LinkParams{LongURL: "https://example.com", ExpiresAt: time.Now().Add(-time.Hour)})
+
+		assert.ErrorIs(t, err, domain.ErrInvalidExpiration)
+	})
+}
+
+func cacheEntry(link cachedLink) string {
+	buf, _ := json.Marshal(link)
+	return string(buf)
+}
+
+func TestURLShortener_ListLinks(t *testing.T) {
+	links := []domain.URL{
+		{Id: "3", ShortURL: "c", CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Id: "2", ShortURL: "b", CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{Id: "1", ShortURL: "a", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	t.Run("First page", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		filter := domain.LinkFilter{OwnerID: "42", SortBy: domain.LinkSortCreatedAt, Limit: 2}
+		db.On("ListLinks", mock.Anything, domain.LinkFilter{OwnerID: "42", SortBy: domain.LinkSortCreatedAt, Limit: 3}).Return(links, nil)
+
+		page, err := shortener.ListLinks(context.Background(), filter, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, links[:2], page.Links)
+		assert.NotEmpty(t, page.NextCursor)
+
+		after, err := decodeCursor(page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "2", after.ID)
+		assert.True(t, links[1].CreatedAt.Equal(after.CreatedAt))
+	})
+
+	t.Run("Last page", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		cursor, _ := encodeCursor(domain.LinkCursor{SortBy: domain.LinkSortCreatedAt, CreatedAt: links[1].CreatedAt, ID: "2"})
+		db.On("ListLinks", mock.Anything, mock.MatchedBy(func(f domain.LinkFilter) bool {
+			return f.After != nil && f.After.ID == "2" && f.Limit == 3
+		})).Return(links[2:], nil)
+
+		page, err := shortener.ListLinks(context.Background(), domain.LinkFilter{OwnerID: "42", SortBy: domain.LinkSortCreatedAt, Limit: 2}, cursor)
+
+		assert.NoError(t, err)
+		assert.Equal(t, links[2:], page.Links)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Cursor of another ordering", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		cursor, _ := encodeCursor(domain.LinkCursor{SortBy: domain.LinkSortClicks, Clicks: 5, ID: "2"})
+
+		_, err := shortener.ListLinks(context.Background(), domain.LinkFilter{OwnerID: "42", SortBy: domain.LinkSortCreatedAt, Limit: 2}, cursor)
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+	})
+
+	t.Run("Malformed cursor", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		_, err := shortener.ListLinks(context.Background(), domain.LinkFilter{OwnerID: "42", Limit: 2}, "not a cursor")
+
+		assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+	})
+}
+
+func TestURLShortener_DeleteShortUrl(t *testing.T) {
+	t.Run("Delete own link", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(&domain.URL{ShortURL: "shortURL", OwnerID: "42"}, nil)
+		db.On("DeleteShortUrl", mock.Anything, "shortURL").Return(nil)
+		cache.On("Delete", mock.Anything, "shortURL").Return(nil)
+
+		err := shortener.DeleteShortUrl(context.Background(), "42", "shortURL")
+
+		assert.NoError(t, err)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Delete link of another user", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(&domain.URL{ShortURL: "shortURL", OwnerID: "7"}, nil)
+
+		err := shortener.DeleteShortUrl(context.Background(), "42", "shortURL")
+
+		assert.ErrorIs(t, err, domain.ErrNotLinkOwner)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Delete anonymous link", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(&domain.URL{ShortURL: "shortURL"}, nil)
+
+		err := shortener.DeleteShortUrl(context.Background(), "42", "shortURL")
+
+		assert.ErrorIs(t, err, domain.ErrNotLinkOwner)
+		db.AssertExpectations(t)
+	})
+}
+
+func TestURLShortener_UpdateLongURL(t *testing.T) {
+	t.Run("Update own link", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		updatedURL := &domain.URL{ShortURL: "shortURL", LongURL: "https://new.example.com", OwnerID: "42"}
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(&domain.URL{ShortURL: "shortURL", LongURL: "https://example.com", OwnerID: "42"}, nil)
+		db.On("UpdateLongUrl", mock.Anything, "shortURL", "https://new.example.com", "42").Return(updatedURL, nil)
+		cache.On("Delete", mock.Anything, "shortURL").Return(nil)
+
+		actualURL, err := shortener.UpdateLongURL(context.Background(), "42", "shortURL", "https://new.example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, updatedURL, actualURL)
+		cache.AssertExpectations(t)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Update link of another user", func(t *testing.T) {
+		logger := &slog.Logger{}
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(&domain.URL{ShortURL: "shortURL", OwnerID: "7"}, nil)
+
+		_, err := shortener.UpdateLongURL(context.Background(), "42", "shortURL", "https://new.example.com")
+
+		assert.ErrorIs(t, err, domain.ErrNotLinkOwner)
+		cache.AssertExpectations(t)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Cache invalidation error", func(t *testing.T) {
+		logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+		cache := urlMocks.NewCache(t)
+		db := urlMocks.NewDatabase(t)
+		shortener := New(&config.ShortenerConfig{}, logger, cache, db, nil, nil)
+
+		updatedURL := &domain.URL{ShortURL: "shortURL", LongURL: "https://new.example.com", OwnerID: "42"}
+		db.On("GetShortUrl", mock.Anything, "shortURL").Return(&domain.URL{ShortURL: "shortURL", OwnerID: "42"}, nil)
+		db.On("UpdateLongUrl", mock.Anything, "shortURL", "https://new.example.com", "42").Return(updatedURL, nil)
+		cache.On("Delete", mock.Anything, "shortURL").Return(errors.New("redis is down"))
+
+		actualURL, err := shortener.UpdateLongURL(context.Background(), "42", "shortURL", "https://new.example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, updatedURL, actualURL)
+	})
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS short_urls_owner_long_url_idx;
+
+ALTER TABLE short_urls ADD CONSTRAINT short_urls_long_url_key UNIQUE (long_url);
+
+ALTER TABLE short_urls DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,6 @@
+ALTER TABLE short_urls ADD COLUMN owner_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+-- the same destination may now be shortened independently by several users
+ALTER TABLE short_urls DROP CONSTRAINT short_urls_long_url_key;
+
+CREATE INDEX short_urls_owner_long_url_idx ON short_urls (owner_id, long_url);
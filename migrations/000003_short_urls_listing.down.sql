@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS short_urls_owner_clicks_idx;
+DROP INDEX IF EXISTS short_urls_owner_created_idx;
+
+ALTER TABLE short_urls DROP COLUMN IF EXISTS clicks;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS created_at;
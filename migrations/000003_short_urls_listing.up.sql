@@ -0,0 +1,5 @@
+ALTER TABLE short_urls ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE short_urls ADD COLUMN clicks BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX short_urls_owner_created_idx ON short_urls (owner_id, created_at, unique_id);
+CREATE INDEX short_urls_owner_clicks_idx ON short_urls (owner_id, clicks, unique_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS short_urls_archive;
+
+DROP INDEX IF EXISTS short_urls_max_clicks_idx;
+DROP INDEX IF EXISTS short_urls_expires_at_idx;
+
+ALTER TABLE short_urls DROP COLUMN IF EXISTS max_clicks;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS expires_at;
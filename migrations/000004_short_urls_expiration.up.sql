@@ -0,0 +1,20 @@
+ALTER TABLE short_urls ADD COLUMN expires_at TIMESTAMPTZ;
+ALTER TABLE short_urls ADD COLUMN max_clicks BIGINT;
+
+CREATE INDEX short_urls_expires_at_idx ON short_urls (expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX short_urls_max_clicks_idx ON short_urls (short_url) WHERE max_clicks IS NOT NULL;
+
+CREATE TABLE short_urls_archive (
+    id SERIAL PRIMARY KEY,
+    unique_id VARCHAR(255) NOT NULL,
+    short_url VARCHAR(255) NOT NULL,
+    long_url TEXT NOT NULL,
+    owner_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    clicks BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ,
+    max_clicks BIGINT,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX short_urls_archive_owner_idx ON short_urls_archive (owner_id);
@@ -0,0 +1,9 @@
+CREATE TABLE short_url_history (
+    id SERIAL PRIMARY KEY,
+    short_url VARCHAR(255) NOT NULL,
+    long_url TEXT NOT NULL,
+    changed_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX short_url_history_short_url_idx ON short_url_history (short_url, changed_at);
@@ -0,0 +1,10 @@
+CREATE TABLE click_events (
+    id BIGSERIAL PRIMARY KEY,
+    short_url VARCHAR(255) NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_network VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX click_events_short_url_occurred_at_idx ON click_events (short_url, occurred_at);
@@ -0,0 +1,5 @@
+ALTER TABLE click_events
+    ADD COLUMN browser VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN os VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN device VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
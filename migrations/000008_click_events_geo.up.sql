@@ -0,0 +1,3 @@
+ALTER TABLE click_events
+    ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '',
+    ADD COLUMN region VARCHAR(8) NOT NULL DEFAULT '';
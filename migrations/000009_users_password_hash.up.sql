@@ -0,0 +1,2 @@
+-- argon2id hashes keep the parameters and the salt next to the key
+ALTER TABLE users ALTER COLUMN password_hash TYPE VARCHAR(255);
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN refresh_token VARCHAR(100),
+    ADD COLUMN expires_at TIMESTAMP;
+
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
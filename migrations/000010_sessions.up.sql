@@ -0,0 +1,27 @@
+-- a session is a login on a device, its refresh token is rotated on every refresh
+CREATE TABLE sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX sessions_user_idx ON sessions (user_id);
+
+-- every refresh token a session was given, rotated ones are kept to detect their reuse
+CREATE TABLE refresh_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    rotated_at TIMESTAMPTZ
+);
+
+CREATE INDEX refresh_tokens_session_idx ON refresh_tokens (session_id);
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS refresh_token,
+    DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,15 @@
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- sha256 of the secret, the secret itself is only shown on creation
+    key_hash CHAR(64) UNIQUE NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_user_idx ON api_keys (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS password_resets;
+DROP INDEX IF EXISTS users_email_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
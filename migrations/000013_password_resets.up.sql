@@ -0,0 +1,14 @@
+-- the address password reset links are sent to, optional
+ALTER TABLE users ADD COLUMN email VARCHAR(255);
+CREATE UNIQUE INDEX users_email_idx ON users (email);
+
+CREATE TABLE password_resets (
+    -- sha256 of the token, the token itself is only sent to the user
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX password_resets_user_idx ON password_resets (user_id);
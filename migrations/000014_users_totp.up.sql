@@ -0,0 +1,14 @@
+-- the TOTP secret is stored on enrolment, totp_enabled is set once a code of
+-- it is confirmed
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT false;
+-- the time step of the last accepted code, codes are single-use
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- sha256 of the code, the codes are only shown to the user once
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,13 @@
+-- audit of the logins locked after too many failures, the locks themselves
+-- live in Redis
+CREATE TABLE login_lockouts (
+    id BIGSERIAL PRIMARY KEY,
+    -- NULL when the IP is locked
+    nickname VARCHAR(50),
+    ip VARCHAR(45) NOT NULL,
+    failures INT NOT NULL,
+    locked_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX login_lockouts_locked_at_idx ON login_lockouts (locked_at);
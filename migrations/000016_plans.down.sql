@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS monthly_usage;
+ALTER TABLE users DROP COLUMN IF EXISTS plan;
+DROP TABLE IF EXISTS plans;
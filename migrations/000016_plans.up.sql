@@ -0,0 +1,23 @@
+-- NULL limits are unlimited
+CREATE TABLE plans (
+    name VARCHAR(32) PRIMARY KEY,
+    monthly_links INT,
+    monthly_clicks BIGINT,
+    custom_aliases BOOLEAN NOT NULL DEFAULT false
+);
+
+INSERT INTO plans (name, monthly_links, monthly_clicks, custom_aliases) VALUES
+    ('free', 100, 10000, false),
+    ('pro', 5000, 1000000, true),
+    ('business', NULL, NULL, true);
+
+ALTER TABLE users ADD COLUMN plan VARCHAR(32) NOT NULL DEFAULT 'free' REFERENCES plans (name);
+
+-- month is the first day of a UTC month
+CREATE TABLE monthly_usage (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    links INT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, month)
+);
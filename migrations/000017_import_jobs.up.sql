@@ -0,0 +1,38 @@
+-- a job is processed by the instance holding its lease, an expired lease
+-- lets another instance resume it
+CREATE TABLE import_jobs (
+    id BIGINT PRIMARY KEY,
+    owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    total INT NOT NULL,
+    processed INT NOT NULL DEFAULT 0,
+    created INT NOT NULL DEFAULT 0,
+    existed INT NOT NULL DEFAULT 0,
+    invalid INT NOT NULL DEFAULT 0,
+    quota_exceeded INT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT,
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX import_jobs_owner_id_idx ON import_jobs (owner_id, id DESC);
+CREATE INDEX import_jobs_unfinished_idx ON import_jobs (id) WHERE status IN ('pending', 'running');
+
+-- rows without a status are still to be processed
+CREATE TABLE import_job_rows (
+    job_id BIGINT NOT NULL REFERENCES import_jobs(id) ON DELETE CASCADE,
+    row_index INT NOT NULL,
+    long_url TEXT NOT NULL,
+    alias TEXT,
+    expires_at TIMESTAMPTZ,
+    max_clicks BIGINT,
+    status VARCHAR(16),
+    short_url VARCHAR(255),
+    error TEXT,
+    PRIMARY KEY (job_id, row_index)
+);
+
+CREATE INDEX import_job_rows_pending_idx ON import_job_rows (job_id, row_index) WHERE status IS NULL;
@@ -8,4 +8,5 @@ import (
 type Cache interface {
 	Set(ctx context.Context, key any, value any, ttl time.Duration) error
 	Get(ctx context.Context, key any) (value any, err error)
+	Delete(ctx context.Context, key any) error
 }
@@ -0,0 +1,130 @@
+// Package geoip resolves client addresses to locations using a local database
+// in the MaxMind DB format, such as GeoLite2-Country or GeoLite2-City.
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is where an address is registered. Country is an ISO 3166-1 code,
+// Region is an ISO 3166-2 code such as "DE-BE", empty for country databases.
+type Location struct {
+	Country string
+	Region  string
+}
+
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+}
+
+// Reader looks addresses up in the database file at path. The file is read
+// into memory, so it can be replaced on disk at any time.
+type Reader struct {
+	path    string
+	logger  *slog.Logger
+	db      atomic.Pointer[maxminddb.Reader]
+	modTime time.Time
+	size    int64
+}
+
+func Open(path string, logger *slog.Logger) (*Reader, error) {
+	r := &Reader{
+		path:   path,
+		logger: logger,
+	}
+
+	_, err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Locate returns the location of ip, the zero Location when it is unknown.
+func (r *Reader) Locate(ip string) Location {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Location{}
+	}
+
+	var rec record
+	err := r.db.Load().Lookup(parsed, &rec)
+	if err != nil {
+		r.logger.Error("geoip lookup failed", slog.String("error", err.Error()))
+		return Location{}
+	}
+
+	location := Location{Country: rec.Country.ISOCode}
+	if len(rec.Subdivisions) > 0 && rec.Subdivisions[0].ISOCode != "" && location.Country != "" {
+		location.Region = location.Country + "-" + rec.Subdivisions[0].ISOCode
+	}
+
+	return location
+}
+
+// Reload reads the database again if the file has changed since the last load
+// and reports whether it was replaced. A broken file keeps the current database.
+func (r *Reader) Reload() (bool, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, fmt.Errorf("geoip: %w", err)
+	}
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false, nil
+	}
+
+	buf, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, fmt.Errorf("geoip: %w", err)
+	}
+
+	db, err := maxminddb.FromBytes(buf)
+	if err != nil {
+		return false, fmt.Errorf("geoip: %s: %w", r.path, err)
+	}
+
+	err = db.Verify()
+	if err != nil {
+		return false, fmt.Errorf("geoip: %s: %w", r.path, err)
+	}
+
+	r.db.Store(db)
+	r.modTime, r.size = info.ModTime(), info.Size()
+	return true, nil
+}
+
+// Watch reloads the database every interval until ctx is done.
+func (r *Reader) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				r.logger.Error("failed to reload geoip database", slog.String("error", err.Error()))
+				continue
+			}
+			if reloaded {
+				r.logger.Info("geoip database reloaded", slog.String("path", r.path))
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader_Locate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geo.mmdb")
+	writeTestDB(t, path, map[string]Location{
+		"203.0.113.0/24":  {Country: "DE", Region: "BE"},
+		"198.51.100.0/24": {Country: "FR"},
+	})
+
+	r, err := Open(path, &slog.Logger{})
+	require.NoError(t, err)
+
+	assert.Equal(t, Location{Country: "DE", Region: "DE-BE"}, r.Locate("203.0.113.7"))
+	assert.Equal(t, Location{Country: "FR"}, r.Locate("198.51.100.1"))
+	assert.Equal(t, Location{}, r.Locate("192.0.2.1"))
+	assert.Equal(t, Location{}, r.Locate("not an ip"))
+}
+
+func TestReader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geo.mmdb")
+	writeTestDB(t, path, map[string]Location{"203.0.113.0/24": {Country: "DE"}})
+
+	r, err := Open(path, &slog.Logger{})
+	require.NoError(t, err)
+
+	// a broken file keeps the current database
+	require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o644))
+	_, err = r.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "DE", r.Locate("203.0.113.7").Country)
+
+	// the file is replaced the way updaters do it, by a rename
+	next := path + ".tmp"
+	writeTestDB(t, next, map[string]Location{"203.0.113.0/24": {Country: "NL"}})
+	require.NoError(t, os.Chtimes(next, time.Now(), time.Now().Add(time.Second)))
+	require.NoError(t, os.Rename(next, path))
+
+	reloaded, err := r.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "NL", r.Locate("203.0.113.7").Country)
+}
+
+// writeTestDB writes an IPv4 database in the MaxMind DB format with 24-bit
+// records, mapping the networks to country and subdivision records.
+func writeTestDB(t *testing.T, path string, networks map[string]Location) {
+	t.Helper()
+
+	type node struct{ records [2]int }
+	const empty = -1
+	nodes := []node{{records: [2]int{empty, empty}}}
+	// leaf records are data offsets, stored as negative numbers until the node count is known
+	var data bytes.Buffer
+
+	for cidr, location := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		ones, _ := network.Mask.Size()
+
+		offset := data.Len()
+		writeLocation(&data, location)
+
+		current := 0
+		ip := network.IP.To4()
+		for i := 0; i < ones; i++ {
+			bit := int(ip[i/8]>>(7-i%8)) & 1
+			if i == ones-1 {
+				nodes[current].records[bit] = -2 - offset
+				break
+			}
+			if nodes[current].records[bit] == empty {
+				nodes = append(nodes, node{records: [2]int{empty, empty}})
+				nodes[current].records[bit] = len(nodes) - 1
+			}
+			current = nodes[current].records[bit]
+		}
+	}
+
+	var file bytes.Buffer
+	for _, n := range nodes {
+		for _, record := range n.records {
+			value := record
+			switch {
+			case record == empty:
+				value = len(nodes)
+			case record < empty:
+				value = len(nodes) + 16 + (-2 - record)
+			}
+			file.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	file.Write(make([]byte, 16))
+	file.Write(data.Bytes())
+
+	file.WriteString("\xab\xcd\xefMaxMind.com")
+	writeMap(&file, 6)
+	writeString(&file, "binary_format_major_version")
+	writeUint(&file, 5, 2)
+	writeString(&file, "description")
+	writeMap(&file, 1)
+	writeString(&file, "en")
+	writeString(&file, "Test")
+	writeString(&file, "database_type")
+	writeString(&file, "Test")
+	writeString(&file, "ip_version")
+	writeUint(&file, 5, 4)
+	writeString(&file, "node_count")
+	writeUint(&file, 6, uint64(len(nodes)))
+	writeString(&file, "record_size")
+	writeUint(&file, 5, 24)
+
+	require.NoError(t, os.WriteFile(path, file.Bytes(), 0o644))
+}
+
+func writeLocation(buf *bytes.Buffer, location Location) {
+	fields := 1
+	if location.Region != "" {
+		fields++
+	}
+
+	writeMap(buf, fields)
+	writeString(buf, "country")
+	writeMap(buf, 1)
+	writeString(buf, "iso_code")
+	writeString(buf, location.Country)
+	if location.Region != "" {
+		writeString(buf, "subdivisions")
+		// arrays are an extended type
+		buf.Write([]byte{1, 11 - 7})
+		writeMap(buf, 1)
+		writeString(buf, "iso_code")
+		writeString(buf, location.Region)
+	}
+}
+
+func writeMap(buf *bytes.Buffer, size int) {
+	buf.WriteByte(7<<5 | byte(size))
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(2<<5 | byte(len(s)))
+	buf.WriteString(s)
+}
+
+func writeUint(buf *bytes.Buffer, typ byte, value uint64) {
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], value)
+	trimmed := bytes.TrimLeft(raw[:], "\x00")
+	buf.WriteByte(typ<<5 | byte(len(trimmed)))
+	buf.Write(trimmed)
+}
@@ -1,16 +1,153 @@
 package hash
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 )
 
+// ErrUnknownHash is returned for hashes in a format the hasher can not verify.
+var ErrUnknownHash = errors.New("unknown password hash format")
+
 // PasswordHasher provides hashing logic to securely store passwords.
 type PasswordHasher interface {
 	Hash(password string) (string, error)
+	// Verify reports whether password matches hash. rehash is true when the
+	// password matches but hash is in an outdated format or has outdated
+	// parameters, so it should be replaced with a fresh Hash of the password.
+	Verify(password, hash string) (ok bool, rehash bool, err error)
+}
+
+// Argon2idParams are the cost parameters of Argon2id, Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the second recommended option of RFC 9106.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// Argon2idHasher hashes passwords with Argon2id and a random salt per password.
+// Hashes are encoded in the PHC string format, which keeps the parameters and
+// the salt next to the key:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<base64 salt>$<base64 key>
+type Argon2idHasher struct {
+	params Argon2idParams
+	legacy PasswordHasher
+}
+
+// NewArgon2idHasher returns a hasher with params. Hashes that are not in the
+// Argon2id format are verified by legacy, which may be nil.
+func NewArgon2idHasher(params Argon2idParams, legacy PasswordHasher) (*Argon2idHasher, error) {
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, fmt.Errorf("invalid argon2id parameters: m=%d, t=%d, p=%d", params.Memory, params.Iterations, params.Parallelism)
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = DefaultArgon2idParams.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = DefaultArgon2idParams.KeyLength
+	}
+
+	return &Argon2idHasher{params: params, legacy: legacy}, nil
+}
+
+// Hash creates an Argon2id hash of given password.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify checks password against an Argon2id hash, or a legacy one. Legacy
+// hashes and hashes with other parameters than the hasher's ask for a rehash.
+func (h *Argon2idHasher) Verify(password, hash string) (bool, bool, error) {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		if h.legacy == nil {
+			return false, false, ErrUnknownHash
+		}
+
+		ok, _, err := h.legacy.Verify(password, hash)
+		return ok, ok, err
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+
+	return true, params != h.params, nil
+}
+
+func decodeArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("%w: malformed argon2id hash", ErrUnknownHash)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported argon2 version %q", ErrUnknownHash, parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: malformed argon2id parameters: %v", ErrUnknownHash, err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: malformed argon2id salt: %v", ErrUnknownHash, err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: malformed argon2id key: %v", ErrUnknownHash, err)
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
 }
 
 // SHA1Hasher uses SHA1 to hash passwords with provided salt.
+//
+// Deprecated: the salt is shared by all passwords and SHA1 is fast to brute
+// force. It is only kept to verify the hashes stored before Argon2idHasher,
+// they are rehashed on the next login.
 type SHA1Hasher struct {
 	salt string
 }
@@ -32,4 +169,14 @@ func (h *SHA1Hasher) Hash(password string) (string, error) {
 	}
 
 	return fmt.Sprintf("%x", hash.Sum([]byte(h.salt))), nil
-}
\ No newline at end of file
+}
+
+// Verify compares the SHA1 hash of password with hash.
+func (h *SHA1Hasher) Verify(password, hash string) (bool, bool, error) {
+	other, err := h.Hash(password)
+	if err != nil {
+		return false, false, err
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(other)) == 1, false, nil
+}
@@ -0,0 +1,90 @@
+package hash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testParams keep the tests fast.
+var testParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idHasher(t *testing.T) {
+	h, err := NewArgon2idHasher(testParams, nil)
+	require.NoError(t, err)
+
+	first, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	second, err := h.Hash("correct horse")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.NotEqual(t, first, second, "salts must be random")
+
+	ok, rehash, err := h.Verify("correct horse", first)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _, err = h.Verify("battery staple", first)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	t.Run("Outdated parameters", func(t *testing.T) {
+		stronger, err := NewArgon2idHasher(Argon2idParams{Memory: 2048, Iterations: 2, Parallelism: 1}, nil)
+		require.NoError(t, err)
+
+		ok, rehash, err := stronger.Verify("correct horse", first)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, rehash)
+
+		_, rehash, err = stronger.Verify("battery staple", first)
+		assert.NoError(t, err)
+		assert.False(t, rehash)
+	})
+
+	t.Run("Malformed hash", func(t *testing.T) {
+		for _, hash := range []string{
+			"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+			"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+		} {
+			_, _, err := h.Verify("correct horse", hash)
+			assert.ErrorIs(t, err, ErrUnknownHash, hash)
+		}
+	})
+
+	t.Run("Unknown format without legacy hasher", func(t *testing.T) {
+		_, _, err := h.Verify("correct horse", "5f4dcc3b5aa765d61d8327deb882cf99")
+		assert.ErrorIs(t, err, ErrUnknownHash)
+	})
+}
+
+func TestArgon2idHasher_Legacy(t *testing.T) {
+	legacy, err := NewSHA1Hasher("salt")
+	require.NoError(t, err)
+	h, err := NewArgon2idHasher(testParams, legacy)
+	require.NoError(t, err)
+
+	old, err := legacy.Hash("correct horse")
+	require.NoError(t, err)
+
+	ok, rehash, err := h.Verify("correct horse", old)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	ok, rehash, err = h.Verify("battery staple", old)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+}
+
+func TestNewArgon2idHasher(t *testing.T) {
+	_, err := NewArgon2idHasher(Argon2idParams{Iterations: 1, Parallelism: 1}, nil)
+	assert.Error(t, err)
+}
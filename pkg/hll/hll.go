@@ -0,0 +1,85 @@
+// Package hll implements the HyperLogLog cardinality estimator. It mirrors the
+// PFADD/PFCOUNT/PFMERGE commands of Redis for setups without Redis.
+package hll
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// precision is the number of index bits, 2^14 registers give a standard error
+// of about 0.81%, the same as Redis.
+const (
+	precision = 14
+	registers = 1 << precision
+)
+
+// Sketch estimates the number of distinct elements added to it.
+// The zero value is not usable, create sketches with New.
+type Sketch struct {
+	registers []uint8
+}
+
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, registers)}
+}
+
+// Add adds the element to the sketch and reports whether the estimate may have changed.
+func (s *Sketch) Add(element []byte) bool {
+	h := hash(element)
+	index := h >> (64 - precision)
+	// the remaining bits with a sentinel, so the rank never exceeds 64-precision+1
+	rank := uint8(bits.LeadingZeros64(h<<precision|1<<(precision-1))) + 1
+
+	if rank <= s.registers[index] {
+		return false
+	}
+	s.registers[index] = rank
+	return true
+}
+
+// Merge folds other into s, after that s estimates the union of both.
+func (s *Sketch) Merge(other *Sketch) {
+	for i, rank := range other.registers {
+		s.registers[i] = max(s.registers[i], rank)
+	}
+}
+
+// Count returns the estimated number of distinct elements.
+func (s *Sketch) Count() uint64 {
+	const m = float64(registers)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	sum := 0.0
+	zeros := 0
+	for _, rank := range s.registers {
+		sum += 1 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha * m * m / sum
+	// linear counting is more precise for small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// hash is 64-bit FNV-1a followed by the murmur3 finalizer, FNV alone does not
+// spread the high bits of similar inputs well enough.
+func hash(element []byte) uint64 {
+	f := fnv.New64a()
+	f.Write(element)
+	h := f.Sum64()
+
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
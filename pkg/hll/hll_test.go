@@ -0,0 +1,47 @@
+package hll
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketch_Count(t *testing.T) {
+	tests := []struct {
+		name     string
+		distinct int
+	}{
+		{"Empty", 0},
+		{"Small", 100},
+		{"Medium", 10000},
+		{"Large", 200000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for i := 0; i < tt.distinct; i++ {
+				s.Add([]byte("visitor-" + strconv.Itoa(i)))
+				// repeated elements must not be counted twice
+				s.Add([]byte("visitor-" + strconv.Itoa(i)))
+			}
+
+			assert.InDelta(t, tt.distinct, s.Count(), float64(tt.distinct)*0.03+1)
+		})
+	}
+}
+
+func TestSketch_Merge(t *testing.T) {
+	a, b := New(), New()
+	for i := 0; i < 6000; i++ {
+		a.Add([]byte(strconv.Itoa(i)))
+	}
+	for i := 4000; i < 10000; i++ {
+		b.Add([]byte(strconv.Itoa(i)))
+	}
+
+	a.Merge(b)
+
+	assert.InDelta(t, 10000, a.Count(), 300)
+}
@@ -0,0 +1,74 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sort"
+)
+
+// JSONWebKey is a public key in the JWK format (RFC 7517).
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// N and E are set for RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve and X are set for Ed25519 keys.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the RSA and Ed25519 keys of the keyset ordered by kid. HS256
+// secrets are never published.
+func (m *Manager) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(m.keys.byID))}
+
+	for id, k := range m.keys.byID {
+		jwk := JSONWebKey{KeyID: id, Use: "sig", Algorithm: k.method.Alg()}
+		switch public := k.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+
+	return set
+}
+
+// ServeJWKS publishes the verification keys of tokenManager, other services
+// use them to verify the access tokens.
+func ServeJWKS(tokenManager TokenManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buf, err := json.Marshal(tokenManager.JWKS())
+		if err != nil {
+			ProcessError(w, "failed to encode keys", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		// keys change only on restarts, verifiers may keep them for a while
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_, _ = w.Write(buf)
+	}
+}
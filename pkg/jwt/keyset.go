@@ -0,0 +1,169 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+// KeyConfig describes the keys of a Manager.
+type KeyConfig struct {
+	// Secret is the HS256 key, it signs new tokens when SigningKeyID is empty.
+	Secret string
+	// PreviousSecrets are former HS256 keys, tokens signed with them are
+	// accepted until they expire.
+	PreviousSecrets []string
+	// KeysDir holds RSA and Ed25519 keys in PEM files named <kid>.pem. Private
+	// keys sign and verify, public keys only verify.
+	KeysDir string
+	// SigningKeyID is the kid of the private key in KeysDir that signs new tokens.
+	SigningKeyID string
+}
+
+// key is a key of the keyset, sign is nil for public keys.
+type key struct {
+	id     string
+	method jwt.SigningMethod
+	sign   any
+	verify any
+}
+
+type keySet struct {
+	signing *key
+	byID    map[string]*key
+	// secrets verify the tokens issued without kid, before the keyset.
+	secrets []*key
+}
+
+func newKeySet(cfg KeyConfig) (*keySet, error) {
+	set := &keySet{byID: make(map[string]*key)}
+
+	for i, secret := range append([]string{cfg.Secret}, cfg.PreviousSecrets...) {
+		if secret == "" {
+			continue
+		}
+		k := hmacKey(secret)
+		set.byID[k.id] = k
+		set.secrets = append(set.secrets, k)
+		if i == 0 {
+			set.signing = k
+		}
+	}
+
+	if cfg.KeysDir != "" {
+		keys, err := loadKeys(cfg.KeysDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if _, ok := set.byID[k.id]; ok {
+				return nil, fmt.Errorf("duplicate key id %q", k.id)
+			}
+			set.byID[k.id] = k
+		}
+	}
+
+	if cfg.SigningKeyID != "" {
+		k, ok := set.byID[cfg.SigningKeyID]
+		if !ok || k.sign == nil {
+			return nil, fmt.Errorf("no private key with id %q in %q", cfg.SigningKeyID, cfg.KeysDir)
+		}
+		set.signing = k
+	}
+
+	if set.signing == nil {
+		return nil, fmt.Errorf("empty signing key")
+	}
+
+	return set, nil
+}
+
+// hmacKey names the secret after its hash, so that the secret itself does not
+// appear in the tokens.
+func hmacKey(secret string) *key {
+	sum := sha256.Sum256([]byte(secret))
+
+	return &key{
+		id:     "hs256-" + hex.EncodeToString(sum[:8]),
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+func loadKeys(dir string) ([]*key, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("jwt.loadKeys: %w", err)
+	}
+
+	keys := make([]*key, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("jwt.loadKeys: %w", err)
+		}
+
+		k, err := parsePEMKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwt.loadKeys: %s: %w", file, err)
+		}
+		k.id = strings.TrimSuffix(filepath.Base(file), ".pem")
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+func parsePEMKey(data []byte) (*key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key is shorter than %d bits", minRSAKeyBits)
+		}
+		return &key{method: jwt.SigningMethodRS256, sign: k, verify: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key is shorter than %d bits", minRSAKeyBits)
+		}
+		return &key{method: jwt.SigningMethodRS256, verify: k}, nil
+	case ed25519.PrivateKey:
+		return &key{method: jwt.SigningMethodEdDSA, sign: k, verify: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &key{method: jwt.SigningMethodEdDSA, verify: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, expected RSA or Ed25519", parsed)
+	}
+}
@@ -1,81 +1,190 @@
 package jwt
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/rand"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 // TokenManager provides logic for JWT & Refresh tokens generation and parsing.
 type TokenManager interface {
-	NewJWT(userId string, nickname string, ttl time.Duration) (string, error)
+	NewJWT(user UserInfo, ttl time.Duration) (string, error)
 	Parse(accessToken string) (*UserInfo, error)
 	NewRefreshToken() (string, error)
+	// NewMFAToken and ParseMFAToken handle the tokens of logins that wait
+	// for the second factor, they are no access tokens.
+	NewMFAToken(userID string, ttl time.Duration) (string, error)
+	ParseMFAToken(token string) (string, error)
+	// JWKS returns the public keys that verify the access tokens.
+	JWKS() JSONWebKeySet
 }
 
 type Manager struct {
-	signingKey string
+	keys    *keySet
+	methods []string
 }
 
+// NewManager signs the tokens with an HS256 signingKey.
 func NewManager(signingKey string) (*Manager, error) {
-	if signingKey == "" {
-		return nil, fmt.Errorf("empty signing key")
+	return NewKeySetManager(KeyConfig{Secret: signingKey})
+}
+
+// NewKeySetManager signs the tokens with the signing key of cfg and accepts
+// the tokens of every key of cfg. The key of a token is named by its "kid"
+// header, tokens without kid are checked against the HS256 secrets.
+func NewKeySetManager(cfg KeyConfig) (*Manager, error) {
+	keys, err := newKeySet(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Manager{signingKey: signingKey}, nil
+	m := &Manager{keys: keys}
+	for _, method := range []jwt.SigningMethod{jwt.SigningMethodHS256, jwt.SigningMethodRS256, jwt.SigningMethodEdDSA} {
+		for _, k := range keys.byID {
+			if k.method == method {
+				m.methods = append(m.methods, method.Alg())
+				break
+			}
+		}
+	}
+
+	return m, nil
 }
 
-func (m *Manager) NewJWT(userId string, nickname string, ttl time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["id"] = userId
-	claims["nickname"] = nickname
-	claims["exp"] = time.Now().Add(ttl).Unix()
+type claims struct {
+	jwt.RegisteredClaims
+	UserID   string `json:"id"`
+	Nickname string `json:"nickname"`
+	// SessionID is carried so that tokens of revoked sessions can be rejected.
+	SessionID string `json:"sid,omitempty"`
+	Role      string `json:"role,omitempty"`
+	// Purpose is empty for access tokens.
+	Purpose string `json:"purpose,omitempty"`
+}
 
-	return token.SignedString([]byte(m.signingKey))
+// purposeMFA marks the tokens that only pass the second step of a login.
+const purposeMFA = "mfa"
+
+// NewJWT issues an access token of the user's session, signed with the
+// signing key of the keyset.
+func (m *Manager) NewJWT(user UserInfo, ttl time.Duration) (string, error) {
+	return m.sign(claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+		UserID:    user.UserID,
+		Nickname:  user.Nickname,
+		SessionID: user.SessionID,
+		Role:      user.Role,
+	})
+}
+
+// NewMFAToken issues the token of a login of the user that waits for the
+// second factor.
+func (m *Manager) NewMFAToken(userID string, ttl time.Duration) (string, error) {
+	return m.sign(claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+		UserID:  userID,
+		Purpose: purposeMFA,
+	})
+}
+
+func (m *Manager) sign(c claims) (string, error) {
+	signing := m.keys.signing
+	token := jwt.NewWithClaims(signing.method, c)
+	token.Header["kid"] = signing.id
+
+	return token.SignedString(signing.sign)
 }
 
 type UserInfo struct {
 	UserID   string
 	Nickname string
+	// SessionID is empty for tokens issued before sessions were introduced.
+	SessionID string
+	// Role is empty for tokens issued before roles and for API keys, both
+	// mean a regular user.
+	Role string
+	// APIKeyID and Scopes are set for requests made with an API key.
+	APIKeyID string
+	Scopes   []string
 }
 
 func (m *Manager) Parse(accessToken string) (*UserInfo, error) {
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (i interface{}, err error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+	parsed, err := m.parse(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Purpose != "" {
+		return nil, fmt.Errorf("not an access token")
+	}
 
-		return []byte(m.signingKey), nil
-	})
+	return &UserInfo{
+		UserID:    parsed.UserID,
+		Nickname:  parsed.Nickname,
+		SessionID: parsed.SessionID,
+		Role:      parsed.Role,
+	}, nil
+}
+
+// ParseMFAToken returns the user of a token issued by NewMFAToken.
+func (m *Manager) ParseMFAToken(token string) (string, error) {
+	parsed, err := m.parse(token)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Purpose != purposeMFA {
+		return "", fmt.Errorf("not an mfa token")
+	}
+
+	return parsed.UserID, nil
+}
+
+func (m *Manager) parse(token string) (*claims, error) {
+	var parsed claims
+	_, err := jwt.ParseWithClaims(token, &parsed, m.verificationKey, jwt.WithValidMethods(m.methods), jwt.WithExpirationRequired())
 	if err != nil {
 		return nil, err
 	}
 
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+	return &parsed, nil
+}
+
+func (m *Manager) verificationKey(token *jwt.Token) (any, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		// tokens issued before the keyset are signed with one of the secrets
+		set := jwt.VerificationKeySet{}
+		for _, k := range m.keys.secrets {
+			set.Keys = append(set.Keys, k.verify)
+		}
+		if token.Method != jwt.SigningMethodHS256 || len(set.Keys) == 0 {
+			return nil, fmt.Errorf("token without kid")
+		}
+
+		return set, nil
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+	k, ok := m.keys.byID[kid]
 	if !ok {
-		return nil, fmt.Errorf("error get user claims from token")
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	// the algorithm is bound to the key, the header can not choose another one
+	if token.Method != k.method {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
 	}
 
-	return &UserInfo{
-		UserID:   claims["id"].(string),
-		Nickname: claims["nickname"].(string),
-	}, nil
+	return k.verify, nil
 }
 
 func (m *Manager) NewRefreshToken() (string, error) {
 	b := make([]byte, 32)
 
-	s := rand.NewSource(time.Now().Unix())
-	r := rand.New(s)
-
-	if _, err := r.Read(b); err != nil {
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
 
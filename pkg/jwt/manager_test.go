@@ -0,0 +1,204 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+}
+
+func TestManager_KeyRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	require.NoError(t, err)
+	writePEM(t, dir, "2024-01", "PRIVATE KEY", der)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err = x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	writePEM(t, dir, "2024-02", "PRIVATE KEY", der)
+
+	user := UserInfo{UserID: "1", Nickname: "bob", SessionID: "5", Role: "admin"}
+
+	old, err := NewKeySetManager(KeyConfig{KeysDir: dir, SigningKeyID: "2024-01"})
+	require.NoError(t, err)
+	oldToken, err := old.NewJWT(user, time.Minute)
+	require.NoError(t, err)
+
+	rotated, err := NewKeySetManager(KeyConfig{KeysDir: dir, SigningKeyID: "2024-02"})
+	require.NoError(t, err)
+	newToken, err := rotated.NewJWT(user, time.Minute)
+	require.NoError(t, err)
+
+	t.Run("Token of the previous key", func(t *testing.T) {
+		got, err := rotated.Parse(oldToken)
+
+		require.NoError(t, err)
+		assert.Equal(t, &user, got)
+	})
+
+	t.Run("Token of the new key", func(t *testing.T) {
+		token, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+		require.NoError(t, err)
+		assert.Equal(t, "EdDSA", token.Method.Alg())
+		assert.Equal(t, "2024-02", token.Header["kid"])
+
+		got, err := old.Parse(newToken)
+
+		require.NoError(t, err)
+		assert.Equal(t, &user, got)
+	})
+
+	t.Run("Removed key", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(dir, "2024-01.pem")))
+		current, err := NewKeySetManager(KeyConfig{KeysDir: dir, SigningKeyID: "2024-02"})
+		require.NoError(t, err)
+
+		_, err = current.Parse(oldToken)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Published keys", func(t *testing.T) {
+		jwks := rotated.JWKS()
+
+		require.Len(t, jwks.Keys, 2)
+		assert.Equal(t, "2024-01", jwks.Keys[0].KeyID)
+		assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+		assert.Equal(t, "RS256", jwks.Keys[0].Algorithm)
+		assert.Equal(t, "AQAB", jwks.Keys[0].E)
+		assert.Equal(t, "2024-02", jwks.Keys[1].KeyID)
+		assert.Equal(t, "OKP", jwks.Keys[1].KeyType)
+		assert.Equal(t, "Ed25519", jwks.Keys[1].Curve)
+	})
+
+	t.Run("Algorithm of the header is ignored", func(t *testing.T) {
+		// an HS256 token keyed with the public RSA key must not pass as RS256
+		public, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		require.NoError(t, err)
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": "1", "nickname": "bob", "exp": time.Now().Add(time.Minute).Unix()})
+		forged.Header["kid"] = "2024-01"
+		token, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+		require.NoError(t, err)
+
+		_, err = old.Parse(token)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Unknown signing key", func(t *testing.T) {
+		_, err := NewKeySetManager(KeyConfig{KeysDir: dir, SigningKeyID: "2023-12"})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestManager_Secrets(t *testing.T) {
+	user := UserInfo{UserID: "1", Nickname: "bob", SessionID: "5"}
+
+	t.Run("Token issued without kid", func(t *testing.T) {
+		manager, err := NewManager("key")
+		require.NoError(t, err)
+		legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": "1", "nickname": "bob", "sid": "5", "exp": time.Now().Add(time.Minute).Unix()})
+		token, err := legacy.SignedString([]byte("key"))
+		require.NoError(t, err)
+
+		got, err := manager.Parse(token)
+
+		require.NoError(t, err)
+		assert.Equal(t, &user, got)
+	})
+
+	t.Run("Previous secret", func(t *testing.T) {
+		old, err := NewManager("old")
+		require.NoError(t, err)
+		token, err := old.NewJWT(user, time.Minute)
+		require.NoError(t, err)
+		rotated, err := NewKeySetManager(KeyConfig{Secret: "new", PreviousSecrets: []string{"old"}})
+		require.NoError(t, err)
+
+		got, err := rotated.Parse(token)
+
+		require.NoError(t, err)
+		assert.Equal(t, &user, got)
+		assert.Empty(t, rotated.JWKS().Keys)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		manager, err := NewManager("key")
+		require.NoError(t, err)
+		token, err := manager.NewJWT(user, -time.Minute)
+		require.NoError(t, err)
+
+		_, err = manager.Parse(token)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("No keys", func(t *testing.T) {
+		_, err := NewKeySetManager(KeyConfig{})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestManager_MFAToken(t *testing.T) {
+	manager, err := NewManager("key")
+	require.NoError(t, err)
+
+	t.Run("Valid token", func(t *testing.T) {
+		token, err := manager.NewMFAToken("1", time.Minute)
+		require.NoError(t, err)
+
+		userID, err := manager.ParseMFAToken(token)
+
+		require.NoError(t, err)
+		assert.Equal(t, "1", userID)
+	})
+
+	t.Run("Not an access token", func(t *testing.T) {
+		token, err := manager.NewMFAToken("1", time.Minute)
+		require.NoError(t, err)
+
+		_, err = manager.Parse(token)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Access token", func(t *testing.T) {
+		token, err := manager.NewJWT(UserInfo{UserID: "1", Nickname: "bob"}, time.Minute)
+		require.NoError(t, err)
+
+		_, err = manager.ParseMFAToken(token)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		token, err := manager.NewMFAToken("1", -time.Minute)
+		require.NoError(t, err)
+
+		_, err = manager.ParseMFAToken(token)
+
+		assert.Error(t, err)
+	})
+}
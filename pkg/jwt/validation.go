@@ -1,8 +1,10 @@
 package jwt
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -20,7 +22,21 @@ func ProcessError(w http.ResponseWriter, msg string, code int) {
 	_, _ = w.Write(buf)
 }
 
-func Validate(tokenManager TokenManager) func(next http.Handler) http.Handler {
+// SessionDenylist knows the sessions revoked before their access tokens expired.
+type SessionDenylist interface {
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// APIKeyAuthenticator resolves the user of an API key. Unknown, revoked and
+// expired keys give no user and no error, errors mean the key can not be checked.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*UserInfo, error)
+}
+
+// Validate authenticates the request with a bearer token, or with an API key
+// ("Authorization: ApiKey <key>") when keys is not nil. Tokens of sessions in
+// denylist are rejected, denylist may be nil.
+func Validate(tokenManager TokenManager, denylist SessionDenylist, keys APIKeyAuthenticator) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			accessToken := r.Header.Get("Authorization")
@@ -34,7 +50,7 @@ func Validate(tokenManager TokenManager) func(next http.Handler) http.Handler {
 			}
 
 			headerParts := strings.Split(accessToken, " ")
-			if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			if len(headerParts) != 2 || !(headerParts[0] == "Bearer" || headerParts[0] == "ApiKey" && keys != nil) {
 				ProcessError(w, "invalid auth header: ", http.StatusUnauthorized)
 				return
 			}
@@ -45,16 +61,111 @@ func Validate(tokenManager TokenManager) func(next http.Handler) http.Handler {
 				return
 			}
 
-			user, err := tokenManager.Parse(tokenString)
-			if err != nil {
-				ProcessError(w, "Unauthorized", http.StatusUnauthorized)
+			var user *UserInfo
+			var msg string
+			var status int
+			if headerParts[0] == "ApiKey" {
+				user, msg, status = authenticateAPIKey(r.Context(), keys, tokenString)
+			} else {
+				user, msg, status = authenticateBearer(r.Context(), tokenManager, denylist, tokenString)
+			}
+			if user == nil {
+				ProcessError(w, msg, status)
 				return
 			}
 
 			r.Header.Set("user_id", user.UserID)
 			r.Header.Set("nickname", user.Nickname)
+			r.Header.Set("session_id", user.SessionID)
+			r.Header.Set("role", user.Role)
+			r.Header.Set("api_key_id", user.APIKeyID)
+			r.Header.Set("scopes", strings.Join(user.Scopes, ","))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func authenticateBearer(ctx context.Context, tokenManager TokenManager, denylist SessionDenylist, token string) (*UserInfo, string, int) {
+	user, err := tokenManager.Parse(token)
+	if err != nil {
+		return nil, "Unauthorized", http.StatusUnauthorized
+	}
+
+	if denylist != nil && user.SessionID != "" {
+		revoked, err := denylist.IsSessionRevoked(ctx, user.SessionID)
+		if err != nil {
+			// the token can not be trusted until the denylist is back
+			return nil, "can not check session", http.StatusServiceUnavailable
+		}
+		if revoked {
+			return nil, "session is revoked", http.StatusUnauthorized
+		}
+	}
+
+	return user, "", http.StatusOK
+}
+
+func authenticateAPIKey(ctx context.Context, keys APIKeyAuthenticator, key string) (*UserInfo, string, int) {
+	user, err := keys.AuthenticateAPIKey(ctx, key)
+	if err != nil {
+		return nil, "can not check api key", http.StatusServiceUnavailable
+	}
+	if user == nil {
+		return nil, "invalid api key", http.StatusUnauthorized
+	}
+
+	return user, "", http.StatusOK
+}
+
+// RequireScope lets requests made with an API key through only when the key
+// has scope. Access tokens of sessions have every scope.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("api_key_id") != "" && !slices.Contains(strings.Split(r.Header.Get("scopes"), ","), scope) {
+				ProcessError(w, "api key lacks scope "+scope, http.StatusForbidden)
+				return
+			}
 
 			next.ServeHTTP(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// RequireRole lets only the users with one of roles through, it goes after Validate.
+func RequireRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(roles, r.Header.Get("role")) {
+				ProcessError(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Identify authenticates the request when credentials are present and lets
+// anonymous requests through with the identity headers cleared.
+func Identify(tokenManager TokenManager, denylist SessionDenylist, keys APIKeyAuthenticator) func(next http.Handler) http.Handler {
+	validate := Validate(tokenManager, denylist, keys)
+	return func(next http.Handler) http.Handler {
+		authenticated := validate(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" && r.URL.Query().Get("access_token") == "" {
+				r.Header.Del("user_id")
+				r.Header.Del("nickname")
+				r.Header.Del("session_id")
+				r.Header.Del("role")
+				r.Header.Del("api_key_id")
+				r.Header.Del("scopes")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
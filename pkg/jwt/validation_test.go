@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type denylist map[string]bool
+
+func (d denylist) IsSessionRevoked(_ context.Context, sessionID string) (bool, error) {
+	if sessionID == "broken" {
+		return false, errors.New("conn refused")
+	}
+
+	return d[sessionID], nil
+}
+
+func TestValidate(t *testing.T) {
+	manager, err := NewManager("key")
+	require.NoError(t, err)
+
+	var got http.Header
+	handler := Validate(manager, denylist{"revoked": true}, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+
+	tests := []struct {
+		name       string
+		sessionID  string
+		wantStatus int
+	}{
+		{name: "Active session", sessionID: "active", wantStatus: http.StatusOK},
+		{name: "Token without a session", sessionID: "", wantStatus: http.StatusOK},
+		{name: "Revoked session", sessionID: "revoked", wantStatus: http.StatusUnauthorized},
+		{name: "Denylist unavailable", sessionID: "broken", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			token, err := manager.NewJWT(UserInfo{UserID: "1", Nickname: "bob", SessionID: tt.sessionID}, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "1", got.Get("user_id"))
+				assert.Equal(t, tt.sessionID, got.Get("session_id"))
+			} else {
+				assert.Nil(t, got)
+			}
+		})
+	}
+}
+
+type apiKeys map[string]*UserInfo
+
+func (k apiKeys) AuthenticateAPIKey(_ context.Context, key string) (*UserInfo, error) {
+	if key == "broken" {
+		return nil, errors.New("conn refused")
+	}
+
+	return k[key], nil
+}
+
+func TestValidate_APIKey(t *testing.T) {
+	manager, err := NewManager("key")
+	require.NoError(t, err)
+	keys := apiKeys{"usk_read": {UserID: "1", APIKeyID: "3", Scopes: []string{"links:read", "stats:read"}}}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := Validate(manager, nil, keys)(RequireScope("links:read")(ok))
+	writeHandler := Validate(manager, nil, keys)(RequireScope("links:write")(ok))
+	sessionOnly := Validate(manager, nil, nil)(ok)
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		auth       string
+		wantStatus int
+	}{
+		{name: "Key with the scope", handler: handler, auth: "ApiKey usk_read", wantStatus: http.StatusOK},
+		{name: "Key without the scope", handler: writeHandler, auth: "ApiKey usk_read", wantStatus: http.StatusForbidden},
+		{name: "Unknown key", handler: handler, auth: "ApiKey usk_unknown", wantStatus: http.StatusUnauthorized},
+		{name: "Keys unavailable", handler: handler, auth: "ApiKey broken", wantStatus: http.StatusServiceUnavailable},
+		{name: "Keys not accepted", handler: sessionOnly, auth: "ApiKey usk_read", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tt.auth)
+			rr := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+
+	t.Run("Access tokens have every scope", func(t *testing.T) {
+		token, err := manager.NewJWT(UserInfo{UserID: "1", Nickname: "bob", SessionID: "5"}, time.Minute)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		// a client can not grant itself scopes
+		req.Header.Set("api_key_id", "3")
+		rr := httptest.NewRecorder()
+
+		writeHandler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestRequireRole(t *testing.T) {
+	manager, err := NewManager("key")
+	require.NoError(t, err)
+	keys := apiKeys{"usk_read": {UserID: "1", APIKeyID: "3", Scopes: []string{"links:read"}}}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := Validate(manager, nil, keys)(RequireRole("admin", "auditor")(ok))
+
+	tests := []struct {
+		name       string
+		role       string
+		wantStatus int
+	}{
+		{name: "Admin", role: "admin", wantStatus: http.StatusOK},
+		{name: "Auditor", role: "auditor", wantStatus: http.StatusOK},
+		{name: "Regular user", role: "user", wantStatus: http.StatusForbidden},
+		{name: "Token issued before roles", role: "", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := manager.NewJWT(UserInfo{UserID: "1", Nickname: "bob", SessionID: "5", Role: tt.role}, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+
+	t.Run("API keys have no role", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "ApiKey usk_read")
+		// a client can not grant itself a role
+		req.Header.Set("role", "admin")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
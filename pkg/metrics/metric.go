@@ -9,6 +9,10 @@ type PrometheusMetrics struct {
 	RedirectsTotal prometheus.Counter
     SuccessRequest prometheus.Counter
     Info     *prometheus.GaugeVec
+    ClickEventsDropped prometheus.Counter
+    ClickEventsOverQuota prometheus.Counter
+    LiveClicksDropped prometheus.Counter
+    RateLimiterMode *prometheus.GaugeVec
 }
 
 func NewMetrics(reg prometheus.Registerer) *PrometheusMetrics {
@@ -38,7 +42,27 @@ func NewMetrics(reg prometheus.Registerer) *PrometheusMetrics {
             Name:      "info",
             Help:      "Information about the My App environment.",
         }, []string{"version"}),
+        ClickEventsDropped: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: "url_shortener",
+            Name:      "click_events_dropped",
+            Help:      "Number of click events dropped because the buffer was full or storing failed.",
+        }),
+        ClickEventsOverQuota: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: "url_shortener",
+            Name:      "click_events_over_quota",
+            Help:      "Number of click events not stored because the plan of the link owner ran out of clicks.",
+        }),
+        LiveClicksDropped: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: "url_shortener",
+            Name:      "live_clicks_dropped",
+            Help:      "Number of clicks not streamed live because the queue or a subscriber lagged behind.",
+        }),
+        RateLimiterMode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+            Namespace: "url_shortener",
+            Name:      "rate_limiter_mode",
+            Help:      "Active rate limiter mode: redis, or open, local or closed while Redis fails.",
+        }, []string{"mode"}),
     }
-    reg.MustRegister(m.UrlsTotal, m.Redirects, m.Info, m.RedirectsTotal, m.SuccessRequest)
+    reg.MustRegister(m.UrlsTotal, m.Redirects, m.Info, m.RedirectsTotal, m.SuccessRequest, m.ClickEventsDropped, m.ClickEventsOverQuota, m.LiveClicksDropped, m.RateLimiterMode)
     return m
 }
\ No newline at end of file
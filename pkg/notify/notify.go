@@ -0,0 +1,73 @@
+// Package notify delivers messages to users, by email or, in development and
+// tests, to the log or a file.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is a plain text message to the address To.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Log writes the messages to the logger instead of delivering them.
+type Log struct {
+	logger *slog.Logger
+}
+
+func NewLog(logger *slog.Logger) *Log {
+	return &Log{logger: logger}
+}
+
+func (l *Log) Send(ctx context.Context, msg Message) error {
+	l.logger.InfoContext(ctx, "notification", slog.String("to", msg.To), slog.String("subject", msg.Subject), slog.String("body", msg.Body))
+	return nil
+}
+
+// File appends the messages to a file as JSON lines, so that tests and local
+// setups can read what would have been sent.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+type fileRecord struct {
+	Message
+	SentAt time.Time `json:"sent_at"`
+}
+
+func (f *File) Send(_ context.Context, msg Message) error {
+	line, err := json.Marshal(fileRecord{Message: msg, SentAt: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("notify.File.Send: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("notify.File.Send: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("notify.File.Send: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	notifier := NewFile(path)
+
+	require.NoError(t, notifier.Send(context.Background(), Message{To: "bob@example.com", Subject: "first", Body: "1"}))
+	require.NoError(t, notifier.Send(context.Background(), Message{To: "bob@example.com", Subject: "second", Body: "2"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var got fileRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &got))
+	assert.Equal(t, Message{To: "bob@example.com", Subject: "second", Body: "2"}, got.Message)
+	assert.False(t, got.SentAt.IsZero())
+}
+
+func TestFormatEmail(t *testing.T) {
+	from := mail.Address{Name: "URL Shortener", Address: "noreply@sho.rt"}
+	to := mail.Address{Address: "bob@example.com"}
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	email := string(formatEmail(from, to, Message{Subject: "Сброс пароля", Body: "line one\nline two"}, date))
+
+	assert.Contains(t, email, "From: \"URL Shortener\" <noreply@sho.rt>\r\n")
+	assert.Contains(t, email, "To: <bob@example.com>\r\n")
+	assert.Contains(t, email, "Subject: =?utf-8?q?")
+	assert.Contains(t, email, "Date: Wed, 01 May 2024 12:00:00 +0000\r\n")
+	assert.True(t, strings.HasSuffix(email, "\r\n\r\nline one\r\nline two"))
+}
+
+// fakeSMTP accepts one email without TLS and authentication and passes its
+// envelope and data to got.
+func fakeSMTP(t *testing.T, got chan<- []string) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		var received []string
+		_ = text.PrintfLine("220 fake ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				_ = text.PrintfLine("250 fake")
+			case strings.HasPrefix(line, "MAIL"), strings.HasPrefix(line, "RCPT"):
+				received = append(received, line)
+				_ = text.PrintfLine("250 OK")
+			case line == "DATA":
+				_ = text.PrintfLine("354 go ahead")
+				data, _ := text.ReadDotBytes()
+				received = append(received, string(data))
+				_ = text.PrintfLine("250 OK")
+			case line == "QUIT":
+				_ = text.PrintfLine("221 bye")
+				got <- received
+				return
+			default:
+				_ = text.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestSMTP_Send(t *testing.T) {
+	got := make(chan []string, 1)
+	port := fakeSMTP(t, got)
+
+	notifier, err := NewSMTP("127.0.0.1", port, "", "", "URL Shortener <noreply@sho.rt>")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = notifier.Send(ctx, Message{To: "bob@example.com", Subject: "Password reset", Body: "link"})
+	require.NoError(t, err)
+
+	received := <-got
+	require.Len(t, received, 3)
+	assert.Equal(t, "MAIL FROM:<noreply@sho.rt>", received[0])
+	assert.Equal(t, "RCPT TO:<bob@example.com>", received[1])
+	assert.Contains(t, received[2], "Subject: Password reset\n")
+	assert.True(t, strings.HasSuffix(received[2], "\nlink\n"), strconv.Quote(received[2]))
+}
+
+func TestNewSMTP(t *testing.T) {
+	_, err := NewSMTP("", 587, "", "", "noreply@sho.rt")
+	assert.Error(t, err)
+
+	_, err = NewSMTP("smtp.example.com", 587, "", "", "not an address")
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP sends the messages as emails through an SMTP server. The connection is
+// upgraded with STARTTLS when the server offers it, credentials are only sent
+// over TLS.
+type SMTP struct {
+	host     string
+	addr     string
+	from     mail.Address
+	username string
+	password string
+}
+
+func NewSMTP(host string, port int, username, password, from string) (*SMTP, error) {
+	if host == "" {
+		return nil, fmt.Errorf("empty smtp host")
+	}
+
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+
+	return &SMTP{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		from:     *sender,
+		username: username,
+		password: password,
+	}, nil
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("notify.SMTP.Send: invalid recipient: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("notify.SMTP.Send: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return fmt.Errorf("notify.SMTP.Send: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: s.host})
+		if err != nil {
+			return fmt.Errorf("notify.SMTP.Send: %w", err)
+		}
+	}
+	if s.username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection
+		err = client.Auth(smtp.PlainAuth("", s.username, s.password, s.host))
+		if err != nil {
+			return fmt.Errorf("notify.SMTP.Send: %w", err)
+		}
+	}
+
+	err = client.Mail(s.from.Address)
+	if err != nil {
+		return fmt.Errorf("notify.SMTP.Send: %w", err)
+	}
+	err = client.Rcpt(to.Address)
+	if err != nil {
+		return fmt.Errorf("notify.SMTP.Send: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("notify.SMTP.Send: %w", err)
+	}
+	_, err = w.Write(formatEmail(s.from, *to, msg, time.Now()))
+	if err != nil {
+		return fmt.Errorf("notify.SMTP.Send: %w", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("notify.SMTP.Send: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// formatEmail builds a plain text email, the body is quoted-printable so that
+// any text passes through the server unchanged.
+func formatEmail(from, to mail.Address, msg Message, date time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from.String() + "\r\n")
+	buf.WriteString("To: " + to.String() + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	_, _ = body.Write([]byte(msg.Body))
+	_ = body.Close()
+
+	return buf.Bytes()
+}
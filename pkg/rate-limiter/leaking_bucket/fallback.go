@@ -0,0 +1,160 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"url-shortener/pkg/metrics"
+)
+
+// ErrUnavailable refuses requests while a fail-closed limiter is degraded.
+var ErrUnavailable = errors.New("rate limiter unavailable")
+
+// Mode is what a Fallback does while its primary store fails.
+type Mode string
+
+const (
+	// ModeOpen lets all requests through unlimited.
+	ModeOpen Mode = "open"
+	// ModeLocal limits the requests in process.
+	ModeLocal Mode = "local"
+	// ModeClosed refuses all requests.
+	ModeClosed Mode = "closed"
+)
+
+// modePrimary is reported while the primary store works.
+const modePrimary = "redis"
+
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeOpen, ModeLocal, ModeClosed:
+		return mode, nil
+	}
+
+	return "", fmt.Errorf("rate limiter mode %q: want open, local or closed", s)
+}
+
+// Fallback limits with the primary store and switches to mode when it fails.
+// While switched, a request every retry interval tries the primary again and
+// the first one that succeeds switches back.
+type Fallback struct {
+	primary Store
+	local   *Local
+	mode    Mode
+	retry   time.Duration
+	logger  *slog.Logger
+	metrics *metrics.PrometheusMetrics
+
+	mu        sync.Mutex
+	degraded  bool
+	nextProbe time.Time
+	now       func() time.Time
+}
+
+// NewFallback returns a Fallback of primary, metrics is optional.
+func NewFallback(primary Store, mode Mode, retry time.Duration, logger *slog.Logger, metrics *metrics.PrometheusMetrics) *Fallback {
+	f := &Fallback{
+		primary: primary,
+		local:   NewLocal(),
+		mode:    mode,
+		retry:   retry,
+		logger:  logger,
+		metrics: metrics,
+		now:     time.Now,
+	}
+	f.report(modePrimary)
+
+	return f
+}
+
+func (f *Fallback) Allow(ctx context.Context, key string, policy Policy) (*Result, error) {
+	if !f.usePrimary() {
+		return f.fallback(ctx, key, policy)
+	}
+
+	res, err := f.primary.Allow(ctx, key, policy)
+	// a client that went away says nothing about Redis
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if err != nil {
+		f.failed(err)
+		return f.fallback(ctx, key, policy)
+	}
+	f.recovered()
+
+	return res, nil
+}
+
+// usePrimary tells whether the primary is working or is due to be probed.
+func (f *Fallback) usePrimary() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.degraded {
+		return true
+	}
+	now := f.now()
+	if now.Before(f.nextProbe) {
+		return false
+	}
+	// the other requests keep to the fallback while this one probes
+	f.nextProbe = now.Add(f.retry)
+
+	return true
+}
+
+func (f *Fallback) failed(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextProbe = f.now().Add(f.retry)
+	if f.degraded {
+		return
+	}
+	f.degraded = true
+	f.logger.Error("rate limiter switched to fallback",
+		slog.String("mode", string(f.mode)), slog.String("error", err.Error()))
+	f.report(string(f.mode))
+}
+
+func (f *Fallback) recovered() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.degraded {
+		return
+	}
+	f.degraded = false
+	f.logger.Info("rate limiter switched back to redis")
+	f.report(modePrimary)
+}
+
+func (f *Fallback) fallback(ctx context.Context, key string, policy Policy) (*Result, error) {
+	switch f.mode {
+	case ModeOpen:
+		return nil, nil
+	case ModeLocal:
+		return f.local.Allow(ctx, key, policy)
+	default:
+		return nil, ErrUnavailable
+	}
+}
+
+// report sets the mode gauge of the active mode to 1 and the others to 0.
+func (f *Fallback) report(active string) {
+	if f.metrics == nil {
+		return
+	}
+
+	for _, mode := range []string{modePrimary, string(ModeOpen), string(ModeLocal), string(ModeClosed)} {
+		value := 0.0
+		if mode == active {
+			value = 1
+		}
+		f.metrics.RateLimiterMode.WithLabelValues(mode).Set(value)
+	}
+}
@@ -0,0 +1,82 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the full buckets are dropped.
+const sweepInterval = time.Minute
+
+// Local is an in-process token bucket limiter. Its counters are per instance,
+// so with N instances a client gets up to N times the policy rate.
+type Local struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket is full again and can be dropped.
+	full time.Time
+}
+
+func NewLocal() *Local {
+	return &Local{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token of the bucket of key, the bucket holds policy.Rate
+// tokens and is refilled at policy.Rate per policy.Period.
+func (l *Local) Allow(_ context.Context, key string, policy Policy) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	capacity := float64(policy.Rate)
+	// the time to refill one token
+	interval := policy.Period / time.Duration(policy.Rate)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(capacity, b.tokens+float64(now.Sub(b.last))/float64(interval))
+	b.last = now
+
+	res := &Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	res.Remaining = int(b.tokens)
+	res.ResetAfter = time.Duration((capacity - b.tokens) * float64(interval))
+	b.full = now.Add(res.ResetAfter)
+
+	return res, nil
+}
+
+// sweep drops the full buckets, a new bucket is full as well.
+func (l *Local) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis_rate/v9"
+)
+
+// Policy allows Rate requests per Period to each client of a route group.
+// A zero Rate turns the limit off.
+type Policy struct {
+	// Name separates the counters of the route groups.
+	Name   string
+	Rate   int
+	Period time.Duration
+}
+
+// ParsePolicy reads a policy like "100/1m" or "5/s", an empty string or
+// "off" turns the limit off.
+func ParsePolicy(name, s string) (Policy, error) {
+	policy := Policy{Name: name}
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return policy, nil
+	}
+
+	rate, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return policy, fmt.Errorf("invalid rate limit %q of %s, expected <requests>/<period>", s, name)
+	}
+
+	n, err := strconv.Atoi(rate)
+	if err != nil || n < 0 {
+		return policy, fmt.Errorf("invalid rate limit %q of %s: bad number of requests", s, name)
+	}
+
+	// "s", "m" and "h" stand for one unit
+	if period != "" && !strings.ContainsAny(period[:1], "0123456789") {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return policy, fmt.Errorf("invalid rate limit %q of %s: bad period", s, name)
+	}
+
+	policy.Rate = n
+	policy.Period = d
+	return policy, nil
+}
+
+func (p Policy) limit() redis_rate.Limit {
+	return redis_rate.Limit{
+		Rate:   p.Rate,
+		Burst:  p.Rate,
+		Period: p.Period,
+	}
+}
+
+// String formats the policy for the RateLimit-Policy header.
+func (p Policy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Rate, int(p.Period.Seconds()))
+}
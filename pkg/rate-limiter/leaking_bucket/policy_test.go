@@ -0,0 +1,43 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Policy
+		wantErr bool
+	}{
+		{in: "100/1m", want: Policy{Name: "api", Rate: 100, Period: time.Minute}},
+		{in: "5/s", want: Policy{Name: "api", Rate: 5, Period: time.Second}},
+		{in: " 1000/1h ", want: Policy{Name: "api", Rate: 1000, Period: time.Hour}},
+		{in: "off", want: Policy{Name: "api"}},
+		{in: "", want: Policy{Name: "api"}},
+		{in: "100", wantErr: true},
+		{in: "many/1m", wantErr: true},
+		{in: "100/soon", wantErr: true},
+		{in: "100/0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePolicy("api", tt.in)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPolicy_String(t *testing.T) {
+	assert.Equal(t, "100;w=60", Policy{Rate: 100, Period: time.Minute}.String())
+}
@@ -3,43 +3,58 @@ package ratelimiter
 import (
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
-
-	"github.com/go-redis/redis_rate/v9"
 )
 
 var ErrRateLimited = errors.New("rate limited")
 
-var Limiter *redis_rate.Limiter
+// KeyFunc names the client a request is counted for.
+type KeyFunc func(r *http.Request) string
 
-func RateLimit(logger *slog.Logger) func(next http.Handler) http.Handler {
+// RateLimit limits the requests of each client named by key to policy. The
+// answers carry the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers, refused requests also Retry-After. When limiter can not count the
+// request, e.g. a fail-closed limiter without Redis, it is answered with 503.
+func RateLimit(logger *slog.Logger, limiter Store, policy Policy, key KeyFunc) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
+		if policy.Rate == 0 {
+			return next
+		}
+
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			res, err := Limiter.Allow(r.Context(), "url-shortener", redis_rate.PerMinute(10))
+			res, err := limiter.Allow(r.Context(), policy.Name+":"+key(r), policy)
 			if err != nil {
 				logger.Error("Rate limiter", slog.String("error", err.Error()))
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			// not limited, e.g. while a fail-open limiter is degraded
+			if res == nil {
+				next.ServeHTTP(w, r)
 				return
 			}
 
 			h := w.Header()
+			h.Set("RateLimit-Policy", policy.String())
+			h.Set("RateLimit-Limit", strconv.Itoa(policy.Rate))
 			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", seconds(res.ResetAfter))
 
-			if res.Allowed == 0 {
-				// We are rate limited.
-
-				seconds := int(res.RetryAfter / time.Second)
-				h.Set("RateLimit-RetryAfter", strconv.Itoa(seconds))
-
-				// Stop processing and return the error.
+			if !res.Allowed {
+				h.Set("Retry-After", seconds(res.RetryAfter))
 				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
 				return
 			}
 
-			// Continue processing as normal.
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// seconds rounds d up to whole seconds, the headers have no fractions.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(max(d, 0).Seconds())))
+}
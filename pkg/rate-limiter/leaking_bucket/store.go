@@ -0,0 +1,49 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis_rate/v9"
+)
+
+// Store counts the requests of keys against policies.
+type Store interface {
+	// Allow counts a request of key. A nil result without an error means
+	// that no limit is applied, e.g. while a fail-open limiter is degraded.
+	Allow(ctx context.Context, key string, policy Policy) (*Result, error)
+}
+
+type Result struct {
+	Allowed bool
+	// Remaining is how many requests are allowed right away.
+	Remaining int
+	// RetryAfter is the time until the next request is allowed, it is only
+	// set for refused requests.
+	RetryAfter time.Duration
+	// ResetAfter is the time until Remaining is back to the policy rate.
+	ResetAfter time.Duration
+}
+
+// Redis shares the counters of all instances in Redis.
+type Redis struct {
+	limiter *redis_rate.Limiter
+}
+
+func NewRedis(limiter *redis_rate.Limiter) *Redis {
+	return &Redis{limiter: limiter}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, policy Policy) (*Result, error) {
+	res, err := r.limiter.Allow(ctx, key, policy.limit())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Allowed:    res.Allowed > 0,
+		Remaining:  res.Remaining,
+		RetryAfter: max(res.RetryAfter, 0),
+		ResetAfter: res.ResetAfter,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a time that the tests move.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func TestLocal_Allow(t *testing.T) {
+	c := &clock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	local := NewLocal()
+	local.now = c.Now
+	policy := Policy{Name: "test", Rate: 2, Period: time.Second}
+
+	for want := 1; want >= 0; want-- {
+		res, err := local.Allow(context.Background(), "a", policy)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, want, res.Remaining)
+	}
+
+	res, err := local.Allow(context.Background(), "a", policy)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, time.Second, res.ResetAfter)
+
+	// other keys have their own buckets
+	res, err = local.Allow(context.Background(), "b", policy)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	c.now = c.now.Add(500 * time.Millisecond)
+	res, err = local.Allow(context.Background(), "a", policy)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// full buckets are dropped
+	c.now = c.now.Add(sweepInterval)
+	_, err = local.Allow(context.Background(), "c", policy)
+	require.NoError(t, err)
+	assert.Len(t, local.buckets, 1)
+}
+
+// fakeStore fails while err is set.
+type fakeStore struct {
+	err   error
+	calls int
+}
+
+func (s *fakeStore) Allow(context.Context, string, Policy) (*Result, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &Result{Allowed: true, Remaining: 99}, nil
+}
+
+func TestFallback_Allow(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	policy := Policy{Name: "test", Rate: 1, Period: time.Minute}
+
+	tests := []struct {
+		mode    Mode
+		allowed []bool
+		err     error
+	}{
+		{mode: ModeOpen},
+		// the local bucket holds one request
+		{mode: ModeLocal, allowed: []bool{true, false}},
+		{mode: ModeClosed, err: ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			c := &clock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+			primary := &fakeStore{}
+			limiter := NewFallback(primary, tt.mode, 5*time.Second, logger, nil)
+			limiter.now = c.Now
+			limiter.local.now = c.Now
+
+			res, err := limiter.Allow(context.Background(), "a", policy)
+			require.NoError(t, err)
+			assert.Equal(t, 99, res.Remaining)
+
+			primary.err = errors.New("redis down")
+			for i := range 2 {
+				res, err = limiter.Allow(context.Background(), "a", policy)
+				if tt.err != nil {
+					assert.ErrorIs(t, err, tt.err)
+					continue
+				}
+				require.NoError(t, err)
+				if tt.allowed == nil {
+					assert.Nil(t, res)
+				} else {
+					assert.Equal(t, tt.allowed[i], res.Allowed)
+				}
+			}
+			// the primary is not tried again before the retry interval
+			assert.Equal(t, 2, primary.calls)
+
+			primary.err = nil
+			c.now = c.now.Add(5 * time.Second)
+			res, err = limiter.Allow(context.Background(), "a", policy)
+			require.NoError(t, err)
+			assert.Equal(t, 99, res.Remaining)
+			assert.Equal(t, 3, primary.calls)
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	policy := Policy{Name: "test", Rate: 1, Period: time.Minute}
+	key := func(*http.Request) string { return "a" }
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	serve := func(limiter Store) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		RateLimit(logger, limiter, policy, key)(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	limiter := NewLocal()
+	rec := serve(limiter)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	rec = serve(limiter)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	down := &fakeStore{err: errors.New("redis down")}
+	rec = serve(NewFallback(down, ModeOpen, time.Minute, logger, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+
+	rec = serve(NewFallback(down, ModeClosed, time.Minute, logger, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,90 @@
+// Package totp implements the time-based one-time passwords of RFC 6238 as
+// authenticator apps use them: SHA1, 6 digits, 30 second steps.
+package totp
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	period = 30
+	// skew is how many steps around the current one are accepted, it covers
+	// the clock drift of phones and the time to type the code.
+	skew   = 1
+	qrSize = 256
+)
+
+var opts = totp.ValidateOpts{
+	Period:    period,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// Key is a new secret with what an authenticator app needs to enrol it.
+type Key struct {
+	Secret string
+	// URL is the otpauth:// URI of the secret.
+	URL string
+	// QRCode is a data URI of a PNG QR code of URL.
+	QRCode string
+}
+
+// Generate creates a secret of account, issuer names the service in the app.
+func Generate(issuer, account string) (*Key, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: account,
+		Period:      period,
+		Digits:      opts.Digits,
+		Algorithm:   opts.Algorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("totp.Generate: %w", err)
+	}
+
+	img, err := key.Image(qrSize, qrSize)
+	if err != nil {
+		return nil, fmt.Errorf("totp.Generate: %w", err)
+	}
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if err != nil {
+		return nil, fmt.Errorf("totp.Generate: %w", err)
+	}
+
+	return &Key{
+		Secret: key.Secret(),
+		URL:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Validate checks code against secret at now and returns the time step it
+// belongs to. Steps up to lastStep are refused, storing the returned step as
+// the next lastStep makes every code single-use.
+func Validate(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	current := now.Unix() / period
+	for step := current - skew; step <= current+skew; step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*period, 0), opts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, err := Generate("URL Shortener", "bob")
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, key.Secret)
+	assert.True(t, strings.HasPrefix(key.URL, "otpauth://totp/URL%20Shortener:bob?"), key.URL)
+	assert.Contains(t, key.URL, "secret="+key.Secret)
+	assert.True(t, strings.HasPrefix(key.QRCode, "data:image/png;base64,"))
+}
+
+func TestValidate(t *testing.T) {
+	key, err := Generate("URL Shortener", "bob")
+	require.NoError(t, err)
+
+	now := time.Unix(1_700_000_010, 0)
+	code := func(at time.Time) string {
+		code, err := totp.GenerateCode(key.Secret, at)
+		require.NoError(t, err)
+		return code
+	}
+
+	t.Run("Current code", func(t *testing.T) {
+		step, ok := Validate(key.Secret, code(now), now, 0)
+
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30, step)
+	})
+
+	t.Run("Code of the previous step", func(t *testing.T) {
+		step, ok := Validate(key.Secret, code(now.Add(-30*time.Second)), now, 0)
+
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30-1, step)
+	})
+
+	t.Run("Code out of the window", func(t *testing.T) {
+		_, ok := Validate(key.Secret, code(now.Add(-2*time.Minute)), now, 0)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Replayed code", func(t *testing.T) {
+		_, ok := Validate(key.Secret, code(now), now, now.Unix()/30)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		_, ok := Validate(key.Secret, "12345", now, 0)
+
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,204 @@
+// Package useragent classifies User-Agent headers by rules from a JSON file,
+// so new browsers and bots are recognised without a rebuild.
+package useragent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Other is reported when no rule matches.
+	Other = "other"
+	// DeviceBot is the device type of bots.
+	DeviceBot = "bot"
+)
+
+// Agent is the result of classification. For bots Browser is the bot name.
+type Agent struct {
+	Browser string
+	OS      string
+	Device  string
+	Bot     bool
+}
+
+// Rule matches a User-Agent by a regular expression. Patterns are case-insensitive.
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// Rules is the content of the rules file. Rules of a list are tried in order,
+// the first match wins.
+type Rules struct {
+	Bots          []Rule `json:"bots"`
+	Browsers      []Rule `json:"browsers"`
+	OS            []Rule `json:"os"`
+	Devices       []Rule `json:"devices"`
+	DefaultDevice string `json:"default_device"`
+}
+
+type compiledRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+type compiledRules struct {
+	bots          []compiledRule
+	browsers      []compiledRule
+	os            []compiledRule
+	devices       []compiledRule
+	defaultDevice string
+}
+
+type Classifier struct {
+	path    string
+	logger  *slog.Logger
+	rules   atomic.Pointer[compiledRules]
+	modTime time.Time
+}
+
+// NewClassifier loads the rules from path.
+func NewClassifier(path string, logger *slog.Logger) (*Classifier, error) {
+	c := &Classifier{
+		path:   path,
+		logger: logger,
+	}
+
+	_, err := c.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Classify returns the browser, OS and device type of userAgent and whether it is a bot.
+func (c *Classifier) Classify(userAgent string) Agent {
+	rules := c.rules.Load()
+
+	if name, ok := match(rules.bots, userAgent); ok {
+		os, _ := match(rules.os, userAgent)
+		return Agent{Browser: name, OS: os, Device: DeviceBot, Bot: true}
+	}
+
+	browser, _ := match(rules.browsers, userAgent)
+	os, _ := match(rules.os, userAgent)
+	device, ok := match(rules.devices, userAgent)
+	if !ok {
+		device = rules.defaultDevice
+	}
+
+	return Agent{Browser: browser, OS: os, Device: device}
+}
+
+// Reload reads the rules file again if it has changed since the last load and
+// reports whether the rules were replaced. Broken rules keep the current ones.
+func (c *Classifier) Reload() (bool, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return false, fmt.Errorf("useragent: %w", err)
+	}
+	if info.ModTime().Equal(c.modTime) {
+		return false, nil
+	}
+
+	buf, err := os.ReadFile(c.path)
+	if err != nil {
+		return false, fmt.Errorf("useragent: %w", err)
+	}
+
+	var rules Rules
+	err = json.Unmarshal(buf, &rules)
+	if err != nil {
+		return false, fmt.Errorf("useragent: parse %s: %w", c.path, err)
+	}
+
+	compiled, err := compile(rules)
+	if err != nil {
+		return false, fmt.Errorf("useragent: %s: %w", c.path, err)
+	}
+
+	c.rules.Store(compiled)
+	c.modTime = info.ModTime()
+	return true, nil
+}
+
+// Watch reloads the rules file every interval until ctx is done.
+func (c *Classifier) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			reloaded, err := c.Reload()
+			if err != nil {
+				c.logger.Error("failed to reload user agent rules", slog.String("error", err.Error()))
+				continue
+			}
+			if reloaded {
+				c.logger.Info("user agent rules reloaded", slog.String("path", c.path))
+			}
+		}
+	}
+}
+
+func compile(rules Rules) (*compiledRules, error) {
+	var err error
+	compiled := &compiledRules{defaultDevice: rules.DefaultDevice}
+	if compiled.defaultDevice == "" {
+		compiled.defaultDevice = Other
+	}
+
+	if compiled.bots, err = compileList("bots", rules.Bots); err != nil {
+		return nil, err
+	}
+	if compiled.browsers, err = compileList("browsers", rules.Browsers); err != nil {
+		return nil, err
+	}
+	if compiled.os, err = compileList("os", rules.OS); err != nil {
+		return nil, err
+	}
+	if compiled.devices, err = compileList("devices", rules.Devices); err != nil {
+		return nil, err
+	}
+
+	return compiled, nil
+}
+
+func compileList(list string, rules []Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("%s[%d]: empty name", list, i)
+		}
+
+		pattern, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d] %q: %w", list, i, rule.Name, err)
+		}
+
+		compiled = append(compiled, compiledRule{name: rule.Name, pattern: pattern})
+	}
+
+	return compiled, nil
+}
+
+func match(rules []compiledRule, userAgent string) (string, bool) {
+	for _, rule := range rules {
+		if rule.pattern.MatchString(userAgent) {
+			return rule.name, true
+		}
+	}
+
+	return Other, false
+}
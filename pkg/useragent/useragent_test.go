@@ -0,0 +1,93 @@
+package useragent
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifier_Classify(t *testing.T) {
+	c, err := NewClassifier("../../config/ua_rules.json", &slog.Logger{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      Agent
+	}{
+		{
+			name:      "Chrome on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			want:      Agent{Browser: "Chrome", OS: "Windows", Device: "desktop"},
+		},
+		{
+			name:      "Edge is not Chrome",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.67",
+			want:      Agent{Browser: "Edge", OS: "Windows", Device: "desktop"},
+		},
+		{
+			name:      "Safari on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want:      Agent{Browser: "Safari", OS: "iOS", Device: "mobile"},
+		},
+		{
+			name:      "Firefox on Android tablet",
+			userAgent: "Mozilla/5.0 (Android 14; Tablet; rv:125.0) Gecko/125.0 Firefox/125.0",
+			want:      Agent{Browser: "Firefox", OS: "Android", Device: "tablet"},
+		},
+		{
+			name:      "Slack link preview",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want:      Agent{Browser: "Slackbot", OS: Other, Device: DeviceBot, Bot: true},
+		},
+		{
+			name:      "Twitter card",
+			userAgent: "Twitterbot/1.0",
+			want:      Agent{Browser: "Twitterbot", OS: Other, Device: DeviceBot, Bot: true},
+		},
+		{
+			name:      "Empty user agent",
+			userAgent: "",
+			want:      Agent{Browser: "empty", OS: Other, Device: DeviceBot, Bot: true},
+		},
+		{
+			name:      "Unknown client",
+			userAgent: "SomethingNew/1.0",
+			want:      Agent{Browser: Other, OS: Other, Device: "desktop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.Classify(tt.userAgent))
+		})
+	}
+}
+
+func TestClassifier_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"bots": [{"name": "OldBot", "pattern": "OldBot"}]}`), 0o644))
+
+	c, err := NewClassifier(path, &slog.Logger{})
+	require.NoError(t, err)
+	assert.False(t, c.Classify("NewBot/2.0").Bot)
+
+	// broken rules keep the current ones
+	require.NoError(t, os.WriteFile(path, []byte(`{"bots": [{"name": "NewBot", "pattern": "("}]}`), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	_, err = c.Reload()
+	assert.Error(t, err)
+	assert.True(t, c.Classify("OldBot/1.0").Bot)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"bots": [{"name": "NewBot", "pattern": "NewBot"}]}`), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)))
+	reloaded, err := c.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.True(t, c.Classify("NewBot/2.0").Bot)
+}